@@ -1,20 +1,66 @@
 package types
 
 import (
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/pterm/pterm"
 )
 
-// The `DirOps` interface defines methods for reading directory entries and removing files.
+// The `DirOps` interface defines the filesystem operations used by this module, so callers can swap
+// the real filesystem for an in-memory, trash or journaling implementation.
 // @property ReadDir - The `ReadDir` method reads the directory named by `name` and returns a list of
 // directory entries sorted by filename.
 // @property {error} Remove - The `Remove` method in the `DirOps` interface is used to delete a
 // directory entry with the specified name. It takes the name of the directory entry as a parameter and
 // returns an error if the operation fails.
+// @property Stat - The `Stat` method returns the `os.FileInfo` describing `name`, following symlinks.
+// @property Lstat - The `Lstat` method returns the `os.FileInfo` describing `name` without following
+// symlinks.
+// @property Open - The `Open` method opens `name` for reading.
+// @property OpenFile - The `OpenFile` method opens `name` with the `os.O_*` flags in `flag`, like
+// `os.OpenFile`, creating it with `perm` when `os.O_CREATE` is set. With `os.O_EXCL` it fails with an
+// error matching `fs.ErrExist` when `name` already exists, which makes it safe for claiming a name.
+// @property {error} Rename - The `Rename` method moves `oldpath` to `newpath`.
+// @property {error} MkdirAll - The `MkdirAll` method creates a directory along with any missing
+// parents using `perm`.
+// @property {error} RemoveAll - The `RemoveAll` method removes `path` and everything it contains.
+// @property {error} Chtimes - The `Chtimes` method changes the access and modification times of `name`.
+// @property {error} WalkDir - The `WalkDir` method walks the tree rooted at `root` calling `fn` for each
+// file or directory, in the same way as `filepath.WalkDir`.
 type DirOps interface {
 	ReadDir(name string) ([]os.DirEntry, error)
 	Remove(name string) error
+	Stat(name string) (os.FileInfo, error)
+	Lstat(name string) (os.FileInfo, error)
+	Open(name string) (fs.File, error)
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	Rename(oldpath, newpath string) error
+	MkdirAll(path string, perm os.FileMode) error
+	RemoveAll(path string) error
+	Chtimes(name string, atime time.Time, mtime time.Time) error
+	WalkDir(root string, fn fs.WalkDirFunc) error
+}
+
+// The `File` interface is a file opened by `DirOps.OpenFile`, which can be written as well as read.
+// @property {error} Write - The `Write` method writes to the file, at its end when it was opened with
+// `os.O_APPEND`.
+// @property {error} Sync - The `Sync` method commits the written data to stable storage.
+type File interface {
+	fs.File
+	io.Writer
+	Sync() error
+}
+
+// The `PathMatcher` interface is implemented by include/exclude rules, such as the gitignore style rules
+// in the `ignore` package, that walks consult before visiting a path.
+// @property {bool} Match - The `Match` method reports whether `path` is excluded, `isDir` tells whether
+// `path` is a directory.
+type PathMatcher interface {
+	Match(path string, isDir bool) bool
 }
 
 // The RealDirOps type is likely related to file system operations in the Go programming language.
@@ -24,6 +70,16 @@ type FileType string
 
 type OperatorType string
 
+type SymlinkPolicy string
+
+type ChangeType string
+
+type DetectionMode string
+
+type SizeSystem string
+
+type TimeField string
+
 // The type `Application` represents an application with various attributes such as name, description,
 // style, usage, and version.
 // @property Name - The `Name` property in the `Application` struct is a pointer to a string, which
@@ -76,18 +132,46 @@ type SizeUnit struct {
 	Size  int64
 }
 
+// The ToleranceBound struct describes how far a size may stray from a wanted size on one side. `Bytes`
+// and `Percent` add up, so a bound usually sets only one of them.
+// @property {int64} Bytes - The `Bytes` property is a fixed number of bytes.
+// @property {float64} Percent - The `Percent` property is a percentage of the wanted size, `5` being
+// 5%.
+type ToleranceBound struct {
+	Bytes   int64
+	Percent float64
+}
+
+// The Tolerance struct describes how far a size may stray below and above a wanted size. The zero value
+// is an exact match.
+// @property {ToleranceBound} Lower - The `Lower` property is how far a size may be below the wanted
+// size.
+// @property {ToleranceBound} Upper - The `Upper` property is how far a size may be above the wanted
+// size.
+type Tolerance struct {
+	Lower ToleranceBound
+	Upper ToleranceBound
+}
+
 // The ToleranceResults struct defines the size tolerance and bounds for a value.
 // @property {int64} ToleranceSize - ToleranceSize represents the acceptable range or margin of error
-// for a particular measurement or value.
+// for a particular measurement or value. For an asymmetric tolerance it is the tolerance above the
+// value, the same as `UpperToleranceSize`.
+// @property {int64} LowerToleranceSize - The `LowerToleranceSize` property is the resolved tolerance
+// below the value in bytes.
+// @property {int64} UpperToleranceSize - The `UpperToleranceSize` property is the resolved tolerance
+// above the value in bytes.
 // @property {int64} UpperBoundSize - UpperBoundSize represents the upper limit or maximum size allowed
 // for a certain parameter or value.
 // @property {int64} LowerBoundSize - The `LowerBoundSize` property in the `ToleranceResults` struct
 // represents the lower limit or threshold size for a certain tolerance level. It is used to define the
 // minimum acceptable size or value within the specified tolerance range.
 type ToleranceResults struct {
-	ToleranceSize  int64
-	UpperBoundSize int64
-	LowerBoundSize int64
+	ToleranceSize      int64
+	LowerToleranceSize int64
+	UpperToleranceSize int64
+	UpperBoundSize     int64
+	LowerBoundSize     int64
 }
 
 // The TestLayout type is a generic struct used for storing test case information.
@@ -107,6 +191,311 @@ type TestLayout[InputT any, ExpectedT any] struct {
 	Err      error
 }
 
+// The FileTypeDefinition struct describes a file type held by the file type registry.
+// @property {FileType} Name - The `Name` property is the canonical name of the file type, it is what
+// `ToFileType` returns.
+// @property {[]string} Aliases - The `Aliases` property lists other names the file type can be looked
+// up by, lookups ignore case.
+// @property {[]string} Extensions - The `Extensions` property lists the extensions that belong to the
+// file type, such as `.mp4`. The wildcard `*.*` makes the file type match every file.
+// @property {string} Description - The `Description` property is a short human readable description
+// used in CLI help and shell completion.
+type FileTypeDefinition struct {
+	Name        FileType
+	Aliases     []string
+	Extensions  []string
+	Description string
+}
+
+// The PruneOptions struct controls how empty directories are pruned from a tree.
+// @property {bool} DryRun - When `DryRun` is true no directories are removed, the report lists what
+// would have been removed.
+// @property {int} MaxDepth - The `MaxDepth` property limits how deep below the root the pruner will
+// descend. The root is depth 0, a value of 0 or less means there is no limit.
+// @property {bool} KeepRoot - When `KeepRoot` is true the root directory is never removed, even if it
+// ends up empty.
+// @property {PathMatcher} Ignore - The `Ignore` property excludes matching directories from pruning,
+// they are neither descended into nor removed. A nil value excludes nothing.
+// @property {SymlinkPolicy} Symlinks - The `Symlinks` property decides what happens to symlinks. A
+// symlink is never removed and always counts as content, with `SymlinkPolicies.Follow` the empty
+// directories inside a linked directory are pruned as well. An empty value behaves like
+// `SymlinkPolicies.Skip`.
+type PruneOptions struct {
+	DryRun   bool
+	MaxDepth int
+	KeepRoot bool
+	Ignore   PathMatcher
+	Symlinks SymlinkPolicy
+}
+
+// The PruneFailure struct pairs a path with the error that stopped it from being pruned.
+// @property {string} Path - The `Path` property is the directory that could not be read or removed.
+// @property {error} Err - The `Err` property is the error returned while reading or removing `Path`.
+type PruneFailure struct {
+	Path string
+	Err  error
+}
+
+// The PruneReport struct is the result of pruning empty directories from a tree.
+// @property {[]string} Removed - The `Removed` property lists directories that were removed, or that
+// would have been removed during a dry run, in the order they were removed (deepest first).
+// @property {[]string} Skipped - The `Skipped` property lists directories that were left in place
+// because they still hold content, sit below `MaxDepth`, or are the root with `KeepRoot` set.
+// @property {[]PruneFailure} Failed - The `Failed` property lists directories that could not be read or
+// removed along with the error encountered, including followed symlinks that would loop.
+// @property {[]string} BrokenLinks - The `BrokenLinks` property lists symlinks whose target does not
+// exist, whatever the symlink policy.
+type PruneReport struct {
+	Removed     []string
+	Skipped     []string
+	Failed      []PruneFailure
+	BrokenLinks []string
+}
+
+// The ScanOptions struct describes which files a scan should match and how it should run.
+// @property {string} Root - The `Root` property is the directory the scan starts from.
+// @property {FileType} FileType - The `FileType` property limits matches to files whose extension is
+// valid for that type, an empty value behaves like `FileTypes.Any`.
+// @property {OperatorType} Operator - The `Operator` property is the size comparison applied to each
+// file, an empty value disables size matching.
+// @property {int64} WantedSize - The `WantedSize` property is the size in bytes `Operator` compares
+// against, the lower bound for the range operators.
+// @property {int64} UpperSize - The `UpperSize` property is the upper bound in bytes for the range
+// operators such as `OperatorTypes.Between`, it is ignored by the other operators.
+// @property {float64} ToleranceSize - The `ToleranceSize` property is the tolerance passed to the size
+// comparison, in KB.
+// @property {Tolerance} Tolerance - The `Tolerance` property is the tolerance of the size comparison in
+// bytes or percent, possibly different below and above the wanted size. When set it is used instead of
+// `ToleranceSize`.
+// @property {int} Workers - The `Workers` property bounds how many directories are read at once, a
+// value of 0 or less uses the number of CPUs.
+// @property {DirOps} Ops - The `Ops` property is the filesystem to scan, nil uses `RealDirOps`.
+// @property {PathMatcher} Ignore - The `Ignore` property excludes matching files and directories from
+// the scan, ignored directories are not descended into. A nil value excludes nothing.
+// @property {SymlinkPolicy} Symlinks - The `Symlinks` property decides whether symlinks are skipped,
+// followed to their target or matched as files with the size of the link itself. Broken links and
+// followed links that would loop are reported as errors. An empty value behaves like
+// `SymlinkPolicies.Skip`.
+// @property {DetectionMode} Detection - The `Detection` property decides whether `FileType` is matched
+// by extension, by content or by both. An empty value behaves like `DetectionModes.Extension`.
+// @property {[]TimeFilter} TimeFilters - The `TimeFilters` property limits matches to files passing
+// every filter, see the `timefilter` package.
+type ScanOptions struct {
+	Root          string
+	FileType      FileType
+	Operator      OperatorType
+	WantedSize    int64
+	UpperSize     int64
+	ToleranceSize float64
+	Tolerance     Tolerance
+	Workers       int
+	Ops           DirOps
+	Ignore        PathMatcher
+	Symlinks      SymlinkPolicy
+	Detection     DetectionMode
+	TimeFilters   []TimeFilter
+}
+
+// The TimeFilter struct compares a timestamp of a file, either as an age, how long ago it was, or as a
+// point in time. Ages are compared when `Time` is zero, so `OperatorTypes.LessThan` with an `Age` of
+// 30 days matches files newer than 30 days, while with a `Time` it matches files older than `Time`.
+// @property {TimeField} Field - The `Field` property is the timestamp compared, an empty value behaves
+// like `TimeFields.Modified`.
+// @property {OperatorType} Operator - The `Operator` property is the comparison applied, the range
+// operators such as `OperatorTypes.Between` also use `UpperAge` or `UpperTime`.
+// @property {time.Duration} Age - The `Age` property is the age compared against, the lower bound for
+// the range operators.
+// @property {time.Duration} UpperAge - The `UpperAge` property is the upper bound in age for the range
+// operators.
+// @property {time.Time} Time - The `Time` property is the point in time compared against, the lower
+// bound for the range operators.
+// @property {time.Time} UpperTime - The `UpperTime` property is the upper bound in time for the range
+// operators.
+// @property {func() time.Time} Now - The `Now` property is the clock ages are measured from, nil uses
+// `time.Now`. Tests set it for deterministic results.
+type TimeFilter struct {
+	Field     TimeField
+	Operator  OperatorType
+	Age       time.Duration
+	UpperAge  time.Duration
+	Time      time.Time
+	UpperTime time.Time
+	Now       func() time.Time
+}
+
+// The ScanMatch struct describes a file that matched a scan.
+// @property {string} Path - The `Path` property is the full path of the matched file.
+// @property {int64} Size - The `Size` property is the size of the file in bytes.
+// @property {time.Time} ModTime - The `ModTime` property is the last modification time of the file.
+// @property {FileType} FileType - The `FileType` property is the category the file was identified as,
+// by its extension or, when detecting by content, its content. It is `FileTypes.Any` when no category
+// claims it.
+type ScanMatch struct {
+	Path     string
+	Size     int64
+	ModTime  time.Time
+	FileType FileType
+}
+
+// The ScanError struct pairs a path with the error encountered while scanning it.
+// @property {string} Path - The `Path` property is the file or directory that failed.
+// @property {error} Err - The `Err` property is the error returned for `Path`.
+type ScanError struct {
+	Path string
+	Err  error
+}
+
+// The DuplicateOptions struct describes which files are checked for duplicates.
+// @property {ScanOptions} ScanOptions - The embedded `ScanOptions` select the candidate files, using the
+// same `FileType` and size filters as a scan.
+// @property {int64} BlockSize - The `BlockSize` property is how many bytes from the start and the end of
+// each file feed the partial hash, a value of 0 or less uses 4 KB.
+type DuplicateOptions struct {
+	ScanOptions
+	BlockSize int64
+}
+
+// The DuplicateGroup struct is a set of files with identical content.
+// @property {string} Hash - The `Hash` property is the hex encoded SHA-256 of the shared content.
+// @property {int64} Size - The `Size` property is the size in bytes of each file in the group.
+// @property {[]string} Paths - The `Paths` property lists every file in the group, sorted.
+// @property {int64} Reclaimable - The `Reclaimable` property is the number of bytes freed by keeping a
+// single copy.
+type DuplicateGroup struct {
+	Hash        string
+	Size        int64
+	Paths       []string
+	Reclaimable int64
+}
+
+// The TrashItem struct describes an entry in a freedesktop.org trash directory.
+// @property {string} Name - The `Name` property is the name of the entry inside the trash `files`
+// directory, it is used to restore the entry.
+// @property {string} OriginalPath - The `OriginalPath` property is the absolute path the entry was
+// trashed from.
+// @property {time.Time} DeletionDate - The `DeletionDate` property is when the entry was trashed, in
+// local time.
+type TrashItem struct {
+	Name         string
+	OriginalPath string
+	DeletionDate time.Time
+}
+
+// The JournalEntry struct is a single record in an undo journal.
+// @property {string} RunID - The `RunID` property groups the entries written by one cleanup run.
+// @property {int} Seq - The `Seq` property orders entries within a run, outcome records reuse the
+// sequence number of the mutation they complete.
+// @property {time.Time} Time - The `Time` property is when the record was written.
+// @property {string} Op - The `Op` property is the mutation (`remove`, `removeall`, `rename`,
+// `openfile`, `mkdirall`, `chtimes`) or the outcome (`commit`, `abort`) being recorded.
+// @property {string} Path - The `Path` property is the path being mutated.
+// @property {string} NewPath - The `NewPath` property is the destination of a rename.
+// @property {bool} IsDir - The `IsDir` property records whether `Path` was a directory.
+// @property {os.FileMode} Mode - The `Mode` property is the mode `Path` had before the mutation.
+// @property {time.Time} ModTime - The `ModTime` property is the modification time `Path` had before
+// the mutation.
+// @property {[]string} Created - The `Created` property lists the directories a `mkdirall` created.
+// @property {bool} Reversible - The `Reversible` property records whether the mutation can be undone.
+// @property {string} Error - The `Error` property holds the error message of an `abort` record.
+type JournalEntry struct {
+	RunID      string      `json:"run_id"`
+	Seq        int         `json:"seq"`
+	Time       time.Time   `json:"time"`
+	Op         string      `json:"op"`
+	Path       string      `json:"path,omitempty"`
+	NewPath    string      `json:"new_path,omitempty"`
+	IsDir      bool        `json:"is_dir,omitempty"`
+	Mode       os.FileMode `json:"mode,omitempty"`
+	ModTime    time.Time   `json:"mod_time,omitempty"`
+	Created    []string    `json:"created,omitempty"`
+	Reversible bool        `json:"reversible,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// The UndoFailure struct pairs a journal entry with the error returned while undoing it.
+// @property {JournalEntry} Entry - The `Entry` property is the mutation that could not be undone.
+// @property {error} Err - The `Err` property is the error returned while undoing `Entry`.
+type UndoFailure struct {
+	Entry JournalEntry
+	Err   error
+}
+
+// The UndoReport struct is the result of undoing a journaled run.
+// @property {[]JournalEntry} Undone - The `Undone` property lists the mutations that were reversed, in
+// the order they were reversed.
+// @property {[]JournalEntry} Skipped - The `Skipped` property lists committed mutations that cannot be
+// reversed, such as removing a file.
+// @property {[]UndoFailure} Failed - The `Failed` property lists mutations whose reversal failed, and
+// mutations that were journaled but never committed or aborted, whose outcome is unknown.
+type UndoReport struct {
+	Undone  []JournalEntry
+	Skipped []JournalEntry
+	Failed  []UndoFailure
+}
+
+// The SnapshotOptions struct controls how a snapshot of a tree is taken.
+// @property {DirOps} Ops - The `Ops` property is the filesystem to read, nil uses `RealDirOps`.
+// @property {bool} Hash - When `Hash` is true the SHA-256 of every regular file is recorded, which lets
+// a diff tell rewritten content apart from a touched file.
+// @property {PathMatcher} Ignore - The `Ignore` property excludes matching files and directories from
+// the snapshot. A nil value excludes nothing.
+// @property {SymlinkPolicy} Symlinks - The `Symlinks` property decides whether symlinks are skipped,
+// followed or recorded as files. An empty value behaves like `SymlinkPolicies.Skip`.
+type SnapshotOptions struct {
+	Ops      DirOps
+	Hash     bool
+	Ignore   PathMatcher
+	Symlinks SymlinkPolicy
+}
+
+// The SnapshotEntry struct records the state of a single file or directory in a snapshot.
+// @property {string} Path - The `Path` property is the slash separated path relative to the snapshot
+// root.
+// @property {int64} Size - The `Size` property is the size of the file in bytes, directories are
+// recorded with a size of 0.
+// @property {time.Time} ModTime - The `ModTime` property is the last modification time of the entry.
+// @property {os.FileMode} Mode - The `Mode` property holds the type and permission bits of the entry.
+// @property {string} Hash - The `Hash` property is the hex encoded SHA-256 of the content, it is only
+// set for regular files when hashing was requested.
+type SnapshotEntry struct {
+	Path    string      `json:"path"`
+	Size    int64       `json:"size"`
+	ModTime time.Time   `json:"mod_time"`
+	Mode    os.FileMode `json:"mode"`
+	Hash    string      `json:"hash,omitempty"`
+}
+
+// The Snapshot struct is the recorded state of a tree at a point in time.
+// @property {int} Version - The `Version` property is the version of the snapshot format.
+// @property {string} Root - The `Root` property is the directory the snapshot was taken from.
+// @property {time.Time} Taken - The `Taken` property is when the snapshot was taken.
+// @property {[]SnapshotEntry} Entries - The `Entries` property lists every entry below the root, sorted
+// by path.
+type Snapshot struct {
+	Version int             `json:"version"`
+	Root    string          `json:"root"`
+	Taken   time.Time       `json:"taken"`
+	Entries []SnapshotEntry `json:"entries"`
+}
+
+// The SnapshotChange struct describes how an entry differs between two snapshots.
+// @property {string} Path - The `Path` property is the slash separated path relative to the snapshot
+// root.
+// @property {ChangeType} Change - The `Change` property classifies the difference.
+// @property {SnapshotEntry} Before - The `Before` property is the entry in the older snapshot, it is
+// the zero value for added entries.
+// @property {SnapshotEntry} After - The `After` property is the entry in the newer snapshot, it is the
+// zero value for removed entries.
+// @property {int64} Delta - The `Delta` property is the change in size in bytes, negative when the
+// entry shrunk or was removed.
+type SnapshotChange struct {
+	Path   string
+	Change ChangeType
+	Before SnapshotEntry
+	After  SnapshotEntry
+	Delta  int64
+}
+
 // The `func (r RealDirOps) ReadDir(name string) ([]os.DirEntry, error)` function is a method defined
 // on the `RealDirOps` struct. This method is implementing the `ReadDir` function of the `DirOps`
 // interface.
@@ -125,39 +514,197 @@ func (r RealDirOps) Remove(name string) error {
 	return os.Remove(name)
 }
 
+// The `func (r RealDirOps) Stat(name string) (os.FileInfo, error)` function implements the `Stat`
+// function of the `DirOps` interface by calling `os.Stat`.
+func (r RealDirOps) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// The `func (r RealDirOps) Lstat(name string) (os.FileInfo, error)` function implements the `Lstat`
+// function of the `DirOps` interface by calling `os.Lstat`.
+func (r RealDirOps) Lstat(name string) (os.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+// The `func (r RealDirOps) Open(name string) (fs.File, error)` function implements the `Open` function
+// of the `DirOps` interface by calling `os.Open`.
+func (r RealDirOps) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+// The `func (r RealDirOps) OpenFile(name string, flag int, perm os.FileMode) (File, error)` function
+// implements the `OpenFile` function of the `DirOps` interface by calling `os.OpenFile`.
+func (r RealDirOps) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	file, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// The `func (r RealDirOps) Rename(oldpath, newpath string) error` function implements the `Rename`
+// function of the `DirOps` interface by calling `os.Rename`.
+func (r RealDirOps) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+// The `func (r RealDirOps) MkdirAll(path string, perm os.FileMode) error` function implements the
+// `MkdirAll` function of the `DirOps` interface by calling `os.MkdirAll`.
+func (r RealDirOps) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+// The `func (r RealDirOps) RemoveAll(path string) error` function implements the `RemoveAll` function
+// of the `DirOps` interface by calling `os.RemoveAll`.
+func (r RealDirOps) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+// The `func (r RealDirOps) Chtimes(name string, atime time.Time, mtime time.Time) error` function
+// implements the `Chtimes` function of the `DirOps` interface by calling `os.Chtimes`.
+func (r RealDirOps) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+// The `func (r RealDirOps) WriteFile(name string, data []byte, perm os.FileMode) error` function writes
+// data to name by calling `os.WriteFile`. It is not part of the `DirOps` interface, which creates files
+// with `OpenFile`.
+func (r RealDirOps) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+
+// The `func (r RealDirOps) WalkDir(root string, fn fs.WalkDirFunc) error` function implements the
+// `WalkDir` function of the `DirOps` interface by calling `filepath.WalkDir`.
+func (r RealDirOps) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return filepath.WalkDir(root, fn)
+}
+
 var (
 	// The `FileTypes` variable is a struct that defines different file types as constants. Each file type
 	// is represented by a `FileType` value. The struct initializes these constants with specific string
 	// values representing the file types.
 	FileTypes = struct {
-		Any       FileType
-		Video     FileType
-		Image     FileType
-		Archive   FileType
-		Documents FileType
+		Any         FileType
+		Video       FileType
+		Image       FileType
+		Archive     FileType
+		Documents   FileType
+		Audio       FileType
+		Code        FileType
+		Fonts       FileType
+		Ebooks      FileType
+		Executables FileType
+		DiskImages  FileType
+		Subtitles   FileType
 	}{
-		Any:       "Any",
-		Video:     "Video",
-		Image:     "Image",
-		Archive:   "Archive",
-		Documents: "Documents",
+		Any:         "Any",
+		Video:       "Video",
+		Image:       "Image",
+		Archive:     "Archive",
+		Documents:   "Documents",
+		Audio:       "Audio",
+		Code:        "Code",
+		Fonts:       "Fonts",
+		Ebooks:      "Ebooks",
+		Executables: "Executables",
+		DiskImages:  "DiskImages",
+		Subtitles:   "Subtitles",
 	}
 
 	// The `OperatorTypes` variable is defining a struct that contains different comparison operator types
 	// as constants. Each operator type is represented by an `OperatorType` value. The struct initializes
 	// these constants with specific string values representing the comparison operators.
+	//
+	// `Between` and `NotBetween` compare against a lower and an upper bound and include the bounds in the
+	// range, their `Exclusive` variants leave the bounds out. `NotEqualTo` is the negation of `EqualTo`
+	// and honours the same tolerance.
 	OperatorTypes = struct {
-		EqualTo            OperatorType
-		GreaterThan        OperatorType
-		GreaterThanEqualTo OperatorType
-		LessThan           OperatorType
-		LessThanEqualTo    OperatorType
+		EqualTo             OperatorType
+		NotEqualTo          OperatorType
+		GreaterThan         OperatorType
+		GreaterThanEqualTo  OperatorType
+		LessThan            OperatorType
+		LessThanEqualTo     OperatorType
+		Between             OperatorType
+		BetweenExclusive    OperatorType
+		NotBetween          OperatorType
+		NotBetweenExclusive OperatorType
 	}{
-		EqualTo:            "Equal To",
-		GreaterThan:        "Greater Than",
-		GreaterThanEqualTo: "Greater Than or Equal To",
-		LessThan:           "Less Than",
-		LessThanEqualTo:    "Less Than Or Equal To",
+		EqualTo:             "Equal To",
+		NotEqualTo:          "Not Equal To",
+		GreaterThan:         "Greater Than",
+		GreaterThanEqualTo:  "Greater Than or Equal To",
+		LessThan:            "Less Than",
+		LessThanEqualTo:     "Less Than Or Equal To",
+		Between:             "Between",
+		BetweenExclusive:    "Between Exclusive",
+		NotBetween:          "Not Between",
+		NotBetweenExclusive: "Not Between Exclusive",
+	}
+
+	// The `SymlinkPolicies` variable defines how walks and size calculations treat symlinks. `Skip`
+	// leaves them out, `Follow` resolves them to their target and descends into linked directories, and
+	// `File` treats the link itself as a file.
+	SymlinkPolicies = struct {
+		Skip   SymlinkPolicy
+		Follow SymlinkPolicy
+		File   SymlinkPolicy
+	}{
+		Skip:   "Skip",
+		Follow: "Follow",
+		File:   "File",
+	}
+
+	// The `DetectionModes` variable defines how a file is matched against a `FileType`. `Extension` trusts
+	// the file extension, `Content` reads the first bytes of the file and ignores the extension, and
+	// `Both` requires the extension and the content to agree.
+	DetectionModes = struct {
+		Extension DetectionMode
+		Content   DetectionMode
+		Both      DetectionMode
+	}{
+		Extension: "Extension",
+		Content:   "Content",
+		Both:      "Both",
+	}
+
+	// The `SizeSystems` variable defines how size units scale. `IEC` uses powers of 1024 labelled KiB,
+	// MiB and so on, `SI` uses powers of 1000 labelled kB, MB and so on.
+	SizeSystems = struct {
+		IEC SizeSystem
+		SI  SizeSystem
+	}{
+		IEC: "IEC",
+		SI:  "SI",
+	}
+
+	// The `TimeFields` variable defines which timestamp of a file a `TimeFilter` compares. `Modified` is
+	// the mtime, `Changed` the ctime, when the file or its metadata last changed, and `Accessed` the atime.
+	TimeFields = struct {
+		Modified TimeField
+		Changed  TimeField
+		Accessed TimeField
+	}{
+		Modified: "Modified",
+		Changed:  "Changed",
+		Accessed: "Accessed",
+	}
+
+	// The `ChangeTypes` variable defines how an entry can differ between two snapshots. `Grown` and
+	// `Shrunk` are used when the size of a file changed, `Modified` when only its content, modification
+	// time, permissions or type changed.
+	ChangeTypes = struct {
+		Added    ChangeType
+		Removed  ChangeType
+		Grown    ChangeType
+		Shrunk   ChangeType
+		Modified ChangeType
+	}{
+		Added:    "Added",
+		Removed:  "Removed",
+		Grown:    "Grown",
+		Shrunk:   "Shrunk",
+		Modified: "Modified",
 	}
 
 	// The `SizeUnits` variable is a slice of `SizeUnit` structs that defines different size units along
@@ -174,7 +721,10 @@ var (
 	}
 
 	// The `FileExtensions` variable is a map in Go that associates each `FileType` with a map of file
-	// extensions and a boolean value. Here's what it does:.
+	// extensions and a boolean value. It is a read-only seed: the registry in the `filetypes` package
+	// copies it once when the program starts and lookups resolve through that registry, so changes made
+	// to this map later are ignored. Add extensions with `filetypes.Register` instead. Here's what it
+	// does:.
 	FileExtensions = map[FileType]map[string]bool{
 		// The `FileTypes.Any: {"*.*": true},` entry in the `FileExtensions` variable is associating the
 		// `FileType` constant `Any` with a map of file extensions. In this case, the file extension `*.*` is
@@ -206,9 +756,14 @@ var (
 		// the archive file formats include `.zip`, `.rar`, `.7z`, `.tar`, `.gz`, `.bz2`, `.xz`, `.iso`,
 		// `.tgz`, and `.tbz2`. This mapping allows for easy identification of archive files based on their
 		// file extensions within the application or system.
+		//
+		// Compound extensions such as `.tar.gz` take precedence over their last suffix, and `#` stands for
+		// the volume number of multi-part archives such as `.part1.rar`, `.r00` or `.7z.001`.
 		FileTypes.Archive: {
 			".zip": true, ".rar": true, ".7z": true, ".tar": true, ".gz": true,
 			".bz2": true, ".xz": true, ".iso": true, ".tgz": true, ".tbz2": true,
+			".tar.gz": true, ".tar.bz2": true, ".tar.xz": true, ".part#.rar": true, ".r#": true,
+			".7z.#": true, ".zip.#": true,
 		},
 		// The `FileTypes.Documents` constant is associated with a map of file extensions and boolean values.
 		// Each file extension key represents a specific document file format, and the boolean value `true`
@@ -218,5 +773,117 @@ var (
 			".odt": true, ".xlsx": true, ".xls": true, ".pptx": true, ".ppt": true,
 			".csv": true, ".md": true, ".pages": true,
 		},
+		// The `FileTypes.Audio` constant is associated with the extensions of music, audiobook and other
+		// sound files.
+		FileTypes.Audio: {
+			".mp3": true, ".flac": true, ".m4a": true, ".m4b": true, ".aac": true,
+			".wav": true, ".ogg": true, ".oga": true, ".opus": true, ".wma": true,
+			".aiff": true, ".aif": true, ".ape": true, ".mka": true, ".mid": true,
+			".midi": true,
+		},
+		// The `FileTypes.Code` constant is associated with the extensions of source code files. `.ts` is
+		// left to `FileTypes.Video`, where it stands for MPEG transport streams.
+		FileTypes.Code: {
+			".go": true, ".py": true, ".js": true, ".jsx": true, ".tsx": true,
+			".java": true, ".c": true, ".h": true, ".cpp": true, ".hpp": true,
+			".cs": true, ".rb": true, ".rs": true, ".php": true, ".swift": true,
+			".kt": true, ".scala": true, ".sh": true, ".ps1": true, ".lua": true,
+			".pl": true, ".sql": true, ".html": true, ".css": true,
+		},
+		// The `FileTypes.Fonts` constant is associated with the extensions of desktop and web fonts.
+		FileTypes.Fonts: {
+			".ttf": true, ".otf": true, ".woff": true, ".woff2": true, ".eot": true,
+			".fon": true,
+		},
+		// The `FileTypes.Ebooks` constant is associated with the extensions of ebooks and comic book
+		// archives.
+		FileTypes.Ebooks: {
+			".epub": true, ".mobi": true, ".azw": true, ".azw3": true, ".fb2": true,
+			".cbz": true, ".cbr": true,
+		},
+		// The `FileTypes.Executables` constant is associated with the extensions of programs, scripts run
+		// by the OS shell and installer packages.
+		FileTypes.Executables: {
+			".exe": true, ".msi": true, ".bat": true, ".cmd": true, ".com": true,
+			".apk": true, ".deb": true, ".rpm": true, ".appimage": true, ".pkg": true,
+			".run": true, ".jar": true,
+		},
+		// The `FileTypes.DiskImages` constant is associated with the extensions of optical disc, disk and
+		// virtual machine images. `.iso` is shared with `FileTypes.Archive`, which claimed it first.
+		FileTypes.DiskImages: {
+			".iso": true, ".img": true, ".dmg": true, ".vhd": true, ".vhdx": true,
+			".vmdk": true, ".qcow2": true, ".vdi": true, ".ova": true,
+		},
+		// The `FileTypes.Subtitles` constant is associated with the extensions of subtitle and caption
+		// files.
+		FileTypes.Subtitles: {
+			".srt": true, ".ass": true, ".ssa": true, ".vtt": true, ".sub": true,
+			".idx": true,
+		},
+	}
+
+	// The `MIMETypes` variable maps every extension in `FileExtensions` to its MIME type. Extensions holding
+	// the `#` volume placeholder map to the MIME type of the archive they are a part of. Extensions missing
+	// from this map fall back to the standard library's `mime` table.
+	MIMETypes = map[string]string{
+		// Video.
+		".mp4": "video/mp4", ".avi": "video/x-msvideo", ".mkv": "video/x-matroska", ".mov": "video/quicktime",
+		".wmv": "video/x-ms-wmv", ".flv": "video/x-flv", ".webm": "video/webm", ".m4v": "video/x-m4v",
+		".mpg": "video/mpeg", ".mpeg": "video/mpeg", ".ts": "video/mp2t",
+		// Image.
+		".jpg": "image/jpeg", ".jpeg": "image/jpeg", ".png": "image/png", ".gif": "image/gif",
+		".bmp": "image/bmp", ".tiff": "image/tiff", ".webp": "image/webp", ".svg": "image/svg+xml",
+		".raw": "image/x-raw", ".heic": "image/heic", ".ico": "image/vnd.microsoft.icon",
+		// Archive.
+		".zip": "application/zip", ".rar": "application/vnd.rar", ".7z": "application/x-7z-compressed",
+		".tar": "application/x-tar", ".gz": "application/gzip", ".bz2": "application/x-bzip2",
+		".xz": "application/x-xz", ".iso": "application/x-iso9660-image", ".tgz": "application/gzip",
+		".tbz2": "application/x-bzip2", ".tar.gz": "application/gzip", ".tar.bz2": "application/x-bzip2",
+		".tar.xz": "application/x-xz", ".part#.rar": "application/vnd.rar", ".r#": "application/vnd.rar",
+		".7z.#": "application/x-7z-compressed", ".zip.#": "application/zip",
+		// Documents.
+		".doc": "application/msword", ".pdf": "application/pdf", ".txt": "text/plain", ".rtf": "application/rtf",
+		".xls": "application/vnd.ms-excel", ".ppt": "application/vnd.ms-powerpoint", ".csv": "text/csv",
+		".md": "text/markdown", ".odt": "application/vnd.oasis.opendocument.text",
+		".docx":  "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		".xlsx":  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		".pptx":  "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		".pages": "application/vnd.apple.pages",
+		// Audio.
+		".mp3": "audio/mpeg", ".flac": "audio/flac", ".m4a": "audio/mp4", ".m4b": "audio/mp4",
+		".aac": "audio/aac", ".wav": "audio/wav", ".ogg": "audio/ogg", ".oga": "audio/ogg",
+		".opus": "audio/opus", ".wma": "audio/x-ms-wma", ".aiff": "audio/aiff", ".aif": "audio/aiff",
+		".ape": "audio/x-ape", ".mka": "audio/x-matroska", ".mid": "audio/midi", ".midi": "audio/midi",
+		// Code.
+		".go": "text/x-go", ".py": "text/x-python", ".js": "text/javascript", ".jsx": "text/jsx",
+		".tsx": "text/tsx", ".java": "text/x-java", ".c": "text/x-c", ".h": "text/x-c",
+		".cpp": "text/x-c++", ".hpp": "text/x-c++", ".cs": "text/x-csharp", ".rb": "text/x-ruby",
+		".rs": "text/x-rust", ".php": "text/x-php", ".swift": "text/x-swift", ".kt": "text/x-kotlin",
+		".scala": "text/x-scala", ".sh": "text/x-shellscript", ".ps1": "text/x-powershell",
+		".lua": "text/x-lua", ".pl": "text/x-perl", ".sql": "text/x-sql", ".html": "text/html",
+		".css": "text/css",
+		// Fonts.
+		".ttf": "font/ttf", ".otf": "font/otf", ".woff": "font/woff", ".woff2": "font/woff2",
+		".eot": "application/vnd.ms-fontobject", ".fon": "application/x-font-fon",
+		// Ebooks.
+		".epub": "application/epub+zip", ".mobi": "application/x-mobipocket-ebook",
+		".azw": "application/vnd.amazon.ebook", ".azw3": "application/vnd.amazon.mobi8-ebook",
+		".fb2": "application/x-fictionbook+xml", ".cbz": "application/vnd.comicbook+zip",
+		".cbr": "application/vnd.comicbook-rar",
+		// Executables.
+		".exe": "application/vnd.microsoft.portable-executable", ".msi": "application/x-msi",
+		".bat": "application/x-msdos-program", ".cmd": "application/x-msdos-program",
+		".com": "application/x-msdos-program", ".apk": "application/vnd.android.package-archive",
+		".deb": "application/vnd.debian.binary-package", ".rpm": "application/x-rpm",
+		".appimage": "application/vnd.appimage", ".pkg": "application/x-newton-compatible-pkg",
+		".run": "application/x-makeself", ".jar": "application/java-archive",
+		// Disk images.
+		".img": "application/x-raw-disk-image", ".dmg": "application/x-apple-diskimage",
+		".vhd": "application/x-vhd", ".vhdx": "application/x-vhdx", ".vmdk": "application/x-vmdk",
+		".qcow2": "application/x-qemu-disk", ".vdi": "application/x-virtualbox-vdi",
+		".ova": "application/x-virtualbox-ova",
+		// Subtitles.
+		".srt": "application/x-subrip", ".ass": "text/x-ssa", ".ssa": "text/x-ssa", ".vtt": "text/vtt",
+		".sub": "text/x-microdvd", ".idx": "application/x-vobsub",
 	}
 )
@@ -5,9 +5,11 @@ import (
 	"os"
 	"os/exec"
 	"runtime"
+	"strings"
 
 	"github.com/ondrovic/common/types"
 	"github.com/ondrovic/common/utils"
+	"github.com/ondrovic/common/utils/filetypes"
 	"github.com/ondrovic/common/utils/formatters"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
@@ -83,3 +85,40 @@ func ApplicationBanner(app *types.Application, clearScreen func(interface{}) err
 		Println(app.Name)
 	return nil
 }
+
+// The function `FileTypeUsage` returns a usage string listing every registered file type and its
+// description, for use in flag help.
+func FileTypeUsage() string {
+	parts := []string{}
+	for _, def := range filetypes.List() {
+		part := string(def.Name)
+		if def.Description != "" {
+			part = fmt.Sprintf("%s (%s)", def.Name, def.Description)
+		}
+		parts = append(parts, part)
+	}
+	return "file type: " + strings.Join(parts, ", ")
+}
+
+// The function `CompleteFileTypes` is a cobra completion function that completes the names of the
+// registered file types, with their descriptions, that start with toComplete.
+func CompleteFileTypes(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	completions := []string{}
+	for _, def := range filetypes.List() {
+		name := strings.ToLower(string(def.Name))
+		if !strings.HasPrefix(name, strings.ToLower(toComplete)) {
+			continue
+		}
+		if def.Description != "" {
+			name += "\t" + def.Description
+		}
+		completions = append(completions, name)
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// The function `RegisterFileTypeCompletion` registers `CompleteFileTypes` as the shell completion of the
+// flag named flagName on cmd.
+func RegisterFileTypeCompletion(cmd *cobra.Command, flagName string) error {
+	return cmd.RegisterFlagCompletionFunc(flagName, CompleteFileTypes)
+}
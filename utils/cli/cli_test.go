@@ -3,11 +3,13 @@ package cli
 import (
 	"fmt"
 	"os"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
 
 	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils/filetypes"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
@@ -136,8 +138,8 @@ func TestApplicationBanner(t *testing.T) {
 			Expected: fmt.Errorf("simulated clear screen error"),
 		},
 		{Name: "Application is not a struct", Input: InputStruct{app: nil, clearScreen: ClearTerminalScreen}, Expected: fmt.Errorf("validateApp expects a struct")},
-		{Name: "Empty application name", Input: InputStruct{app: &types.Application{Description: "Test Description", Style: types.Styles{Color: types.Colors{}}, Usage: "Test Usage", Version: "1.0.0"}, clearScreen: ClearTerminalScreen}, Expected: fmt.Errorf("Name cannot be empty")},
-		{Name: "Empty application description", Input: InputStruct{app: &types.Application{Name: "Test App", Style: types.Styles{Color: types.Colors{}}, Usage: "Test Usage", Version: "1.0.0"}, clearScreen: ClearTerminalScreen}, Expected: fmt.Errorf("Description cannot be empty")},
+		{Name: "Empty application name", Input: InputStruct{app: &types.Application{Description: "Test Description", Style: types.Styles{Color: types.Colors{}}, Usage: "Test Usage", Version: "1.0.0"}, clearScreen: ClearTerminalScreen}, Expected: fmt.Errorf("Name cannot be empty\nStyle cannot be an empty struct")},
+		{Name: "Empty application description", Input: InputStruct{app: &types.Application{Name: "Test App", Style: types.Styles{Color: types.Colors{}}, Usage: "Test Usage", Version: "1.0.0"}, clearScreen: ClearTerminalScreen}, Expected: fmt.Errorf("Description cannot be empty\nStyle cannot be an empty struct")},
 		{Name: "Empty application style struct", Input: InputStruct{app: &types.Application{Name: "Test App", Description: "Test Description", Style: types.Styles{Color: types.Colors{}}, Usage: "Test Usage", Version: "1.0.0"}, clearScreen: ClearTerminalScreen}, Expected: fmt.Errorf("Style cannot be an empty struct")},
 		{Name: "Empty application usage", Input: InputStruct{app: &types.Application{Name: "Test App", Description: "Test Description", Style: types.Styles{Color: types.Colors{Background: pterm.BgRed, Foreground: pterm.FgWhite}}, Version: "1.0.0"}, clearScreen: ClearTerminalScreen}, Expected: fmt.Errorf("Usage cannot be empty")},
 		{Name: "Empty application version", Input: InputStruct{app: &types.Application{Name: "Test App", Description: "Test Description", Style: types.Styles{Color: types.Colors{Background: pterm.BgRed, Foreground: pterm.FgWhite}}, Usage: "Test Usage"}, clearScreen: ClearTerminalScreen}, Expected: fmt.Errorf("Version cannot be empty")},
@@ -159,3 +161,57 @@ func TestApplicationBanner(t *testing.T) {
 		})
 	}
 }
+
+// TestFileTypeUsage tests that FileTypeUsage lists the registered file types.
+func TestFileTypeUsage(t *testing.T) {
+	if err := filetypes.Register(types.FileTypeDefinition{Name: "Lyrics", Extensions: []string{".lrc"}, Description: "Lyrics files"}); err != nil {
+		t.Fatalf("filetypes.Register() error = %v", err)
+	}
+	defer filetypes.Unregister("Lyrics")
+
+	usage := FileTypeUsage()
+	for _, expected := range []string{"Any (Any file)", "Video (Video files)", "DiskImages (Disk and virtual machine images)", "Lyrics (Lyrics files)"} {
+		if !strings.Contains(usage, expected) {
+			t.Errorf("FileTypeUsage() = %q; want it to contain %q", usage, expected)
+		}
+	}
+}
+
+// TestCompleteFileTypes tests the CompleteFileTypes func.
+func TestCompleteFileTypes(t *testing.T) {
+	tests := []*types.TestLayout[string, []string]{
+		{Name: "Everything", Input: "", Expected: []string{
+			"any\tAny file", "video\tVideo files", "image\tImage files", "archive\tArchive files", "documents\tDocument files",
+			"audio\tAudio files", "code\tSource code files", "fonts\tFont files", "ebooks\tEbook files",
+			"executables\tExecutables and installers", "diskimages\tDisk and virtual machine images", "subtitles\tSubtitle files",
+		}},
+		{Name: "Prefix", Input: "a", Expected: []string{"any\tAny file", "archive\tArchive files", "audio\tAudio files"}},
+		{Name: "Upper case prefix", Input: "VI", Expected: []string{"video\tVideo files"}},
+		{Name: "No match", Input: "x", Expected: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			completions, directive := CompleteFileTypes(nil, nil, test.Input)
+			if !reflect.DeepEqual(completions, test.Expected) {
+				t.Errorf("CompleteFileTypes(%q) - %v = %q; want %q", test.Input, test.Name, completions, test.Expected)
+			}
+			if directive != cobra.ShellCompDirectiveNoFileComp {
+				t.Errorf("CompleteFileTypes(%q) - %v directive = %v; want %v", test.Input, test.Name, directive, cobra.ShellCompDirectiveNoFileComp)
+			}
+		})
+	}
+}
+
+// TestRegisterFileTypeCompletion tests the RegisterFileTypeCompletion func.
+func TestRegisterFileTypeCompletion(t *testing.T) {
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().String("type", "any", FileTypeUsage())
+
+	if err := RegisterFileTypeCompletion(cmd, "type"); err != nil {
+		t.Errorf("RegisterFileTypeCompletion() error = %v", err)
+	}
+	if err := RegisterFileTypeCompletion(cmd, "missing"); err == nil {
+		t.Errorf("RegisterFileTypeCompletion() for a missing flag error = nil; want error")
+	}
+}
@@ -0,0 +1,276 @@
+package duplicates
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils"
+	"github.com/ondrovic/common/utils/formatters"
+	"github.com/ondrovic/common/utils/results"
+	"github.com/ondrovic/common/utils/scanner"
+	"github.com/pterm/pterm"
+)
+
+// defaultBlockSize is the number of bytes hashed from each end of a file when no block size is given.
+const defaultBlockSize = 4 << 10
+
+// The DuplicateRow struct is a single rendered row of the duplicates table.
+type DuplicateRow struct {
+	Hash        string
+	Files       int
+	Size        int64
+	Reclaimable string
+	Paths       string
+}
+
+// Find scans opts.Root for candidate files and returns every group of files with identical content.
+// Candidates are grouped by size first, then by a hash of their first and last blocks, and only files
+// that still collide are fully hashed. Empty files are never reported as duplicates, nor are several
+// paths to the same file, such as hardlinks or a symlink followed with `types.SymlinkPolicies.Follow`,
+// since removing one would free nothing. Groups are sorted by reclaimable space, largest first.
+func Find(ctx context.Context, opts types.DuplicateOptions) ([]types.DuplicateGroup, []types.ScanError) {
+	if opts.Ops == nil {
+		opts.Ops = &types.RealDirOps{}
+	}
+	blockSize := opts.BlockSize
+	if blockSize <= 0 {
+		blockSize = defaultBlockSize
+	}
+
+	matches, scanErrors := scanner.Collect(ctx, opts.ScanOptions)
+
+	// Stage 1: group by size.
+	bySize := map[int64][]string{}
+	for _, match := range matches {
+		if match.Size == 0 {
+			continue
+		}
+		bySize[match.Size] = append(bySize[match.Size], match.Path)
+	}
+
+	groups := []types.DuplicateGroup{}
+	for size, paths := range bySize {
+		if len(paths) < 2 {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		paths, errs := distinctFiles(opts.Ops, paths)
+		scanErrors = append(scanErrors, errs...)
+		if len(paths) < 2 {
+			continue
+		}
+
+		// Stage 2: group by a hash of the first and last blocks.
+		byPartial := map[string][]string{}
+		for _, path := range paths {
+			hash, err := partialHash(opts.Ops, path, size, blockSize)
+			if err != nil {
+				scanErrors = append(scanErrors, types.ScanError{Path: path, Err: err})
+				continue
+			}
+			byPartial[hash] = append(byPartial[hash], path)
+		}
+
+		for partial, candidates := range byPartial {
+			if len(candidates) < 2 {
+				continue
+			}
+
+			// Small files were read in full by the partial hash, so it already identifies them.
+			if size <= 2*blockSize {
+				groups = append(groups, newGroup(partial, size, candidates))
+				continue
+			}
+
+			// Stage 3: group by a hash of the whole file.
+			byFull := map[string][]string{}
+			for _, path := range candidates {
+				hash, err := fullHash(opts.Ops, path)
+				if err != nil {
+					scanErrors = append(scanErrors, types.ScanError{Path: path, Err: err})
+					continue
+				}
+				byFull[hash] = append(byFull[hash], path)
+			}
+
+			for hash, identical := range byFull {
+				if len(identical) > 1 {
+					groups = append(groups, newGroup(hash, size, identical))
+				}
+			}
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Reclaimable != groups[j].Reclaimable {
+			return groups[i].Reclaimable > groups[j].Reclaimable
+		}
+		return groups[i].Hash < groups[j].Hash
+	})
+
+	return groups, scanErrors
+}
+
+// TotalReclaimable returns the bytes freed by keeping a single copy from every group.
+func TotalReclaimable(groups []types.DuplicateGroup) int64 {
+	var total int64
+	for _, group := range groups {
+		total += group.Reclaimable
+	}
+	return total
+}
+
+// ToRows converts duplicate groups into rows for `results.GenericRenderResultsTableInterface`.
+func ToRows(groups []types.DuplicateGroup) []DuplicateRow {
+	rows := make([]DuplicateRow, 0, len(groups))
+	for _, group := range groups {
+		hash := group.Hash
+		if len(hash) > 12 {
+			hash = hash[:12]
+		}
+		rows = append(rows, DuplicateRow{
+			Hash:        hash,
+			Files:       len(group.Paths),
+			Size:        group.Size,
+			Reclaimable: formatters.FormatSize(group.Reclaimable),
+			Paths:       strings.Join(group.Paths, "\n"),
+		})
+	}
+	return rows
+}
+
+// RenderResults renders duplicate groups as a results table with the total reclaimable space in the
+// footer.
+func RenderResults(groups []types.DuplicateGroup) {
+	if len(groups) == 0 {
+		pterm.Println("No duplicates found")
+		return
+	}
+
+	results.GenericRenderResultsTableInterface(ToRows(groups), map[string]interface{}{
+		"Hash":        "Total",
+		"Reclaimable": formatters.FormatSize(TotalReclaimable(groups)),
+	})
+}
+
+// newGroup builds a duplicate group with sorted paths.
+func newGroup(hash string, size int64, paths []string) types.DuplicateGroup {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+	return types.DuplicateGroup{
+		Hash:        hash,
+		Size:        size,
+		Paths:       sorted,
+		Reclaimable: size * int64(len(sorted)-1),
+	}
+}
+
+// distinctFiles keeps a single path for each file in paths, so hardlinks and symlinks to a file already
+// listed are dropped. Of several paths to one file the first one that is not a symlink is kept, in
+// sorted order.
+func distinctFiles(ops types.DirOps, paths []string) ([]string, []types.ScanError) {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+
+	type file struct {
+		path   string
+		info   os.FileInfo
+		isLink bool
+	}
+	files := []*file{}
+	scanErrors := []types.ScanError{}
+	for _, path := range sorted {
+		info, err := ops.Stat(path)
+		if err != nil {
+			scanErrors = append(scanErrors, types.ScanError{Path: path, Err: err})
+			continue
+		}
+		linkInfo, err := ops.Lstat(path)
+		isLink := err == nil && linkInfo.Mode()&os.ModeSymlink != 0
+
+		seen := false
+		for _, f := range files {
+			if utils.SameFile(f.info, info) {
+				if f.isLink && !isLink {
+					f.path, f.isLink = path, false
+				}
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			files = append(files, &file{path: path, info: info, isLink: isLink})
+		}
+	}
+
+	distinct := make([]string, 0, len(files))
+	for _, f := range files {
+		distinct = append(distinct, f.path)
+	}
+	return distinct, scanErrors
+}
+
+// partialHash hashes the first and last blockSize bytes of path, files no larger than two blocks are
+// hashed in full.
+func partialHash(ops types.DirOps, path string, size, blockSize int64) (string, error) {
+	file, err := ops.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if size <= 2*blockSize {
+		if _, err := io.Copy(hasher, file); err != nil {
+			return "", fmt.Errorf("error hashing %s: %w", path, err)
+		}
+		return hex.EncodeToString(hasher.Sum(nil)), nil
+	}
+
+	if _, err := io.CopyN(hasher, file, blockSize); err != nil {
+		return "", fmt.Errorf("error hashing %s: %w", path, err)
+	}
+
+	tail := make([]byte, blockSize)
+	if readerAt, ok := file.(io.ReaderAt); ok {
+		if _, err := readerAt.ReadAt(tail, size-blockSize); err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("error hashing %s: %w", path, err)
+		}
+	} else {
+		if _, err := io.CopyN(io.Discard, file, size-2*blockSize); err != nil {
+			return "", fmt.Errorf("error hashing %s: %w", path, err)
+		}
+		if _, err := io.ReadFull(file, tail); err != nil {
+			return "", fmt.Errorf("error hashing %s: %w", path, err)
+		}
+	}
+	hasher.Write(tail)
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// fullHash hashes the whole content of path.
+func fullHash(ops types.DirOps, path string) (string, error) {
+	file, err := ops.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("error hashing %s: %w", path, err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,205 @@
+package duplicates
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils/memfs"
+)
+
+// CreateTestFS creates an in-memory tree with duplicate and near-duplicate files for testing.
+//
+//	/root
+//	├── a.mp4, b.mp4            identical, small
+//	├── c.mp4                   same size as a.mp4, different content
+//	├── big1.mkv, sub/big2.mkv  identical, larger than two blocks
+//	├── big3.mkv                same first and last block as big1.mkv, different middle
+//	├── empty1.mp4, empty2.mp4  empty files
+//	└── copy.txt                same content as a.mp4 but not a video
+func CreateTestFS(t *testing.T) *memfs.MemDirOps {
+	t.Helper()
+	ops := memfs.New()
+
+	big := bytes.Repeat([]byte("x"), 64)
+	nearBig := append([]byte(nil), big...)
+	nearBig[32] = 'y'
+
+	files := map[string][]byte{
+		"/root/a.mp4":        []byte("same content"),
+		"/root/b.mp4":        []byte("same content"),
+		"/root/c.mp4":        []byte("diff content"),
+		"/root/big1.mkv":     big,
+		"/root/sub/big2.mkv": big,
+		"/root/big3.mkv":     nearBig,
+		"/root/empty1.mp4":   {},
+		"/root/empty2.mp4":   {},
+		"/root/copy.txt":     []byte("same content"),
+	}
+	for name, data := range files {
+		if err := ops.WriteFile(name, data, 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	return ops
+}
+
+// groupPaths returns the slash separated paths of each group.
+func groupPaths(groups []types.DuplicateGroup) [][]string {
+	out := [][]string{}
+	for _, group := range groups {
+		paths := []string{}
+		for _, path := range group.Paths {
+			paths = append(paths, filepath.ToSlash(path))
+		}
+		out = append(out, paths)
+	}
+	return out
+}
+
+// TestFind tests the Find func.
+func TestFind(t *testing.T) {
+	tests := []*types.TestLayout[types.DuplicateOptions, [][]string]{
+		{
+			Name:     "Videos",
+			Input:    types.DuplicateOptions{ScanOptions: types.ScanOptions{Root: "/root", FileType: types.FileTypes.Video}, BlockSize: 8},
+			Expected: [][]string{{"/root/big1.mkv", "/root/sub/big2.mkv"}, {"/root/a.mp4", "/root/b.mp4"}},
+		},
+		{
+			Name:     "Any file type includes other extensions",
+			Input:    types.DuplicateOptions{ScanOptions: types.ScanOptions{Root: "/root", FileType: types.FileTypes.Any}, BlockSize: 8},
+			Expected: [][]string{{"/root/big1.mkv", "/root/sub/big2.mkv"}, {"/root/a.mp4", "/root/b.mp4", "/root/copy.txt"}},
+		},
+		{
+			Name:     "Size filter",
+			Input:    types.DuplicateOptions{ScanOptions: types.ScanOptions{Root: "/root", FileType: types.FileTypes.Video, Operator: types.OperatorTypes.LessThan, WantedSize: 32}, BlockSize: 8},
+			Expected: [][]string{{"/root/a.mp4", "/root/b.mp4"}},
+		},
+		{
+			Name:     "Default block size hashes small files in full",
+			Input:    types.DuplicateOptions{ScanOptions: types.ScanOptions{Root: "/root", FileType: types.FileTypes.Video}},
+			Expected: [][]string{{"/root/big1.mkv", "/root/sub/big2.mkv"}, {"/root/a.mp4", "/root/b.mp4"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			test.Input.Ops = CreateTestFS(t)
+			groups, scanErrors := Find(context.Background(), test.Input)
+			if len(scanErrors) != 0 {
+				t.Errorf("Find() - %v errors = %v; want none", test.Name, scanErrors)
+			}
+			if got := groupPaths(groups); !reflect.DeepEqual(got, test.Expected) {
+				t.Errorf("Find() - %v = %v; want %v", test.Name, got, test.Expected)
+			}
+		})
+	}
+}
+
+// TestFind_OpenError tests that files which cannot be hashed are reported and left out.
+func TestFind_OpenError(t *testing.T) {
+	ops := CreateTestFS(t)
+	ops.InjectError(memfs.OpOpen, "/root/b.mp4", errors.New("simulated Open error"))
+
+	groups, scanErrors := Find(context.Background(), types.DuplicateOptions{ScanOptions: types.ScanOptions{Root: "/root", FileType: types.FileTypes.Video, Ops: ops}, BlockSize: 8})
+	if got := groupPaths(groups); !reflect.DeepEqual(got, [][]string{{"/root/big1.mkv", "/root/sub/big2.mkv"}}) {
+		t.Errorf("Find() = %v; want only the mkv group", got)
+	}
+	if len(scanErrors) != 1 || filepath.ToSlash(scanErrors[0].Path) != "/root/b.mp4" {
+		t.Errorf("Find() errors = %v; want one error for /root/b.mp4", scanErrors)
+	}
+}
+
+// TestToRows tests the ToRows and TotalReclaimable funcs.
+func TestToRows(t *testing.T) {
+	groups := []types.DuplicateGroup{
+		{Hash: "0123456789abcdef", Size: 2048, Paths: []string{"/a", "/b", "/c"}, Reclaimable: 4096},
+		{Hash: "short", Size: 10, Paths: []string{"/d", "/e"}, Reclaimable: 10},
+	}
+	expected := []DuplicateRow{
+		{Hash: "0123456789ab", Files: 3, Size: 2048, Reclaimable: "4.00 KB", Paths: "/a\n/b\n/c"},
+		{Hash: "short", Files: 2, Size: 10, Reclaimable: "10.00 B", Paths: "/d\n/e"},
+	}
+
+	if rows := ToRows(groups); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("ToRows() = %+v; want %+v", rows, expected)
+	}
+	if total := TotalReclaimable(groups); total != 4106 {
+		t.Errorf("TotalReclaimable() = %v; want %v", total, 4106)
+	}
+}
+
+// TestRenderResults tests the RenderResults func.
+func TestRenderResults(t *testing.T) {
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	defer func() { os.Stdout = old }()
+
+	RenderResults([]types.DuplicateGroup{
+		{Hash: "0123456789abcdef", Size: 1 << 20, Paths: []string{"/a", "/b"}, Reclaimable: 1 << 20},
+	})
+
+	w.Close()
+	out, _ := io.ReadAll(r)
+	output := string(out)
+
+	for _, expected := range []string{"HASH", "RECLAIMABLE", "0123456789ab", "1.00 MB", "TOTAL"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("RenderResults() output missing %q:\n%s", expected, output)
+		}
+	}
+}
+
+// TestFind_SameFile tests that several paths to one file are not reported as duplicates of each other.
+func TestFind_SameFile(t *testing.T) {
+	t.Run("Symlink followed", func(t *testing.T) {
+		ops := CreateTestFS(t)
+		if err := ops.Symlink("/root/a.mp4", "/root/link.mp4"); err != nil {
+			t.Fatalf("failed to create symlink: %v", err)
+		}
+		if err := ops.Symlink("/root/a.mp4", "/root/0-link.mp4"); err != nil {
+			t.Fatalf("failed to create symlink: %v", err)
+		}
+
+		groups, scanErrors := Find(context.Background(), types.DuplicateOptions{ScanOptions: types.ScanOptions{Root: "/root", FileType: types.FileTypes.Video, Ops: ops, Symlinks: types.SymlinkPolicies.Follow}, BlockSize: 8})
+		if len(scanErrors) != 0 {
+			t.Errorf("Find() errors = %v; want none", scanErrors)
+		}
+		expected := [][]string{{"/root/big1.mkv", "/root/sub/big2.mkv"}, {"/root/a.mp4", "/root/b.mp4"}}
+		if got := groupPaths(groups); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Find() = %v; want %v", got, expected)
+		}
+		if total := TotalReclaimable(groups); total != 64+12 {
+			t.Errorf("TotalReclaimable() = %d; want %d", total, 64+12)
+		}
+	})
+
+	t.Run("Hardlinks", func(t *testing.T) {
+		dir := t.TempDir()
+		for name, data := range map[string]string{"a.mp4": "same content", "c.mp4": "same content"} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+		}
+		if err := os.Link(filepath.Join(dir, "a.mp4"), filepath.Join(dir, "b.mp4")); err != nil {
+			t.Skipf("hardlinks not supported: %v", err)
+		}
+
+		groups, scanErrors := Find(context.Background(), types.DuplicateOptions{ScanOptions: types.ScanOptions{Root: dir, FileType: types.FileTypes.Video}})
+		if len(scanErrors) != 0 {
+			t.Errorf("Find() errors = %v; want none", scanErrors)
+		}
+		expected := [][]string{{filepath.Join(dir, "a.mp4"), filepath.Join(dir, "c.mp4")}}
+		if len(groups) != 1 || !reflect.DeepEqual(groups[0].Paths, expected[0]) || groups[0].Reclaimable != 12 {
+			t.Errorf("Find() = %+v; want one group of %v reclaiming 12 bytes", groups, expected[0])
+		}
+	})
+}
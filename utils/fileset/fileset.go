@@ -0,0 +1,279 @@
+package fileset
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils/filetypes"
+	"github.com/ondrovic/common/utils/mimetypes"
+)
+
+var (
+	// ErrEmpty is returned when parsing an expression without any terms.
+	ErrEmpty = errors.New("empty file type expression")
+	// ErrUnknownTerm is returned for a term that is neither a file type, an extension nor a MIME pattern.
+	ErrUnknownTerm = errors.New("unknown file type or extension")
+	// ErrMissingTerm is returned for an operator that is not followed by a term.
+	ErrMissingTerm = errors.New("operator without a term")
+)
+
+// The ParseError struct describes where an expression failed to parse. It wraps one of `ErrEmpty`,
+// `ErrUnknownTerm`, `ErrMissingTerm` or `mimetypes.ErrInvalidPattern`.
+type ParseError struct {
+	// Expr is the expression being parsed.
+	Expr string
+	// Token is the offending term or operator.
+	Token string
+	// Column is the 1-based byte column of Token in Expr.
+	Column int
+	// Err is the reason the token was rejected.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%v %q at column %d of %q", e.Err, e.Token, e.Column, e.Expr)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// kind is the kind of value a term matches against.
+type kind int
+
+const (
+	fileTypeTerm kind = iota
+	extensionTerm
+	mimeTerm
+)
+
+// term is a single, normalized operand of an expression.
+type term struct {
+	exclude  bool
+	kind     kind
+	fileType types.FileType
+	value    string
+}
+
+// The Set struct is a parsed file type expression. The zero value matches nothing.
+type Set struct {
+	terms []term
+}
+
+// Parse parses a file type expression into a `Set`. An expression is a list of terms, each of which is
+//
+//   - a file type name or alias from the `filetypes` registry, such as `video` or `docs`;
+//   - an extension, such as `.pdf`, `*.tar.gz` or, when a registered file type claims it, `csv`;
+//   - a MIME type or pattern, such as `image/png` or `video/*`.
+//
+// Terms are separated by `,`, `+` or white space and add to the set, a term prefixed with `-` removes
+// from it. Terms are applied from left to right, so a later term wins over an earlier one, and an
+// expression starting with a removal starts from every file.
+//
+// Example usage:
+//
+//	set, err := fileset.Parse("documents -csv -md")
+//	set.Match("report.pdf") // true
+//	set.Match("data.csv")   // false
+//	set.String()            // "Documents -.csv -.md"
+func Parse(expr string) (*Set, error) {
+	p := &parser{expr: expr, set: &Set{}}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.set, nil
+}
+
+// MustParse is like `Parse` but panics with the `Parse` error, such as one wrapping `ErrUnknownTerm`
+// for a file type that is not registered, so package level sets can be declared in a single line:
+//
+//	var media = fileset.MustParse("video audio images")
+func MustParse(expr string) *Set {
+	set, err := Parse(expr)
+	if err != nil {
+		panic(err)
+	}
+	return set
+}
+
+// Match reports whether path belongs to the set. File type terms match the way
+// `utils.IsExtensionValid` does, extension terms match any dotted suffix of the base name, so `.gz`
+// matches `backup.tar.gz`, and MIME terms match the MIME type of path from `mimetypes.TypeByPath`.
+func (s *Set) Match(path string) bool {
+	if s == nil {
+		return false
+	}
+
+	name := strings.ToLower(filepath.Base(path))
+	matched := false
+	for _, t := range s.terms {
+		if matched != t.exclude {
+			// The term cannot change the outcome.
+			continue
+		}
+		if t.match(path, name) {
+			matched = !t.exclude
+		}
+	}
+	return matched
+}
+
+// String returns the canonical form of the set. File types are written with their registered name,
+// extensions in lower case with their leading dot and MIME patterns in lower case. Parsing the
+// canonical form yields an equal set.
+func (s *Set) String() string {
+	if s == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	for i, t := range s.terms {
+		switch {
+		case t.exclude:
+			b.WriteString(" -")
+		case i > 0:
+			b.WriteString(",")
+		}
+		b.WriteString(t.value)
+	}
+	return b.String()
+}
+
+// match reports whether the term matches path, whose lower case base name is name.
+func (t term) match(path, name string) bool {
+	switch t.kind {
+	case fileTypeTerm:
+		if filetypes.HasExtension(t.fileType, filetypes.Wildcard) {
+			return true
+		}
+		_, owners := filetypes.Extension(name)
+		return slices.Contains(owners, t.fileType)
+	case extensionTerm:
+		for i := 0; i < len(name); i++ {
+			if name[i] == '.' && filetypes.MatchPattern(t.value, name[i:]) {
+				return true
+			}
+		}
+		return false
+	default:
+		return mimetypes.Match(t.value, mimetypes.TypeByPath(path))
+	}
+}
+
+// parser holds the state of a running Parse.
+type parser struct {
+	expr string
+	pos  int
+	set  *Set
+}
+
+// parse reads every term of the expression.
+func (p *parser) parse() error {
+	// operator is the pending operator and where it was found, it is empty at the start and after
+	// white space.
+	operator, operatorPos := "", 0
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.expr) {
+			break
+		}
+
+		switch c := p.expr[p.pos]; {
+		case c == ',' || c == '+':
+			if operator != "" || len(p.set.terms) == 0 {
+				return p.fail(string(c), p.pos, ErrMissingTerm)
+			}
+			operator, operatorPos = string(c), p.pos
+			p.pos++
+		case c == '-':
+			if operator == "-" {
+				return p.fail(operator, operatorPos, ErrMissingTerm)
+			}
+			operator, operatorPos = "-", p.pos
+			p.pos++
+		default:
+			start := p.pos
+			t, err := p.term(p.word())
+			if err != nil {
+				return p.fail(p.expr[start:p.pos], start, err)
+			}
+			t.exclude = operator == "-"
+			if t.exclude && len(p.set.terms) == 0 {
+				p.set.terms = append(p.set.terms, term{kind: fileTypeTerm, fileType: types.FileTypes.Any, value: string(types.FileTypes.Any)})
+			}
+			p.set.terms = append(p.set.terms, t)
+			operator = ""
+		}
+	}
+
+	if operator != "" {
+		return p.fail(operator, operatorPos, ErrMissingTerm)
+	}
+	if len(p.set.terms) == 0 {
+		return p.fail(p.expr, 0, ErrEmpty)
+	}
+	return nil
+}
+
+// word reads a term. A term ends at white space or `,`, and at `+` unless it is a MIME pattern such as
+// `image/svg+xml`.
+func (p *parser) word() string {
+	start := p.pos
+	for p.pos < len(p.expr) {
+		c := rune(p.expr[p.pos])
+		if unicode.IsSpace(c) || c == ',' || (c == '+' && !strings.Contains(p.expr[start:p.pos], "/")) {
+			break
+		}
+		p.pos++
+	}
+	return p.expr[start:p.pos]
+}
+
+// term resolves word to a normalized term.
+func (p *parser) term(word string) (term, error) {
+	switch {
+	case strings.Contains(word, "/"):
+		pattern, err := mimetypes.Normalize(word)
+		if err != nil {
+			return term{}, mimetypes.ErrInvalidPattern
+		}
+		return term{kind: mimeTerm, value: pattern}, nil
+	case strings.HasPrefix(word, ".") || strings.HasPrefix(word, "*."):
+		ext := "." + strings.ToLower(strings.TrimLeft(word, "*."))
+		if ext == "." {
+			return term{}, ErrUnknownTerm
+		}
+		return term{kind: extensionTerm, value: ext}, nil
+	}
+
+	if fileType, ok := filetypes.Resolve(word); ok {
+		return term{kind: fileTypeTerm, fileType: fileType, value: string(fileType)}, nil
+	}
+
+	// A bare word that is not a file type is an extension, as long as a file type claims it, so that a
+	// misspelled file type is reported rather than silently matching nothing.
+	ext := "." + strings.ToLower(word)
+	for _, def := range filetypes.List() {
+		if slices.Contains(def.Extensions, ext) {
+			return term{kind: extensionTerm, value: ext}, nil
+		}
+	}
+	return term{}, ErrUnknownTerm
+}
+
+// skipSpace advances past white space.
+func (p *parser) skipSpace() {
+	for p.pos < len(p.expr) && unicode.IsSpace(rune(p.expr[p.pos])) {
+		p.pos++
+	}
+}
+
+// fail returns a `ParseError` for token found at the byte offset pos.
+func (p *parser) fail(token string, pos int, err error) error {
+	return &ParseError{Expr: p.expr, Token: token, Column: pos + 1, Err: err}
+}
@@ -0,0 +1,123 @@
+package fileset
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils/mimetypes"
+)
+
+// TestParse tests that expressions parse to their canonical form.
+func TestParse(t *testing.T) {
+	tests := []*types.TestLayout[string, string]{
+		{Name: "Single file type", Input: "video", Expected: "Video"},
+		{Name: "Union with comma", Input: "video,image", Expected: "Video,Image"},
+		{Name: "Union with white space", Input: "  videos   IMAGES ", Expected: "Video,Image"},
+		{Name: "Union with plus", Input: "archive+.pdf", Expected: "Archive,.pdf"},
+		{Name: "Exclusions", Input: "documents -csv -md", Expected: "Documents -.csv -.md"},
+		{Name: "Comma before exclusion", Input: "docs,-.CSV", Expected: "Documents -.csv"},
+		{Name: "Glob extension", Input: "*.tar.gz", Expected: ".tar.gz"},
+		{Name: "Volume pattern", Input: ".7z.#", Expected: ".7z.#"},
+		{Name: "MIME pattern", Input: "Video/*,image/svg+xml", Expected: "video/*,image/svg+xml"},
+		{Name: "Leading exclusion starts from any", Input: "-video", Expected: "Any -Video"},
+		{Name: "Re-inclusion", Input: "archive -gz +.tar.gz", Expected: "Archive -.gz,.tar.gz"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			set, err := Parse(test.Input)
+			if err != nil {
+				t.Fatalf("Parse(%q) - %v error = %v", test.Input, test.Name, err)
+			}
+			if result := set.String(); result != test.Expected {
+				t.Errorf("Parse(%q).String() - %v = %q; want %q", test.Input, test.Name, result, test.Expected)
+			}
+
+			again, err := Parse(set.String())
+			if err != nil {
+				t.Fatalf("Parse(%q) - %v round trip error = %v", set.String(), test.Name, err)
+			}
+			if again.String() != set.String() {
+				t.Errorf("Parse(%q).String() - %v round trip = %q; want %q", set.String(), test.Name, again.String(), set.String())
+			}
+		})
+	}
+}
+
+// TestParse_Errors tests that parse errors point at the offending token.
+func TestParse_Errors(t *testing.T) {
+	type ExpectedResults struct {
+		token  string
+		column int
+	}
+
+	tests := []*types.TestLayout[string, ExpectedResults]{
+		{Name: "Empty", Input: "  ", Expected: ExpectedResults{token: "  ", column: 1}, Err: ErrEmpty},
+		{Name: "Misspelled file type", Input: "video,imgae", Expected: ExpectedResults{token: "imgae", column: 7}, Err: ErrUnknownTerm},
+		{Name: "Unknown bare extension", Input: "documents -cvs", Expected: ExpectedResults{token: "cvs", column: 12}, Err: ErrUnknownTerm},
+		{Name: "Empty extension", Input: "video .", Expected: ExpectedResults{token: ".", column: 7}, Err: ErrUnknownTerm},
+		{Name: "Invalid MIME pattern", Input: "*/mp4", Expected: ExpectedResults{token: "*/mp4", column: 1}, Err: mimetypes.ErrInvalidPattern},
+		{Name: "Leading comma", Input: ",video", Expected: ExpectedResults{token: ",", column: 1}, Err: ErrMissingTerm},
+		{Name: "Double comma", Input: "video,,image", Expected: ExpectedResults{token: ",", column: 7}, Err: ErrMissingTerm},
+		{Name: "Double minus", Input: "video --mp4", Expected: ExpectedResults{token: "-", column: 7}, Err: ErrMissingTerm},
+		{Name: "Trailing operator", Input: "video +", Expected: ExpectedResults{token: "+", column: 7}, Err: ErrMissingTerm},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := Parse(test.Input)
+			if !errors.Is(err, test.Err) {
+				t.Fatalf("Parse(%q) - %v error = %v; want %v", test.Input, test.Name, err, test.Err)
+			}
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Parse(%q) - %v error = %T; want *ParseError", test.Input, test.Name, err)
+			}
+			if parseErr.Token != test.Expected.token || parseErr.Column != test.Expected.column {
+				t.Errorf("Parse(%q) - %v error at %q column %d; want %q column %d", test.Input, test.Name, parseErr.Token, parseErr.Column, test.Expected.token, test.Expected.column)
+			}
+		})
+	}
+}
+
+// TestSet_Match tests the Match func.
+func TestSet_Match(t *testing.T) {
+	type InputStruct struct {
+		expr string
+		path string
+	}
+
+	tests := []*types.TestLayout[InputStruct, bool]{
+		{Name: "Union first", Input: InputStruct{expr: "video,image", path: "/media/movie.mkv"}, Expected: true},
+		{Name: "Union second", Input: InputStruct{expr: "video,image", path: "/media/cat.JPG"}, Expected: true},
+		{Name: "Union neither", Input: InputStruct{expr: "video,image", path: "/media/notes.txt"}, Expected: false},
+		{Name: "Exclusion keeps the rest", Input: InputStruct{expr: "documents -csv -md", path: "report.pdf"}, Expected: true},
+		{Name: "Exclusion removes", Input: InputStruct{expr: "documents -csv -md", path: "data.CSV"}, Expected: false},
+		{Name: "Added extension", Input: InputStruct{expr: "archive+.pdf", path: "manual.pdf"}, Expected: true},
+		{Name: "Added extension keeps file type", Input: InputStruct{expr: "archive+.pdf", path: "backup.tar.gz"}, Expected: true},
+		{Name: "Excluded suffix of compound extension", Input: InputStruct{expr: "archive -gz", path: "backup.tar.gz"}, Expected: false},
+		{Name: "Later inclusion wins", Input: InputStruct{expr: "archive -gz +.tar.gz", path: "backup.tar.gz"}, Expected: true},
+		{Name: "Later inclusion is specific", Input: InputStruct{expr: "archive -gz +.tar.gz", path: "notes.gz"}, Expected: false},
+		{Name: "Volume pattern", Input: InputStruct{expr: ".7z.#", path: "backup.7z.002"}, Expected: true},
+		{Name: "Leading exclusion", Input: InputStruct{expr: "-video", path: "README"}, Expected: true},
+		{Name: "Leading exclusion removes", Input: InputStruct{expr: "-video", path: "movie.mp4"}, Expected: false},
+		{Name: "MIME pattern", Input: InputStruct{expr: "image/*", path: "drawing.svg"}, Expected: true},
+		{Name: "MIME pattern exclusion", Input: InputStruct{expr: "documents -text/*", path: "notes.md"}, Expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			set := MustParse(test.Input.expr)
+			if result := set.Match(test.Input.path); result != test.Expected {
+				t.Errorf("Parse(%q).Match(%q) - %v = %v; want %v", test.Input.expr, test.Input.path, test.Name, result, test.Expected)
+			}
+		})
+	}
+
+	var empty *Set
+	if empty.Match("movie.mp4") || empty.String() != "" {
+		t.Errorf("nil Set matched or printed a term")
+	}
+}
@@ -0,0 +1,380 @@
+package filetypes
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ondrovic/common/types"
+)
+
+const (
+	// Wildcard is the extension that makes a file type match every file.
+	Wildcard = "*.*"
+	// Digits is the placeholder for a run of one or more digits within an extension, such as `.part#.rar`
+	// or `.7z.#` for the volumes of multi-part archives.
+	Digits = "#"
+)
+
+var (
+	// ErrInvalidDefinition is returned when registering a definition without a name or with an empty
+	// extension or alias.
+	ErrInvalidDefinition = errors.New("invalid file type definition")
+	// ErrConflict is returned when a name or alias of a definition is already used by another file type.
+	ErrConflict = errors.New("file type name conflict")
+)
+
+// Registry holds the file types known to an application. Each file type has a canonical name, any
+// number of aliases, the extensions that belong to it and a description. Extensions may be compound,
+// such as `.tar.gz`, and may hold the `Digits` placeholder. Registry is safe for concurrent use.
+type Registry struct {
+	mu         sync.RWMutex
+	defs       map[types.FileType]types.FileTypeDefinition
+	extensions map[types.FileType]map[string]bool
+	patterns   map[types.FileType][]string
+	names      map[string]types.FileType
+	order      []types.FileType
+}
+
+// Default is the registry used by `utils.ToFileType`, `utils.IsExtensionValid` and the package level
+// functions. It starts out holding the built-in file types, copied from `types.FileExtensions` at
+// package initialisation, so later writes to that map are not seen. To add an extension to a built-in
+// file type, register its definition again:
+//
+//	def, _ := filetypes.Get(types.FileTypes.Video)
+//	def.Extensions = append(def.Extensions, ".ogm")
+//	err := filetypes.Register(def)
+var Default = newDefault()
+
+// builtinInfo holds the aliases and description of each built-in file type.
+var builtinInfo = map[types.FileType]struct {
+	aliases     []string
+	description string
+}{
+	types.FileTypes.Any:         {aliases: []string{"all"}, description: "Any file"},
+	types.FileTypes.Video:       {aliases: []string{"videos"}, description: "Video files"},
+	types.FileTypes.Image:       {aliases: []string{"images"}, description: "Image files"},
+	types.FileTypes.Archive:     {aliases: []string{"archives"}, description: "Archive files"},
+	types.FileTypes.Documents:   {aliases: []string{"document", "docs"}, description: "Document files"},
+	types.FileTypes.Audio:       {aliases: []string{"music", "sound"}, description: "Audio files"},
+	types.FileTypes.Code:        {aliases: []string{"source", "src"}, description: "Source code files"},
+	types.FileTypes.Fonts:       {aliases: []string{"font"}, description: "Font files"},
+	types.FileTypes.Ebooks:      {aliases: []string{"ebook", "books"}, description: "Ebook files"},
+	types.FileTypes.Executables: {aliases: []string{"executable", "installers", "programs"}, description: "Executables and installers"},
+	types.FileTypes.DiskImages:  {aliases: []string{"diskimage", "disk images", "disk-images", "vm"}, description: "Disk and virtual machine images"},
+	types.FileTypes.Subtitles:   {aliases: []string{"subtitle", "subs", "captions"}, description: "Subtitle files"},
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		defs:       map[types.FileType]types.FileTypeDefinition{},
+		extensions: map[types.FileType]map[string]bool{},
+		patterns:   map[types.FileType][]string{},
+		names:      map[string]types.FileType{},
+	}
+}
+
+// Builtins returns the definitions of the built-in file types, built from `types.FileExtensions`.
+func Builtins() []types.FileTypeDefinition {
+	defs := []types.FileTypeDefinition{}
+	for _, fileType := range []types.FileType{
+		types.FileTypes.Any,
+		types.FileTypes.Video,
+		types.FileTypes.Image,
+		types.FileTypes.Archive,
+		types.FileTypes.Documents,
+		types.FileTypes.Audio,
+		types.FileTypes.Code,
+		types.FileTypes.Fonts,
+		types.FileTypes.Ebooks,
+		types.FileTypes.Executables,
+		types.FileTypes.DiskImages,
+		types.FileTypes.Subtitles,
+	} {
+		extensions := []string{}
+		for ext := range types.FileExtensions[fileType] {
+			extensions = append(extensions, ext)
+		}
+		sort.Strings(extensions)
+
+		info := builtinInfo[fileType]
+		defs = append(defs, types.FileTypeDefinition{
+			Name:        fileType,
+			Aliases:     append([]string(nil), info.aliases...),
+			Extensions:  extensions,
+			Description: info.description,
+		})
+	}
+	return defs
+}
+
+// Register adds def to the registry, or replaces the definition registered under the same name while
+// keeping its position in `List`. Names, aliases and extensions are matched without regard to case and
+// extensions may be given with or without the leading dot.
+func (r *Registry) Register(def types.FileTypeDefinition) error {
+	def, err := normalize(def)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range keys(def) {
+		if owner, ok := r.names[key]; ok && owner != def.Name {
+			return fmt.Errorf("%w: %q is already used by %s", ErrConflict, key, owner)
+		}
+	}
+
+	if previous, ok := r.defs[def.Name]; ok {
+		for _, key := range keys(previous) {
+			delete(r.names, key)
+		}
+	} else {
+		r.order = append(r.order, def.Name)
+	}
+
+	extensions := make(map[string]bool, len(def.Extensions))
+	patterns := []string{}
+	for _, ext := range def.Extensions {
+		extensions[ext] = true
+		if strings.Contains(ext, Digits) {
+			patterns = append(patterns, ext)
+		}
+	}
+	for _, key := range keys(def) {
+		r.names[key] = def.Name
+	}
+	r.defs[def.Name] = def
+	r.extensions[def.Name] = extensions
+	r.patterns[def.Name] = patterns
+	return nil
+}
+
+// Unregister removes fileType from the registry, it returns false when it was not registered.
+func (r *Registry) Unregister(fileType types.FileType) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	def, ok := r.defs[fileType]
+	if !ok {
+		return false
+	}
+
+	for _, key := range keys(def) {
+		delete(r.names, key)
+	}
+	delete(r.defs, fileType)
+	delete(r.extensions, fileType)
+	delete(r.patterns, fileType)
+	for i, name := range r.order {
+		if name == fileType {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Resolve returns the file type whose name or alias is name, ignoring case.
+func (r *Registry) Resolve(name string) (types.FileType, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	fileType, ok := r.names[strings.ToLower(strings.TrimSpace(name))]
+	return fileType, ok
+}
+
+// Get returns a copy of the definition of fileType.
+func (r *Registry) Get(fileType types.FileType) (types.FileTypeDefinition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	def, ok := r.defs[fileType]
+	return clone(def), ok
+}
+
+// HasExtension reports whether ext, given in lower case with its leading dot, belongs to fileType. A
+// file type holding the wildcard matches every extension, including none.
+func (r *Registry) HasExtension(fileType types.FileType, ext string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	extensions, ok := r.extensions[fileType]
+	if !ok {
+		return false
+	}
+	return extensions[Wildcard] || r.claims(fileType, ext)
+}
+
+// Extension returns the longest registered extension that name ends with, as it is written in name,
+// along with every file type claiming it in registration order. For `backup.tar.gz` that is `.tar.gz`
+// rather than `.gz`. Only the base name is considered and wildcard file types are left out. It returns
+// an empty extension and no file types when nothing claims name.
+func (r *Registry) Extension(name string) (string, []types.FileType) {
+	base := filepath.Base(name)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := 0; i < len(base); i++ {
+		if base[i] != '.' {
+			continue
+		}
+		ext := strings.ToLower(base[i:])
+		owners := []types.FileType{}
+		for _, fileType := range r.order {
+			if r.claims(fileType, ext) {
+				owners = append(owners, fileType)
+			}
+		}
+		if len(owners) > 0 {
+			return base[i:], owners
+		}
+	}
+	return "", nil
+}
+
+// List returns a copy of every definition in the order they were first registered.
+func (r *Registry) List() []types.FileTypeDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	defs := make([]types.FileTypeDefinition, 0, len(r.order))
+	for _, name := range r.order {
+		defs = append(defs, clone(r.defs[name]))
+	}
+	return defs
+}
+
+// Register adds or replaces def in the `Default` registry.
+func Register(def types.FileTypeDefinition) error {
+	return Default.Register(def)
+}
+
+// Unregister removes fileType from the `Default` registry.
+func Unregister(fileType types.FileType) bool {
+	return Default.Unregister(fileType)
+}
+
+// Resolve looks up name in the `Default` registry.
+func Resolve(name string) (types.FileType, bool) {
+	return Default.Resolve(name)
+}
+
+// Get returns the definition of fileType from the `Default` registry.
+func Get(fileType types.FileType) (types.FileTypeDefinition, bool) {
+	return Default.Get(fileType)
+}
+
+// HasExtension reports whether ext belongs to fileType in the `Default` registry.
+func HasExtension(fileType types.FileType, ext string) bool {
+	return Default.HasExtension(fileType, ext)
+}
+
+// Extension returns the longest extension of name registered in the `Default` registry.
+func Extension(name string) (string, []types.FileType) {
+	return Default.Extension(name)
+}
+
+// List returns every definition in the `Default` registry.
+func List() []types.FileTypeDefinition {
+	return Default.List()
+}
+
+// newDefault returns a registry holding the built-in file types.
+func newDefault() *Registry {
+	r := NewRegistry()
+	for _, def := range Builtins() {
+		if err := r.Register(def); err != nil {
+			panic(fmt.Sprintf("registering built-in file type %s: %v", def.Name, err))
+		}
+	}
+	return r
+}
+
+// normalize validates def and returns a copy with trimmed aliases and lower case, dotted extensions.
+func normalize(def types.FileTypeDefinition) (types.FileTypeDefinition, error) {
+	name := types.FileType(strings.TrimSpace(string(def.Name)))
+	if name == "" {
+		return def, fmt.Errorf("%w: name cannot be empty", ErrInvalidDefinition)
+	}
+
+	normalized := types.FileTypeDefinition{Name: name, Description: def.Description}
+	for _, alias := range def.Aliases {
+		alias = strings.TrimSpace(alias)
+		if alias == "" {
+			return def, fmt.Errorf("%w: %s has an empty alias", ErrInvalidDefinition, name)
+		}
+		normalized.Aliases = append(normalized.Aliases, alias)
+	}
+	for _, ext := range def.Extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext != Wildcard {
+			ext = "." + strings.TrimLeft(ext, "*.")
+		}
+		if ext == "." {
+			return def, fmt.Errorf("%w: %s has an empty extension", ErrInvalidDefinition, name)
+		}
+		normalized.Extensions = append(normalized.Extensions, ext)
+	}
+	return normalized, nil
+}
+
+// claims reports whether ext, in lower case, is one of the extensions of fileType or matches one of
+// its patterns. The caller must hold the lock.
+func (r *Registry) claims(fileType types.FileType, ext string) bool {
+	if r.extensions[fileType][ext] {
+		return true
+	}
+	for _, pattern := range r.patterns[fileType] {
+		if MatchPattern(pattern, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPattern reports whether ext matches the registered extension pattern, where each `Digits`
+// placeholder in pattern stands for a run of one or more digits. Both are expected in lower case.
+func MatchPattern(pattern, ext string) bool {
+	for pattern != "" {
+		if !strings.HasPrefix(pattern, Digits) {
+			if ext == "" || pattern[0] != ext[0] {
+				return false
+			}
+			pattern, ext = pattern[1:], ext[1:]
+			continue
+		}
+
+		n := 0
+		for n < len(ext) && ext[n] >= '0' && ext[n] <= '9' {
+			n++
+		}
+		if n == 0 {
+			return false
+		}
+		pattern, ext = pattern[len(Digits):], ext[n:]
+	}
+	return ext == ""
+}
+
+// keys returns the lower case lookup keys of def, its name followed by its aliases.
+func keys(def types.FileTypeDefinition) []string {
+	keys := []string{strings.ToLower(string(def.Name))}
+	for _, alias := range def.Aliases {
+		keys = append(keys, strings.ToLower(alias))
+	}
+	return keys
+}
+
+// clone returns a copy of def that shares no slices with it.
+func clone(def types.FileTypeDefinition) types.FileTypeDefinition {
+	def.Aliases = append([]string(nil), def.Aliases...)
+	def.Extensions = append([]string(nil), def.Extensions...)
+	return def
+}
@@ -0,0 +1,256 @@
+package filetypes
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/ondrovic/common/types"
+)
+
+// lyrics is a custom definition used across tests.
+var lyrics = types.FileTypeDefinition{
+	Name:        "Lyrics",
+	Aliases:     []string{"lyric"},
+	Extensions:  []string{".LRC", "elrc", "*.txtl"},
+	Description: "Lyrics files",
+}
+
+// TestDefault tests that the default registry holds the built-in file types.
+func TestDefault(t *testing.T) {
+	names := []types.FileType{}
+	for _, def := range List() {
+		names = append(names, def.Name)
+	}
+	expected := []types.FileType{
+		types.FileTypes.Any, types.FileTypes.Video, types.FileTypes.Image, types.FileTypes.Archive, types.FileTypes.Documents,
+		types.FileTypes.Audio, types.FileTypes.Code, types.FileTypes.Fonts, types.FileTypes.Ebooks, types.FileTypes.Executables,
+		types.FileTypes.DiskImages, types.FileTypes.Subtitles,
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("List() = %v; want %v", names, expected)
+	}
+
+	for fileType, extensions := range types.FileExtensions {
+		for ext := range extensions {
+			if !HasExtension(fileType, ext) {
+				t.Errorf("HasExtension(%q, %q) = false; want true", fileType, ext)
+			}
+		}
+	}
+}
+
+// TestResolve tests the Resolve func.
+func TestResolve(t *testing.T) {
+	tests := []*types.TestLayout[string, types.FileType]{
+		{Name: "Name", Input: "Video", Expected: types.FileTypes.Video},
+		{Name: "Lower case name", Input: "documents", Expected: types.FileTypes.Documents},
+		{Name: "Alias", Input: "docs", Expected: types.FileTypes.Documents},
+		{Name: "Alias with spaces and case", Input: "  IMAGES ", Expected: types.FileTypes.Image},
+		{Name: "New built-in name", Input: "diskimages", Expected: types.FileTypes.DiskImages},
+		{Name: "New built-in alias", Input: "Disk Images", Expected: types.FileTypes.DiskImages},
+		{Name: "Custom name", Input: "lyrics", Expected: "Lyrics"},
+		{Name: "Custom alias", Input: "LYRIC", Expected: "Lyrics"},
+		{Name: "Unknown", Input: "unknown", Expected: ""},
+		{Name: "Empty", Input: "", Expected: ""},
+	}
+
+	r := newDefault()
+	if err := r.Register(lyrics); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, ok := r.Resolve(test.Input)
+			if result != test.Expected || ok != (test.Expected != "") {
+				t.Errorf("Resolve(%q) - %v = %q, %v; want %q", test.Input, test.Name, result, ok, test.Expected)
+			}
+		})
+	}
+}
+
+// TestHasExtension tests the HasExtension func.
+func TestHasExtension(t *testing.T) {
+	type InputStruct struct {
+		fileType types.FileType
+		ext      string
+	}
+
+	tests := []*types.TestLayout[InputStruct, bool]{
+		{Name: "Built-in extension", Input: InputStruct{fileType: types.FileTypes.Video, ext: ".mp4"}, Expected: true},
+		{Name: "Wildcard matches anything", Input: InputStruct{fileType: types.FileTypes.Any, ext: ".whatever"}, Expected: true},
+		{Name: "Wildcard matches no extension", Input: InputStruct{fileType: types.FileTypes.Any, ext: ""}, Expected: true},
+		{Name: "Upper case extension is normalized", Input: InputStruct{fileType: "Lyrics", ext: ".lrc"}, Expected: true},
+		{Name: "Extension without dot is normalized", Input: InputStruct{fileType: "Lyrics", ext: ".elrc"}, Expected: true},
+		{Name: "Glob extension is normalized", Input: InputStruct{fileType: "Lyrics", ext: ".txtl"}, Expected: true},
+		{Name: "Other extension", Input: InputStruct{fileType: "Lyrics", ext: ".mp4"}, Expected: false},
+		{Name: "Compound extension", Input: InputStruct{fileType: types.FileTypes.Archive, ext: ".tar.gz"}, Expected: true},
+		{Name: "Digits placeholder", Input: InputStruct{fileType: types.FileTypes.Archive, ext: ".part12.rar"}, Expected: true},
+		{Name: "Digits placeholder needs a digit", Input: InputStruct{fileType: types.FileTypes.Archive, ext: ".part.rar"}, Expected: false},
+		{Name: "Digits placeholder only matches digits", Input: InputStruct{fileType: types.FileTypes.Archive, ext: ".7z.00a"}, Expected: false},
+		{Name: "Unknown file type", Input: InputStruct{fileType: "unknown", ext: ".mp4"}, Expected: false},
+	}
+
+	r := newDefault()
+	if err := r.Register(lyrics); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if result := r.HasExtension(test.Input.fileType, test.Input.ext); result != test.Expected {
+				t.Errorf("HasExtension(%q, %q) - %v = %v; want %v", test.Input.fileType, test.Input.ext, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestExtension tests the Extension func.
+func TestExtension(t *testing.T) {
+	type ExpectedResults struct {
+		ext    string
+		owners []types.FileType
+	}
+
+	tests := []*types.TestLayout[string, ExpectedResults]{
+		{Name: "Single extension", Input: "/media/movie.mp4", Expected: ExpectedResults{ext: ".mp4", owners: []types.FileType{types.FileTypes.Video}}},
+		{Name: "Longest match wins", Input: "backup.tar.gz", Expected: ExpectedResults{ext: ".tar.gz", owners: []types.FileType{types.FileTypes.Archive}}},
+		{Name: "Case is kept", Input: "BACKUP.Tar.Gz", Expected: ExpectedResults{ext: ".Tar.Gz", owners: []types.FileType{types.FileTypes.Archive}}},
+		{Name: "Unclaimed compound falls back to suffix", Input: "notes.old.gz", Expected: ExpectedResults{ext: ".gz", owners: []types.FileType{types.FileTypes.Archive}}},
+		{Name: "Multi-part archive", Input: "movie.part3.rar", Expected: ExpectedResults{ext: ".part3.rar", owners: []types.FileType{types.FileTypes.Archive}}},
+		{Name: "Custom compound extension", Input: "song.en.lrc", Expected: ExpectedResults{ext: ".en.lrc", owners: []types.FileType{"Lyrics"}}},
+		{Name: "Shared extension", Input: "clip.ts", Expected: ExpectedResults{ext: ".ts", owners: []types.FileType{types.FileTypes.Video, "Lyrics"}}},
+		{Name: "Shared built-in extension", Input: "ubuntu.iso", Expected: ExpectedResults{ext: ".iso", owners: []types.FileType{types.FileTypes.Archive, types.FileTypes.DiskImages}}},
+		{Name: "Unknown extension", Input: "program.xyz", Expected: ExpectedResults{ext: "", owners: nil}},
+		{Name: "No extension", Input: "README", Expected: ExpectedResults{ext: "", owners: nil}},
+	}
+
+	r := newDefault()
+	if err := r.Register(types.FileTypeDefinition{Name: "Lyrics", Extensions: []string{".en.lrc", ".ts"}}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ext, owners := r.Extension(test.Input)
+			if ext != test.Expected.ext || !reflect.DeepEqual(owners, test.Expected.owners) {
+				t.Errorf("Extension(%q) - %v = %q, %v; want %q, %v", test.Input, test.Name, ext, owners, test.Expected.ext, test.Expected.owners)
+			}
+		})
+	}
+}
+
+// TestRegister_Override tests that registering an existing name replaces it in place.
+func TestRegister_Override(t *testing.T) {
+	r := newDefault()
+	override := types.FileTypeDefinition{Name: types.FileTypes.Video, Aliases: []string{"movies"}, Extensions: []string{".mp4"}, Description: "Movies only"}
+	if err := r.Register(override); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	if r.HasExtension(types.FileTypes.Video, ".mkv") {
+		t.Errorf("HasExtension() after override kept .mkv")
+	}
+	if _, ok := r.Resolve("videos"); ok {
+		t.Errorf("Resolve() after override kept the old alias")
+	}
+	if fileType, _ := r.Resolve("movies"); fileType != types.FileTypes.Video {
+		t.Errorf("Resolve(%q) = %q; want %q", "movies", fileType, types.FileTypes.Video)
+	}
+	if defs := r.List(); defs[1].Name != types.FileTypes.Video || defs[1].Description != "Movies only" {
+		t.Errorf("List() after override = %+v; want Video second with the new description", defs)
+	}
+}
+
+// TestRegister_Errors tests Register error handling.
+func TestRegister_Errors(t *testing.T) {
+	tests := []*types.TestLayout[types.FileTypeDefinition, error]{
+		{Name: "Empty name", Input: types.FileTypeDefinition{Name: " "}, Expected: ErrInvalidDefinition},
+		{Name: "Empty alias", Input: types.FileTypeDefinition{Name: "Custom", Aliases: []string{""}}, Expected: ErrInvalidDefinition},
+		{Name: "Empty extension", Input: types.FileTypeDefinition{Name: "Custom", Extensions: []string{"."}}, Expected: ErrInvalidDefinition},
+		{Name: "Alias used by another type", Input: types.FileTypeDefinition{Name: "Custom", Aliases: []string{"Docs"}}, Expected: ErrConflict},
+		{Name: "Name used as another alias", Input: types.FileTypeDefinition{Name: "videos"}, Expected: ErrConflict},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			r := newDefault()
+			if err := r.Register(test.Input); !errors.Is(err, test.Expected) {
+				t.Errorf("Register(%+v) - %v error = %v; want %v", test.Input, test.Name, err, test.Expected)
+			}
+			if len(r.List()) != len(Builtins()) {
+				t.Errorf("Register(%+v) - %v changed the registry", test.Input, test.Name)
+			}
+		})
+	}
+}
+
+// TestUnregister tests the Unregister func.
+func TestUnregister(t *testing.T) {
+	r := newDefault()
+	if err := r.Register(lyrics); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	if !r.Unregister("Lyrics") {
+		t.Fatalf("Unregister() = false; want true")
+	}
+	if r.Unregister("Lyrics") {
+		t.Errorf("Unregister() twice = true; want false")
+	}
+	if _, ok := r.Resolve("lyric"); ok {
+		t.Errorf("Resolve() after Unregister still finds the alias")
+	}
+	if r.HasExtension("Lyrics", ".lrc") {
+		t.Errorf("HasExtension() after Unregister = true; want false")
+	}
+	if len(r.List()) != len(Builtins()) {
+		t.Errorf("List() after Unregister = %v", r.List())
+	}
+}
+
+// TestGet tests that Get and List return copies.
+func TestGet(t *testing.T) {
+	r := newDefault()
+	def, ok := r.Get(types.FileTypes.Video)
+	if !ok || def.Description != "Video files" {
+		t.Fatalf("Get() = %+v, %v", def, ok)
+	}
+
+	def.Extensions[0] = ".changed"
+	r.List()[1].Aliases[0] = "changed"
+	if again, _ := r.Get(types.FileTypes.Video); again.Extensions[0] == ".changed" || again.Aliases[0] == "changed" {
+		t.Errorf("Get() returned a definition sharing memory with the registry")
+	}
+
+	if _, ok := r.Get("unknown"); ok {
+		t.Errorf("Get() unknown = true; want false")
+	}
+}
+
+// TestRegistry_Concurrent tests that a registry can be used from several goroutines.
+func TestRegistry_Concurrent(t *testing.T) {
+	r := newDefault()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := types.FileType(fmt.Sprintf("Custom%d", i))
+			if err := r.Register(types.FileTypeDefinition{Name: name, Extensions: []string{fmt.Sprintf(".c%d", i)}}); err != nil {
+				t.Errorf("Register() error = %v", err)
+			}
+			r.Resolve("video")
+			r.HasExtension(name, fmt.Sprintf(".c%d", i))
+			r.List()
+		}(i)
+	}
+	wg.Wait()
+
+	if len(r.List()) != len(Builtins())+20 {
+		t.Errorf("List() after concurrent registers = %d entries; want %d", len(r.List()), len(Builtins())+20)
+	}
+}
@@ -0,0 +1,507 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils"
+	"github.com/ondrovic/common/utils/timefilter"
+)
+
+var (
+	// ErrEmpty is returned when parsing an expression without any comparisons.
+	ErrEmpty = errors.New("empty filter expression")
+	// ErrUnexpected is returned for a token that cannot appear where it was found.
+	ErrUnexpected = errors.New("unexpected token")
+	// ErrUnknownField is returned for a comparison on a field the filter does not know.
+	ErrUnknownField = errors.New("unknown field")
+	// ErrOperator is returned for an operator that is unknown or not supported by its field.
+	ErrOperator = errors.New("invalid operator")
+	// ErrValue is returned for a value that cannot be read for its field.
+	ErrValue = errors.New("invalid value")
+)
+
+// The ParseError struct describes where a filter expression failed to parse. It wraps one of
+// `ErrEmpty`, `ErrUnexpected`, `ErrUnknownField`, `ErrOperator` or `ErrValue`.
+type ParseError struct {
+	// Expr is the expression being parsed.
+	Expr string
+	// Token is the offending token, it is empty at the end of the expression.
+	Token string
+	// Column is the 1-based byte column of Token in Expr.
+	Column int
+	// Err is the reason the token was rejected.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("%v at end of %q, column %d", e.Err, e.Expr, e.Column)
+	}
+	return fmt.Sprintf("%v %q at column %d of %q", e.Err, e.Token, e.Column, e.Expr)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// predicate reports whether a file matches, now is the time file ages are measured from.
+type predicate func(file types.ScanMatch, now time.Time) bool
+
+// The Filter struct is a compiled filter expression.
+type Filter struct {
+	expr  string
+	match predicate
+}
+
+// Parse compiles a filter expression, such as
+//
+//	type=video && size >= 700MB && (mtime < 30d || name ~ "sample")
+//
+// into a `Filter`. An expression combines comparisons with `&&`, `||` and `!`, or `and`, `or` and
+// `not`, and parentheses. `!` binds tightest and `&&` binds tighter than `||`. A comparison is a field,
+// an operator and a value:
+//
+//   - `type` is a file type name, alias or MIME pattern as read by `utils.ToFileType`, compared with
+//     `=` or `!=`.
+//   - `size` is a size such as `700MB`, read by `utils.ConvertStringSizeToBytes`, or a number of bytes.
+//   - `mtime` is either an age such as `90s`, `45m`, `30d` or `1y2mo`, read by
+//     `timefilter.ParseDuration`, so `mtime < 30d` matches files modified in the last 30 days, or a date
+//     such as `2024-05-06`, so `mtime < 2024-05-06` matches files modified before that day.
+//   - `name`, `path` and `ext` are the base name, the full path and the extension of the file, compared
+//     with `=` or `!=`, or with `~` and `!~` for a case-insensitive regular expression.
+//
+// Comparison operators are read by `utils.ToOperatorType`, so words such as `gte` work as well. Values
+// holding white space or operator characters are written between double quotes, with `\"` and `\\` as
+// escapes.
+func Parse(expr string) (*Filter, error) {
+	p := &parser{expr: expr}
+	p.next()
+	if p.tok.kind == endToken {
+		return nil, p.fail(p.tok, ErrEmpty)
+	}
+
+	match, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != endToken {
+		return nil, p.fail(p.tok, ErrUnexpected)
+	}
+	return &Filter{expr: expr, match: match}, nil
+}
+
+// MustParse is like `Parse` but panics on a malformed expression, with the `*ParseError` that `Parse`
+// returns, so the panic message points at the offending column.
+func MustParse(expr string) *Filter {
+	f, err := Parse(expr)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// Match reports whether file passes the filter, ages are measured from the current time. A nil
+// `Filter` matches every file.
+func (f *Filter) Match(file types.ScanMatch) bool {
+	return f.MatchAt(file, time.Now())
+}
+
+// MatchAt is like `Match` but measures ages from now.
+func (f *Filter) MatchAt(file types.ScanMatch, now time.Time) bool {
+	if f == nil {
+		return true
+	}
+	return f.match(file, now)
+}
+
+// String returns the expression the filter was parsed from.
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.expr
+}
+
+// tokenKind is the kind of a lexed token.
+type tokenKind int
+
+const (
+	endToken tokenKind = iota
+	wordToken
+	stringToken
+	operatorToken
+	andToken
+	orToken
+	notToken
+	openToken
+	closeToken
+	badToken
+)
+
+// token is a lexed token, pos is its byte offset in the expression.
+type token struct {
+	kind  tokenKind
+	text  string
+	value string
+	pos   int
+}
+
+// parser holds the state of a running Parse, tok is the current token.
+type parser struct {
+	expr string
+	pos  int
+	tok  token
+}
+
+// or reads `and {|| and}`.
+func (p *parser) or() (predicate, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == orToken {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = either(left, right)
+	}
+	return left, nil
+}
+
+// and reads `unary {&& unary}`.
+func (p *parser) and() (predicate, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == andToken {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = both(left, right)
+	}
+	return left, nil
+}
+
+// unary reads `! unary`, `( or )` or a comparison.
+func (p *parser) unary() (predicate, error) {
+	switch p.tok.kind {
+	case notToken:
+		p.next()
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(file types.ScanMatch, now time.Time) bool { return !operand(file, now) }, nil
+	case openToken:
+		p.next()
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != closeToken {
+			return nil, p.fail(p.tok, ErrUnexpected)
+		}
+		p.next()
+		return inner, nil
+	case wordToken:
+		return p.comparison()
+	default:
+		return nil, p.fail(p.tok, ErrUnexpected)
+	}
+}
+
+// comparison reads `field operator value`.
+func (p *parser) comparison() (predicate, error) {
+	field := p.tok
+	p.next()
+
+	operator := p.tok
+	if operator.kind == wordToken && utils.ToOperatorType(operator.text) != "" {
+		// A word operator such as `gte`.
+		operator.kind = operatorToken
+	}
+	if operator.kind != operatorToken {
+		return nil, p.fail(operator, ErrUnexpected)
+	}
+	p.next()
+
+	value := p.tok
+	if value.kind != wordToken && value.kind != stringToken {
+		return nil, p.fail(value, ErrUnexpected)
+	}
+	p.next()
+
+	var compile func(operator, value token) (predicate, error)
+	switch strings.ToLower(field.text) {
+	case "type":
+		compile = p.typeField
+	case "size":
+		compile = p.sizeField
+	case "mtime":
+		compile = p.mtimeField
+	case "name":
+		compile = p.textField(func(file types.ScanMatch) string { return filepath.Base(file.Path) }, sameText)
+	case "path":
+		compile = p.textField(func(file types.ScanMatch) string { return file.Path }, sameText)
+	case "ext":
+		compile = p.textField(func(file types.ScanMatch) string {
+			_, ext := utils.SplitExtension(filepath.Base(file.Path))
+			return strings.TrimPrefix(ext, ".")
+		}, sameExtension)
+	default:
+		return nil, p.fail(field, ErrUnknownField)
+	}
+	return compile(operator, value)
+}
+
+// typeField compiles a comparison on the file type.
+func (p *parser) typeField(operator, value token) (predicate, error) {
+	negate, err := p.equality(operator)
+	if err != nil {
+		return nil, err
+	}
+	fileType := utils.ToFileType(value.value)
+	if fileType == "" {
+		return nil, p.fail(value, fmt.Errorf("%w: unknown file type", ErrValue))
+	}
+
+	return func(file types.ScanMatch, _ time.Time) bool {
+		matched := file.FileType == fileType || utils.IsExtensionValid(fileType, file.Path)
+		return matched != negate
+	}, nil
+}
+
+// sizeField compiles a comparison on the file size.
+func (p *parser) sizeField(operator, value token) (predicate, error) {
+	op, err := p.ordering(operator)
+	if err != nil {
+		return nil, err
+	}
+	size, err := strconv.ParseInt(value.value, 10, 64)
+	if err != nil {
+		size, err = utils.ConvertStringSizeToBytes(value.value)
+	}
+	if err != nil || size < 0 {
+		return nil, p.fail(value, fmt.Errorf("%w: not a size", ErrValue))
+	}
+
+	return func(file types.ScanMatch, _ time.Time) bool {
+		matched, err := utils.GetOperatorSizeMatches(op, size, 0, file.Size)
+		return err == nil && matched
+	}, nil
+}
+
+// mtimeField compiles a comparison on the modification time, against an age or a date.
+func (p *parser) mtimeField(operator, value token) (predicate, error) {
+	op, err := p.ordering(operator)
+	if err != nil {
+		return nil, err
+	}
+
+	if day, err := time.ParseInLocation("2006-01-02", value.value, time.Local); err == nil {
+		next := day.AddDate(0, 0, 1)
+		return func(file types.ScanMatch, _ time.Time) bool {
+			switch op {
+			case types.OperatorTypes.LessThan:
+				return file.ModTime.Before(day)
+			case types.OperatorTypes.LessThanEqualTo:
+				return file.ModTime.Before(next)
+			case types.OperatorTypes.GreaterThan:
+				return !file.ModTime.Before(next)
+			case types.OperatorTypes.GreaterThanEqualTo:
+				return !file.ModTime.Before(day)
+			case types.OperatorTypes.NotEqualTo:
+				return file.ModTime.Before(day) || !file.ModTime.Before(next)
+			default:
+				return !file.ModTime.Before(day) && file.ModTime.Before(next)
+			}
+		}, nil
+	}
+
+	age, err := timefilter.ParseDuration(value.value)
+	if err != nil {
+		return nil, p.fail(value, fmt.Errorf("%w: %v", ErrValue, err))
+	}
+	if op == types.OperatorTypes.EqualTo || op == types.OperatorTypes.NotEqualTo {
+		return nil, p.fail(operator, fmt.Errorf("%w: ages cannot be compared with %s", ErrOperator, operator.text))
+	}
+	return func(file types.ScanMatch, now time.Time) bool {
+		matched, err := utils.GetOperatorSizeMatches(op, int64(age), 0, int64(now.Sub(file.ModTime)))
+		return err == nil && matched
+	}, nil
+}
+
+// textField returns a func compiling a comparison on the text get returns for a file, equal decides
+// whether that text is equal to a value.
+func (p *parser) textField(get func(file types.ScanMatch) string, equal func(text, value string) bool) func(operator, value token) (predicate, error) {
+	return func(operator, value token) (predicate, error) {
+		if operator.text == "~" || operator.text == "!~" {
+			pattern, err := regexp.Compile("(?i)" + value.value)
+			if err != nil {
+				return nil, p.fail(value, fmt.Errorf("%w: %v", ErrValue, err))
+			}
+			negate := operator.text == "!~"
+			return func(file types.ScanMatch, _ time.Time) bool { return pattern.MatchString(get(file)) != negate }, nil
+		}
+
+		negate, err := p.equality(operator)
+		if err != nil {
+			return nil, err
+		}
+		return func(file types.ScanMatch, _ time.Time) bool { return equal(get(file), value.value) != negate }, nil
+	}
+}
+
+// equality reads an `=` or `!=` operator and reports whether it is `!=`.
+func (p *parser) equality(operator token) (bool, error) {
+	switch utils.ToOperatorType(operator.text) {
+	case types.OperatorTypes.EqualTo:
+		return false, nil
+	case types.OperatorTypes.NotEqualTo:
+		return true, nil
+	default:
+		return false, p.fail(operator, ErrOperator)
+	}
+}
+
+// ordering reads a comparison operator.
+func (p *parser) ordering(operator token) (types.OperatorType, error) {
+	switch op := utils.ToOperatorType(operator.text); op {
+	case types.OperatorTypes.EqualTo, types.OperatorTypes.NotEqualTo,
+		types.OperatorTypes.LessThan, types.OperatorTypes.LessThanEqualTo,
+		types.OperatorTypes.GreaterThan, types.OperatorTypes.GreaterThanEqualTo:
+		return op, nil
+	default:
+		return "", p.fail(operator, ErrOperator)
+	}
+}
+
+// next reads the next token into p.tok. Text that starts no token becomes a `badToken`, which every
+// caller reports as unexpected.
+func (p *parser) next() {
+	for p.pos < len(p.expr) && unicode.IsSpace(rune(p.expr[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.expr) {
+		p.tok = token{kind: endToken, pos: start}
+		return
+	}
+
+	switch c := p.expr[p.pos]; {
+	case c == '(':
+		p.pos++
+		p.tok = token{kind: openToken, text: "(", pos: start}
+	case c == ')':
+		p.pos++
+		p.tok = token{kind: closeToken, text: ")", pos: start}
+	case strings.HasPrefix(p.expr[p.pos:], "&&"):
+		p.pos += 2
+		p.tok = token{kind: andToken, text: "&&", pos: start}
+	case strings.HasPrefix(p.expr[p.pos:], "||"):
+		p.pos += 2
+		p.tok = token{kind: orToken, text: "||", pos: start}
+	case c == '"':
+		p.tok = p.quoted()
+	case isOperator(c):
+		for p.pos < len(p.expr) && isOperator(p.expr[p.pos]) {
+			p.pos++
+		}
+		text := p.expr[start:p.pos]
+		if text == "!" {
+			p.tok = token{kind: notToken, text: text, pos: start}
+			return
+		}
+		p.tok = token{kind: operatorToken, text: text, pos: start}
+	default:
+		for p.pos < len(p.expr) && !isDelimiter(p.expr[p.pos]) {
+			p.pos++
+		}
+		if p.pos == start {
+			// A lone `&` or `|`.
+			p.pos++
+			p.tok = token{kind: badToken, text: p.expr[start:p.pos], pos: start}
+			return
+		}
+		text := p.expr[start:p.pos]
+		switch strings.ToLower(text) {
+		case "and":
+			p.tok = token{kind: andToken, text: text, pos: start}
+		case "or":
+			p.tok = token{kind: orToken, text: text, pos: start}
+		case "not":
+			p.tok = token{kind: notToken, text: text, pos: start}
+		default:
+			p.tok = token{kind: wordToken, text: text, value: text, pos: start}
+		}
+	}
+}
+
+// quoted reads a double-quoted string. An unterminated string becomes an unexpected token spanning the
+// rest of the expression.
+func (p *parser) quoted() token {
+	start := p.pos
+	var value strings.Builder
+	for p.pos++; p.pos < len(p.expr); p.pos++ {
+		switch c := p.expr[p.pos]; {
+		case c == '"':
+			p.pos++
+			return token{kind: stringToken, text: p.expr[start:p.pos], value: value.String(), pos: start}
+		case c == '\\' && p.pos+1 < len(p.expr):
+			p.pos++
+			value.WriteByte(p.expr[p.pos])
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return token{kind: badToken, text: p.expr[start:], pos: start}
+}
+
+// fail returns a `ParseError` for tok.
+func (p *parser) fail(tok token, err error) error {
+	return &ParseError{Expr: p.expr, Token: tok.text, Column: tok.pos + 1, Err: err}
+}
+
+// isOperator reports whether c belongs to a comparison operator.
+func isOperator(c byte) bool {
+	return strings.IndexByte("=!<>~", c) >= 0
+}
+
+// isDelimiter reports whether c ends a bare word.
+func isDelimiter(c byte) bool {
+	return unicode.IsSpace(rune(c)) || isOperator(c) || strings.IndexByte(`()&|"`, c) >= 0
+}
+
+// sameText reports whether text is value.
+func sameText(text, value string) bool {
+	return text == value
+}
+
+// sameExtension reports whether the extension text is value, without regard to case and to a leading
+// dot in value.
+func sameExtension(text, value string) bool {
+	return strings.EqualFold(text, strings.TrimPrefix(value, "."))
+}
+
+// either returns a predicate matching when left or right matches.
+func either(left, right predicate) predicate {
+	return func(file types.ScanMatch, now time.Time) bool { return left(file, now) || right(file, now) }
+}
+
+// both returns a predicate matching when left and right match.
+func both(left, right predicate) predicate {
+	return func(file types.ScanMatch, now time.Time) bool { return left(file, now) && right(file, now) }
+}
@@ -0,0 +1,113 @@
+package filter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ondrovic/common/types"
+)
+
+// TestFilter_Match tests the Parse and MatchAt funcs.
+func TestFilter_Match(t *testing.T) {
+	now := time.Date(2024, 5, 6, 12, 0, 0, 0, time.Local)
+	movie := types.ScanMatch{Path: "/media/Movie.mkv", Size: 1 << 30, ModTime: now.AddDate(0, 0, -60), FileType: types.FileTypes.Video}
+	sample := types.ScanMatch{Path: "/media/movie-SAMPLE.mp4", Size: 50 << 20, ModTime: now.AddDate(0, 0, -60), FileType: types.FileTypes.Video}
+	recent := types.ScanMatch{Path: "/media/new.mp4", Size: 800 << 20, ModTime: now.AddDate(0, 0, -2), FileType: types.FileTypes.Video}
+	backup := types.ScanMatch{Path: "/backups/site.TAR.GZ", Size: 4096, ModTime: now.AddDate(-1, 0, 0)}
+
+	type InputStruct struct {
+		expr string
+		file types.ScanMatch
+	}
+
+	tests := []*types.TestLayout[InputStruct, bool]{
+		{Name: "Example matches old large movie", Input: InputStruct{expr: `type=video && size >= 700MB && (mtime < 30d || name ~ "sample")`, file: movie}, Expected: false},
+		{Name: "Example matches recent large movie", Input: InputStruct{expr: `type=video && size >= 700MB && (mtime < 30d || name ~ "sample")`, file: recent}, Expected: true},
+		{Name: "Example rejects small sample", Input: InputStruct{expr: `type=video && size >= 700MB && (mtime < 30d || name ~ "sample")`, file: sample}, Expected: false},
+		{Name: "Or without size", Input: InputStruct{expr: `type=video && (mtime < 30d || name ~ "sample")`, file: sample}, Expected: true},
+		{Name: "Type alias", Input: InputStruct{expr: "type == videos", file: movie}, Expected: true},
+		{Name: "Type from extension", Input: InputStruct{expr: "type=archive", file: backup}, Expected: true},
+		{Name: "Type MIME pattern", Input: InputStruct{expr: "type=video/*", file: backup}, Expected: false},
+		{Name: "Type not equal", Input: InputStruct{expr: "type != video", file: backup}, Expected: true},
+		{Name: "Size in bytes", Input: InputStruct{expr: "size=4096", file: backup}, Expected: true},
+		{Name: "Size operator alias", Input: InputStruct{expr: "size lt 1KB", file: backup}, Expected: false},
+		{Name: "Size not equal", Input: InputStruct{expr: "size <> 4KB", file: backup}, Expected: false},
+		{Name: "Older than", Input: InputStruct{expr: "mtime > 6w", file: movie}, Expected: true},
+		{Name: "Newer than hours", Input: InputStruct{expr: "mtime <= 48h", file: recent}, Expected: true},
+		{Name: "Before date", Input: InputStruct{expr: "mtime < 2024-01-01", file: backup}, Expected: true},
+		{Name: "On date", Input: InputStruct{expr: "mtime = 2024-05-04", file: recent}, Expected: true},
+		{Name: "After date", Input: InputStruct{expr: "mtime > 2024-05-04", file: recent}, Expected: false},
+		{Name: "Name equal is exact", Input: InputStruct{expr: "name = movie.mkv", file: movie}, Expected: false},
+		{Name: "Name regular expression", Input: InputStruct{expr: `name ~ "^movie\\.(mkv|mp4)$"`, file: movie}, Expected: true},
+		{Name: "Name does not match", Input: InputStruct{expr: "name !~ sample", file: sample}, Expected: false},
+		{Name: "Path", Input: InputStruct{expr: `path ~ "^/backups/"`, file: backup}, Expected: true},
+		{Name: "Compound extension", Input: InputStruct{expr: "ext = .tar.gz", file: backup}, Expected: true},
+		{Name: "Extension without dot", Input: InputStruct{expr: "ext=mkv", file: movie}, Expected: true},
+		{Name: "Not", Input: InputStruct{expr: "!type=video", file: movie}, Expected: false},
+		{Name: "Not with parentheses", Input: InputStruct{expr: "!(type=archive || size > 1GB)", file: sample}, Expected: true},
+		{Name: "And binds tighter than or", Input: InputStruct{expr: "type=archive && size > 1GB || ext=mkv", file: movie}, Expected: true},
+		{Name: "Keywords", Input: InputStruct{expr: "type=video AND NOT name ~ sample OR size=0", file: sample}, Expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			f, err := Parse(test.Input.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) - %v error = %v", test.Input.expr, test.Name, err)
+			}
+			if result := f.MatchAt(test.Input.file, now); result != test.Expected {
+				t.Errorf("Parse(%q).MatchAt(%q) - %v = %v; want %v", test.Input.expr, test.Input.file.Path, test.Name, result, test.Expected)
+			}
+		})
+	}
+
+	var empty *Filter
+	if !empty.Match(movie) || empty.String() != "" {
+		t.Errorf("nil Filter did not match every file")
+	}
+}
+
+// TestParse_Errors tests that parse errors point at the offending token.
+func TestParse_Errors(t *testing.T) {
+	type ExpectedResults struct {
+		token  string
+		column int
+	}
+
+	tests := []*types.TestLayout[string, ExpectedResults]{
+		{Name: "Empty", Input: "   ", Expected: ExpectedResults{token: "", column: 4}, Err: ErrEmpty},
+		{Name: "Unknown field", Input: "type=video && colour=red", Expected: ExpectedResults{token: "colour", column: 15}, Err: ErrUnknownField},
+		{Name: "Unknown file type", Input: "type=vidoe", Expected: ExpectedResults{token: "vidoe", column: 6}, Err: ErrValue},
+		{Name: "Invalid size", Input: "size >= 700XB", Expected: ExpectedResults{token: "700XB", column: 9}, Err: ErrValue},
+		{Name: "Invalid age", Input: "mtime < 30q", Expected: ExpectedResults{token: "30q", column: 9}, Err: ErrValue},
+		{Name: "Invalid regular expression", Input: `name ~ "("`, Expected: ExpectedResults{token: `"("`, column: 8}, Err: ErrValue},
+		{Name: "Unknown operator", Input: "size =< 1MB", Expected: ExpectedResults{token: "=<", column: 6}, Err: ErrOperator},
+		{Name: "Unsupported operator", Input: "type > video", Expected: ExpectedResults{token: ">", column: 6}, Err: ErrOperator},
+		{Name: "Age equality", Input: "mtime = 30d", Expected: ExpectedResults{token: "=", column: 7}, Err: ErrOperator},
+		{Name: "Missing operator", Input: "size 1MB", Expected: ExpectedResults{token: "1MB", column: 6}, Err: ErrUnexpected},
+		{Name: "Missing value", Input: "type=video && size >=", Expected: ExpectedResults{token: "", column: 22}, Err: ErrUnexpected},
+		{Name: "Missing close", Input: "(type=video || size > 1MB", Expected: ExpectedResults{token: "", column: 26}, Err: ErrUnexpected},
+		{Name: "Extra close", Input: "type=video)", Expected: ExpectedResults{token: ")", column: 11}, Err: ErrUnexpected},
+		{Name: "Single ampersand", Input: "type=video & size > 1MB", Expected: ExpectedResults{token: "&", column: 12}, Err: ErrUnexpected},
+		{Name: "Unterminated string", Input: `name ~ "sample`, Expected: ExpectedResults{token: `"sample`, column: 8}, Err: ErrUnexpected},
+		{Name: "Dangling or", Input: "type=video ||", Expected: ExpectedResults{token: "", column: 14}, Err: ErrUnexpected},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := Parse(test.Input)
+			if !errors.Is(err, test.Err) {
+				t.Fatalf("Parse(%q) - %v error = %v; want %v", test.Input, test.Name, err, test.Err)
+			}
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Parse(%q) - %v error = %T; want *ParseError", test.Input, test.Name, err)
+			}
+			if parseErr.Token != test.Expected.token || parseErr.Column != test.Expected.column {
+				t.Errorf("Parse(%q) - %v error at %q column %d; want %q column %d", test.Input, test.Name, parseErr.Token, parseErr.Column, test.Expected.token, test.Expected.column)
+			}
+		})
+	}
+}
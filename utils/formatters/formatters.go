@@ -98,7 +98,7 @@ func FormatPath(path, goos string) string {
 }
 
 // The `FormatSize` function converts a given size in bytes to a human-readable format with appropriate.
-// units.
+// units. The units are binary but labelled KB, MB and so on, see `sizes.Format` for SI or IEC labels.
 func FormatSize(bytes int64) string {
 	for _, unit := range types.SizeUnits {
 		if bytes >= unit.Size {
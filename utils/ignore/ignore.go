@@ -0,0 +1,303 @@
+package ignore
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/ondrovic/common/types"
+)
+
+// Matcher holds gitignore style rules for a tree rooted at Root. Rules follow gitignore semantics: the
+// last matching rule wins, `!` negates a rule, a leading or middle `/` anchors a rule to the directory
+// it was defined in, a trailing `/` matches directories only and `**` matches across directories. Like
+// git, a path inside an ignored directory stays ignored even if a later rule negates it.
+// Matcher is safe for concurrent use.
+type Matcher struct {
+	// Root is the directory rule bases and matched paths are relative to.
+	Root string
+
+	mu    sync.RWMutex
+	rules []rule
+}
+
+// rule is a single compiled ignore pattern.
+type rule struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+	regex   *regexp.Regexp
+}
+
+var _ types.PathMatcher = (*Matcher)(nil)
+
+// New returns a Matcher for root holding the given patterns, which apply from root down.
+func New(root string, patterns ...string) (*Matcher, error) {
+	m := &Matcher{Root: root}
+	if err := m.AddPatterns("", patterns...); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Load returns a Matcher for root with the given patterns plus the rules of every file named fileName
+// found in the tree. Rules in a nested ignore file apply to the directory holding it, and are loaded on
+// entering that directory so they already apply to its entries, whatever their names sort as.
+// Directories that are already ignored are not searched for more ignore files.
+func Load(ops types.DirOps, root, fileName string, patterns ...string) (*Matcher, error) {
+	if ops == nil {
+		ops = &types.RealDirOps{}
+	}
+
+	m, err := New(root, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ops.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != root && m.Match(p, true) {
+			return fs.SkipDir
+		}
+		if err := m.LoadFile(ops, filepath.Join(p, fileName)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// LoadFile adds the rules in the ignore file at name, they apply to the directory holding it.
+func (m *Matcher) LoadFile(ops types.DirOps, name string) error {
+	if ops == nil {
+		ops = &types.RealDirOps{}
+	}
+
+	file, err := ops.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	patterns := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading %s: %w", name, err)
+	}
+
+	base, err := m.relative(filepath.Dir(name))
+	if err != nil {
+		return err
+	}
+	if err := m.AddPatterns(base, patterns...); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// AddPatterns adds patterns that apply to base, a slash separated directory relative to Root. Blank
+// lines and lines starting with `#` are ignored.
+func (m *Matcher) AddPatterns(base string, patterns ...string) error {
+	base = strings.Trim(path.Clean("/"+filepath.ToSlash(base)), "/")
+
+	compiled := []rule{}
+	for _, pattern := range patterns {
+		r, ok, err := compile(base, pattern)
+		if err != nil {
+			return err
+		}
+		if ok {
+			compiled = append(compiled, r)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rules = append(m.rules, compiled...)
+	return nil
+}
+
+// Match reports whether name, a path inside Root, is ignored. isDir tells whether name is a directory,
+// which matters for directory-only rules. Paths outside Root are never ignored.
+func (m *Matcher) Match(name string, isDir bool) bool {
+	rel, err := m.relative(name)
+	if err != nil || rel == "" {
+		return false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// A path is ignored when any of its parent directories is ignored.
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.matchRel(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.matchRel(rel, isDir)
+}
+
+// matchRel applies the rules to a single relative path, the caller must hold the lock.
+func (m *Matcher) matchRel(rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.regex.MatchString(rel) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// relative converts name to a slash separated path relative to Root.
+func (m *Matcher) relative(name string) (string, error) {
+	rel := name
+	if m.Root != "" {
+		var err error
+		rel, err = filepath.Rel(m.Root, name)
+		if err != nil {
+			return "", err
+		}
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." {
+		return "", nil
+	}
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("%s is outside %s", name, m.Root)
+	}
+	return rel, nil
+}
+
+// compile turns a gitignore line into a rule anchored at base. It returns false for blank lines and
+// comments.
+func compile(base, line string) (rule, bool, error) {
+	pattern := trimTrailingSpace(line)
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return rule{}, false, nil
+	}
+
+	r := rule{pattern: line}
+	if strings.HasPrefix(pattern, "!") {
+		r.negate = true
+		pattern = pattern[1:]
+	} else if strings.HasPrefix(pattern, `\!`) || strings.HasPrefix(pattern, `\#`) {
+		pattern = pattern[1:]
+	}
+
+	if strings.HasSuffix(pattern, "/") && !strings.HasSuffix(pattern, `\/`) {
+		r.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	if pattern == "" {
+		return rule{}, false, nil
+	}
+
+	// Patterns without a leading or middle slash match at any depth.
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if !anchored && !strings.HasPrefix(pattern, "**/") {
+		pattern = "**/" + pattern
+	}
+
+	expr, err := translate(pattern)
+	if err != nil {
+		return rule{}, false, fmt.Errorf("invalid ignore pattern %q: %w", line, err)
+	}
+	if base != "" {
+		expr = regexp.QuoteMeta(base) + "/" + expr
+	}
+
+	r.regex, err = regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return rule{}, false, fmt.Errorf("invalid ignore pattern %q: %w", line, err)
+	}
+	return r, true, nil
+}
+
+// translate converts a gitignore glob into a regular expression.
+func translate(pattern string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '*' && strings.HasPrefix(pattern[i:], "**"):
+			atStart := i == 0 || pattern[i-1] == '/'
+			next := i + 2
+			switch {
+			case atStart && next == len(pattern):
+				b.WriteString(".*")
+			case atStart && pattern[next] == '/':
+				b.WriteString("(?:.*/)?")
+				next++
+			default:
+				b.WriteString("[^/]*")
+			}
+			i = next - 1
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated character class")
+			}
+			class := pattern[i+1 : i+1+end]
+			if end == 0 {
+				// A leading `]` is part of the class.
+				end = strings.IndexByte(pattern[i+2:], ']')
+				if end < 0 {
+					return "", fmt.Errorf("unterminated character class")
+				}
+				end++
+				class = pattern[i+1 : i+1+end]
+			}
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String(), nil
+}
+
+// trimTrailingSpace removes trailing spaces that are not escaped with a backslash.
+func trimTrailingSpace(line string) string {
+	line = strings.TrimRight(line, "\r")
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-2] + " "
+	}
+	return line
+}
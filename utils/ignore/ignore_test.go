@@ -0,0 +1,154 @@
+package ignore
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils/memfs"
+)
+
+// TestMatch tests the Match func against gitignore semantics.
+func TestMatch(t *testing.T) {
+	type InputStruct struct {
+		patterns []string
+		path     string
+		isDir    bool
+	}
+
+	tests := []*types.TestLayout[InputStruct, bool]{
+		{Name: "Basename matches at any depth", Input: InputStruct{patterns: []string{"*.part"}, path: "a/b/movie.part"}, Expected: true},
+		{Name: "Basename does not match other extension", Input: InputStruct{patterns: []string{"*.part"}, path: "a/b/movie.mp4"}, Expected: false},
+		{Name: "Directory name matches at any depth", Input: InputStruct{patterns: []string{"node_modules"}, path: "web/node_modules", isDir: true}, Expected: true},
+		{Name: "Contents of ignored directory", Input: InputStruct{patterns: []string{"node_modules"}, path: "web/node_modules/pkg/index.js"}, Expected: true},
+		{Name: "Directory-only pattern skips files", Input: InputStruct{patterns: []string{"build/"}, path: "src/build"}, Expected: false},
+		{Name: "Directory-only pattern matches directories", Input: InputStruct{patterns: []string{"build/"}, path: "src/build", isDir: true}, Expected: true},
+		{Name: "Leading slash anchors to root", Input: InputStruct{patterns: []string{"/tmp"}, path: "a/tmp"}, Expected: false},
+		{Name: "Leading slash matches at root", Input: InputStruct{patterns: []string{"/tmp"}, path: "tmp"}, Expected: true},
+		{Name: "Middle slash anchors", Input: InputStruct{patterns: []string{"docs/*.md"}, path: "docs/readme.md"}, Expected: true},
+		{Name: "Middle slash does not match deeper", Input: InputStruct{patterns: []string{"docs/*.md"}, path: "a/docs/readme.md"}, Expected: false},
+		{Name: "Star does not cross directories", Input: InputStruct{patterns: []string{"docs/*.md"}, path: "docs/sub/readme.md"}, Expected: false},
+		{Name: "Leading double star", Input: InputStruct{patterns: []string{"**/cache"}, path: "a/b/cache", isDir: true}, Expected: true},
+		{Name: "Middle double star matches zero directories", Input: InputStruct{patterns: []string{"a/**/b"}, path: "a/b"}, Expected: true},
+		{Name: "Middle double star matches many directories", Input: InputStruct{patterns: []string{"a/**/b"}, path: "a/x/y/b"}, Expected: true},
+		{Name: "Trailing double star matches contents", Input: InputStruct{patterns: []string{"logs/**"}, path: "logs/2024/app.log"}, Expected: true},
+		{Name: "Trailing double star does not match directory itself", Input: InputStruct{patterns: []string{"logs/**"}, path: "logs", isDir: true}, Expected: false},
+		{Name: "Negation re-includes", Input: InputStruct{patterns: []string{"*.log", "!keep.log"}, path: "keep.log"}, Expected: false},
+		{Name: "Last rule wins", Input: InputStruct{patterns: []string{"!keep.log", "*.log"}, path: "keep.log"}, Expected: true},
+		{Name: "Negation cannot re-include inside ignored directory", Input: InputStruct{patterns: []string{"out/", "!out/keep.txt"}, path: "out/keep.txt"}, Expected: true},
+		{Name: "Question mark", Input: InputStruct{patterns: []string{"file?.txt"}, path: "file1.txt"}, Expected: true},
+		{Name: "Character class", Input: InputStruct{patterns: []string{"file[0-9].txt"}, path: "filea.txt"}, Expected: false},
+		{Name: "Negated character class", Input: InputStruct{patterns: []string{"file[!0-9].txt"}, path: "filea.txt"}, Expected: true},
+		{Name: "Escaped hash", Input: InputStruct{patterns: []string{`\#notes`}, path: "#notes"}, Expected: true},
+		{Name: "Escaped bang", Input: InputStruct{patterns: []string{`\!important`}, path: "!important"}, Expected: true},
+		{Name: "Comments and blank lines", Input: InputStruct{patterns: []string{"# *.txt", "", "   "}, path: "a.txt"}, Expected: false},
+		{Name: "Trailing spaces trimmed", Input: InputStruct{patterns: []string{"*.tmp   "}, path: "a.tmp"}, Expected: true},
+		{Name: "Root itself never matches", Input: InputStruct{patterns: []string{"*"}, path: "."}, Expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			m, err := New("", test.Input.patterns...)
+			if err != nil {
+				t.Fatalf("New(%q) - %v error = %v", test.Input.patterns, test.Name, err)
+			}
+			if result := m.Match(test.Input.path, test.Input.isDir); result != test.Expected {
+				t.Errorf("Match(%q, %v) with %q - %v = %v; want %v", test.Input.path, test.Input.isDir, test.Input.patterns, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestMatch_Root tests that paths are matched relative to Root.
+func TestMatch_Root(t *testing.T) {
+	root := filepath.FromSlash("/data/root")
+	m, err := New(root, "/movies/*.part")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []*types.TestLayout[string, bool]{
+		{Name: "Inside root", Input: filepath.Join(root, "movies", "a.part"), Expected: true},
+		{Name: "Relative path is not re-anchored", Input: filepath.Join(root, "x", "movies", "a.part"), Expected: false},
+		{Name: "Outside root", Input: filepath.FromSlash("/data/movies/a.part"), Expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if result := m.Match(test.Input, false); result != test.Expected {
+				t.Errorf("Match(%q) - %v = %v; want %v", test.Input, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestNew_InvalidPattern tests that malformed patterns are reported.
+func TestNew_InvalidPattern(t *testing.T) {
+	if _, err := New("", "file[0-9.txt"); err == nil {
+		t.Errorf("New() with unterminated class error = nil; want error")
+	}
+}
+
+// TestLoad tests that ignore files found in the tree are loaded relative to their directory.
+func TestLoad(t *testing.T) {
+	ops := memfs.New()
+	files := map[string]string{
+		"/root/.cleanignore":                "*.part\nbuild/\n",
+		"/root/web/.cleanignore":            "/dist\n!keep.part\n",
+		"/root/skipped/.cleanignore":        "!*.part\n",
+		"/root/web/dist/app.js":             "",
+		"/root/web/keep.part":               "",
+		"/root/web/src/dist/index.js":       "",
+		"/root/app/.cleanignore":            "/-cache/\n",
+		"/root/app/-cache/sub/.cleanignore": "!*.part\n",
+	}
+	for name, data := range files {
+		if err := ops.WriteFile(name, []byte(data), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	// -cache sorts before .cleanignore, it must be skipped without being searched.
+	ops.InjectError(memfs.OpOpen, "/root/app/-cache/sub/.cleanignore", errors.New("simulated Open error"))
+
+	m, err := Load(ops, "/root", ".cleanignore", "skipped/")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []*types.TestLayout[string, bool]{
+		{Name: "Root rule applies everywhere", Input: "/root/a/b.part", Expected: true},
+		{Name: "Nested anchored rule", Input: "/root/web/dist", Expected: true},
+		{Name: "Nested anchored rule is relative to its directory", Input: "/root/web/src/dist", Expected: false},
+		{Name: "Nested negation", Input: "/root/web/keep.part", Expected: false},
+		{Name: "Nested negation does not leak upward", Input: "/root/keep.part", Expected: true},
+		{Name: "Ignore files in ignored directories are not loaded", Input: "/root/skipped/x.part", Expected: true},
+		{Name: "Code pattern", Input: "/root/skipped", Expected: true},
+		{Name: "Rules apply to entries sorting before the ignore file", Input: "/root/app/-cache", Expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if result := m.Match(filepath.FromSlash(test.Input), true); result != test.Expected {
+				t.Errorf("Match(%q) - %v = %v; want %v", test.Input, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestLoad_Errors tests Load error handling.
+func TestLoad_Errors(t *testing.T) {
+	ops := memfs.New()
+	if err := ops.WriteFile("/root/.cleanignore", []byte("*.tmp\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	ops.InjectError(memfs.OpOpen, "/root/.cleanignore", errors.New("simulated Open error"))
+
+	if _, err := Load(ops, "/root", ".cleanignore"); err == nil {
+		t.Errorf("Load() with unreadable ignore file error = nil; want error")
+	}
+	if _, err := Load(ops, "/missing", ".cleanignore"); err == nil {
+		t.Errorf("Load() with missing root error = nil; want error")
+	}
+}
@@ -0,0 +1,454 @@
+package journal
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ondrovic/common/types"
+)
+
+// The operations and outcomes recorded in the journal.
+const (
+	OpRemove    = "remove"
+	OpRemoveAll = "removeall"
+	OpRename    = "rename"
+	OpOpenFile  = "openfile"
+	OpMkdirAll  = "mkdirall"
+	OpChtimes   = "chtimes"
+	OpCommit    = "commit"
+	OpAbort     = "abort"
+	OpUndo      = "undo"
+)
+
+var (
+	// ErrRunNotFound is returned by `Undo` when the journal holds no entries for the run.
+	ErrRunNotFound = errors.New("run not found in journal")
+	// ErrOutcomeUnknown is reported by `Undo` for a mutation that has neither a `commit` nor an `abort`
+	// record, because the process stopped after writing it. The mutation may or may not have happened.
+	ErrOutcomeUnknown = errors.New("mutation has no commit or abort record")
+)
+
+// JournalDirOps is a `types.DirOps` that writes every mutation to an append-only journal before passing
+// it to the wrapped `Ops`. Each mutation is followed by a `commit` or `abort` record, and `Undo` uses the
+// committed records to reverse a run. Read-only operations are passed through untouched.
+//
+// Removing a directory, renaming, creating directories or files and changing times can be undone.
+// Removing a file, or a directory that still has content, and writing to an existing file are recorded
+// but cannot be reversed.
+type JournalDirOps struct {
+	// Ops is the filesystem mutations are applied to.
+	Ops types.DirOps
+	// RunID identifies the entries written by this instance.
+	RunID string
+	// Now returns the time stamped on each record, it defaults to `time.Now`.
+	Now func() time.Time
+
+	mu  sync.Mutex
+	seq int
+	log types.File
+}
+
+var _ types.DirOps = (*JournalDirOps)(nil)
+
+// New opens, or creates, the journal at logPath and returns a JournalDirOps recording mutations of ops
+// under runID. The journal itself is written through ops, without being recorded, so an in-memory ops
+// keeps it in memory too. An empty runID is replaced with one from `NewRunID` and a nil ops uses
+// `types.RealDirOps`.
+func New(ops types.DirOps, logPath, runID string) (*JournalDirOps, error) {
+	if ops == nil {
+		ops = &types.RealDirOps{}
+	}
+	if runID == "" {
+		runID = NewRunID()
+	}
+
+	log, err := ops.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening journal: %w", err)
+	}
+
+	return &JournalDirOps{Ops: ops, RunID: runID, Now: time.Now, log: log}, nil
+}
+
+// NewRunID returns a run ID made of the current UTC time and a random suffix.
+func NewRunID() string {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+// Close closes the journal file.
+func (j *JournalDirOps) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.log.Close()
+}
+
+// ReadDir implements the `ReadDir` function of the `types.DirOps` interface.
+func (j *JournalDirOps) ReadDir(name string) ([]os.DirEntry, error) {
+	return j.Ops.ReadDir(name)
+}
+
+// Remove implements the `Remove` function of the `types.DirOps` interface. Removing a directory is
+// reversible, removing a file is not.
+func (j *JournalDirOps) Remove(name string) error {
+	info, err := j.Ops.Lstat(name)
+	if err != nil {
+		return j.Ops.Remove(name)
+	}
+
+	entry := types.JournalEntry{
+		Op:         OpRemove,
+		Path:       name,
+		IsDir:      info.IsDir(),
+		Mode:       info.Mode(),
+		ModTime:    info.ModTime(),
+		Reversible: info.IsDir(),
+	}
+	return j.record(entry, func() error {
+		return j.Ops.Remove(name)
+	})
+}
+
+// Stat implements the `Stat` function of the `types.DirOps` interface.
+func (j *JournalDirOps) Stat(name string) (os.FileInfo, error) {
+	return j.Ops.Stat(name)
+}
+
+// Lstat implements the `Lstat` function of the `types.DirOps` interface.
+func (j *JournalDirOps) Lstat(name string) (os.FileInfo, error) {
+	return j.Ops.Lstat(name)
+}
+
+// Open implements the `Open` function of the `types.DirOps` interface.
+func (j *JournalDirOps) Open(name string) (fs.File, error) {
+	return j.Ops.Open(name)
+}
+
+// OpenFile implements the `OpenFile` function of the `types.DirOps` interface. Opening name for reading
+// is passed through, opening it for writing is recorded and only reversible when it creates name, undo
+// then removes it. What is written to the file is not recorded.
+func (j *JournalDirOps) OpenFile(name string, flag int, perm os.FileMode) (types.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC) == 0 {
+		return j.Ops.OpenFile(name, flag, perm)
+	}
+
+	entry := types.JournalEntry{Op: OpOpenFile, Path: name, Mode: perm.Perm()}
+	if info, err := j.Ops.Stat(name); err == nil {
+		entry.Mode = info.Mode()
+		entry.ModTime = info.ModTime()
+	} else {
+		entry.Reversible = flag&os.O_CREATE != 0
+	}
+
+	var file types.File
+	err := j.record(entry, func() error {
+		var err error
+		file, err = j.Ops.OpenFile(name, flag, perm)
+		return err
+	})
+	return file, err
+}
+
+// Rename implements the `Rename` function of the `types.DirOps` interface. Renames are reversible,
+// although anything replaced at newpath is not brought back.
+func (j *JournalDirOps) Rename(oldpath, newpath string) error {
+	entry := types.JournalEntry{
+		Op:         OpRename,
+		Path:       oldpath,
+		NewPath:    newpath,
+		Reversible: true,
+	}
+	if info, err := j.Ops.Lstat(oldpath); err == nil {
+		entry.IsDir = info.IsDir()
+		entry.Mode = info.Mode()
+		entry.ModTime = info.ModTime()
+	}
+
+	return j.record(entry, func() error {
+		return j.Ops.Rename(oldpath, newpath)
+	})
+}
+
+// MkdirAll implements the `MkdirAll` function of the `types.DirOps` interface. The directories that did
+// not exist beforehand are recorded so undo removes only those.
+func (j *JournalDirOps) MkdirAll(path string, perm os.FileMode) error {
+	created := []string{}
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if _, err := j.Ops.Stat(dir); err == nil {
+			break
+		}
+		created = append([]string{dir}, created...)
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	if len(created) == 0 {
+		return j.Ops.MkdirAll(path, perm)
+	}
+
+	entry := types.JournalEntry{
+		Op:         OpMkdirAll,
+		Path:       path,
+		IsDir:      true,
+		Mode:       fs.ModeDir | perm.Perm(),
+		Created:    created,
+		Reversible: true,
+	}
+	return j.record(entry, func() error {
+		return j.Ops.MkdirAll(path, perm)
+	})
+}
+
+// RemoveAll implements the `RemoveAll` function of the `types.DirOps` interface. It is only reversible
+// when path is an empty directory.
+func (j *JournalDirOps) RemoveAll(path string) error {
+	info, err := j.Ops.Lstat(path)
+	if err != nil {
+		return j.Ops.RemoveAll(path)
+	}
+
+	reversible := false
+	if info.IsDir() {
+		entries, err := j.Ops.ReadDir(path)
+		reversible = err == nil && len(entries) == 0
+	}
+
+	entry := types.JournalEntry{
+		Op:         OpRemoveAll,
+		Path:       path,
+		IsDir:      info.IsDir(),
+		Mode:       info.Mode(),
+		ModTime:    info.ModTime(),
+		Reversible: reversible,
+	}
+	return j.record(entry, func() error {
+		return j.Ops.RemoveAll(path)
+	})
+}
+
+// Chtimes implements the `Chtimes` function of the `types.DirOps` interface. Undo restores the previous
+// modification time, which is also used as the access time.
+func (j *JournalDirOps) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	info, err := j.Ops.Stat(name)
+	if err != nil {
+		return j.Ops.Chtimes(name, atime, mtime)
+	}
+
+	entry := types.JournalEntry{
+		Op:         OpChtimes,
+		Path:       name,
+		IsDir:      info.IsDir(),
+		Mode:       info.Mode(),
+		ModTime:    info.ModTime(),
+		Reversible: true,
+	}
+	return j.record(entry, func() error {
+		return j.Ops.Chtimes(name, atime, mtime)
+	})
+}
+
+// WalkDir implements the `WalkDir` function of the `types.DirOps` interface.
+func (j *JournalDirOps) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return j.Ops.WalkDir(root, fn)
+}
+
+// record writes entry to the journal, runs mutate and then writes its outcome. The mutation is not run
+// if the entry cannot be written.
+func (j *JournalDirOps) record(entry types.JournalEntry, mutate func() error) error {
+	j.mu.Lock()
+	j.seq++
+	entry.RunID = j.RunID
+	entry.Seq = j.seq
+	entry.Time = j.now()
+	err := appendEntry(j.log, entry)
+	j.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("error writing journal: %w", err)
+	}
+
+	mutateErr := mutate()
+
+	outcome := types.JournalEntry{RunID: entry.RunID, Seq: entry.Seq, Op: OpCommit}
+	if mutateErr != nil {
+		outcome.Op = OpAbort
+		outcome.Error = mutateErr.Error()
+	}
+
+	j.mu.Lock()
+	outcome.Time = j.now()
+	err = appendEntry(j.log, outcome)
+	j.mu.Unlock()
+	if mutateErr != nil {
+		return mutateErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing journal: %w", err)
+	}
+	return nil
+}
+
+// now returns the current time from the configured clock.
+func (j *JournalDirOps) now() time.Time {
+	if j.Now == nil {
+		return time.Now()
+	}
+	return j.Now()
+}
+
+// ReadJournal returns every record in the journal at logPath on ops, in the order they were written. A
+// nil ops uses `types.RealDirOps`.
+func ReadJournal(ops types.DirOps, logPath string) ([]types.JournalEntry, error) {
+	if ops == nil {
+		ops = &types.RealDirOps{}
+	}
+
+	file, err := ops.Open(logPath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening journal: %w", err)
+	}
+	defer file.Close()
+
+	entries := []types.JournalEntry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry types.JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("error reading journal line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading journal: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Undo reverses the committed mutations of runID in the journal at logPath on ops, newest first,
+// applying them to ops. Every reversal is appended to the journal as an `undo` record, so running Undo
+// again only retries what is left. Mutations without an outcome, left behind when the process stopped
+// while applying them, are not reversed but reported as failures wrapping `ErrOutcomeUnknown`.
+func Undo(ops types.DirOps, logPath, runID string) (types.UndoReport, error) {
+	report := types.UndoReport{}
+	if ops == nil {
+		ops = &types.RealDirOps{}
+	}
+
+	entries, err := ReadJournal(ops, logPath)
+	if err != nil {
+		return report, err
+	}
+
+	mutations := []types.JournalEntry{}
+	committed := map[int]bool{}
+	aborted := map[int]bool{}
+	undone := map[int]bool{}
+	for _, entry := range entries {
+		if entry.RunID != runID {
+			continue
+		}
+		switch entry.Op {
+		case OpCommit:
+			committed[entry.Seq] = true
+		case OpAbort:
+			aborted[entry.Seq] = true
+		case OpUndo:
+			undone[entry.Seq] = true
+		default:
+			mutations = append(mutations, entry)
+		}
+	}
+	if len(mutations) == 0 {
+		return report, fmt.Errorf("%s: %w", runID, ErrRunNotFound)
+	}
+
+	log, err := ops.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return report, fmt.Errorf("error opening journal: %w", err)
+	}
+	defer log.Close()
+
+	sort.SliceStable(mutations, func(i, j int) bool {
+		return mutations[i].Seq > mutations[j].Seq
+	})
+
+	for _, entry := range mutations {
+		if aborted[entry.Seq] || undone[entry.Seq] {
+			continue
+		}
+		if !committed[entry.Seq] {
+			report.Failed = append(report.Failed, types.UndoFailure{Entry: entry, Err: ErrOutcomeUnknown})
+			continue
+		}
+		if !entry.Reversible {
+			report.Skipped = append(report.Skipped, entry)
+			continue
+		}
+
+		if err := reverse(ops, entry); err != nil {
+			report.Failed = append(report.Failed, types.UndoFailure{Entry: entry, Err: err})
+			continue
+		}
+
+		if err := appendEntry(log, types.JournalEntry{RunID: runID, Seq: entry.Seq, Time: time.Now(), Op: OpUndo}); err != nil {
+			return report, fmt.Errorf("error writing journal: %w", err)
+		}
+		report.Undone = append(report.Undone, entry)
+	}
+
+	return report, nil
+}
+
+// reverse applies the inverse of a single journaled mutation.
+func reverse(ops types.DirOps, entry types.JournalEntry) error {
+	switch entry.Op {
+	case OpRemove, OpRemoveAll:
+		if err := ops.MkdirAll(entry.Path, entry.Mode.Perm()); err != nil {
+			return err
+		}
+		return ops.Chtimes(entry.Path, entry.ModTime, entry.ModTime)
+	case OpRename:
+		return ops.Rename(entry.NewPath, entry.Path)
+	case OpOpenFile:
+		return ops.Remove(entry.Path)
+	case OpMkdirAll:
+		for i := len(entry.Created) - 1; i >= 0; i-- {
+			if err := ops.Remove(entry.Created[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case OpChtimes:
+		return ops.Chtimes(entry.Path, entry.ModTime, entry.ModTime)
+	default:
+		return fmt.Errorf("unknown journal operation %q", entry.Op)
+	}
+}
+
+// appendEntry writes entry as a single JSON line and syncs it to disk.
+func appendEntry(log types.File, entry types.JournalEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := log.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return log.Sync()
+}
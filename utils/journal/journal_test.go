@@ -0,0 +1,290 @@
+package journal
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils"
+	"github.com/ondrovic/common/utils/memfs"
+)
+
+// fixedTime is the modification time of every entry in the test filesystem.
+var fixedTime = time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+
+// CreateTestJournal creates an in-memory filesystem and a journal wrapping it for testing.
+//
+//	/data
+//	├── empty/a/b/
+//	├── file.txt
+//	└── full/file.txt
+func CreateTestJournal(t *testing.T, runID string) (*memfs.MemDirOps, *JournalDirOps, string) {
+	t.Helper()
+	ops := memfs.New()
+	ops.Now = func() time.Time { return fixedTime }
+	if err := ops.MkdirAll("/data/empty/a/b", 0o750); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	for _, name := range []string{"/data/file.txt", "/data/full/file.txt"} {
+		if err := ops.WriteFile(name, []byte("content"), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	logPath := "/journal.log"
+	journal, err := New(ops, logPath, runID)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { journal.Close() })
+	return ops, journal, logPath
+}
+
+// TestRecord tests that mutations are journaled with their outcome.
+func TestRecord(t *testing.T) {
+	ops, journal, logPath := CreateTestJournal(t, "run-1")
+	ops.InjectError(memfs.OpRemove, "/data/full", errors.New("simulated Remove error"))
+
+	if err := journal.Remove("/data/file.txt"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := journal.Remove("/data/full"); err == nil {
+		t.Fatalf("Remove() error = nil; want simulated error")
+	}
+	if err := journal.Remove("/data/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Remove() missing error = %v; want %v", err, fs.ErrNotExist)
+	}
+
+	entries, err := ReadJournal(ops, logPath)
+	if err != nil {
+		t.Fatalf("ReadJournal() error = %v", err)
+	}
+
+	type record struct {
+		Seq        int
+		Op         string
+		Path       string
+		Reversible bool
+		Error      string
+	}
+	got := []record{}
+	for _, entry := range entries {
+		if entry.RunID != "run-1" {
+			t.Errorf("ReadJournal() run ID = %q; want %q", entry.RunID, "run-1")
+		}
+		got = append(got, record{Seq: entry.Seq, Op: entry.Op, Path: entry.Path, Reversible: entry.Reversible, Error: entry.Error})
+	}
+	expected := []record{
+		{Seq: 1, Op: OpRemove, Path: "/data/file.txt"},
+		{Seq: 1, Op: OpCommit},
+		{Seq: 2, Op: OpRemove, Path: "/data/full", Reversible: true},
+		{Seq: 2, Op: OpAbort, Error: "simulated Remove error"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("ReadJournal() = %+v; want %+v", got, expected)
+	}
+}
+
+// TestUndo tests that each kind of mutation is reversed.
+func TestUndo(t *testing.T) {
+	tests := []*types.TestLayout[string, []string]{
+		{Name: "Undo pruned directories", Input: "prune", Expected: []string{"/data/empty/a/b"}},
+		{Name: "Undo rename", Input: "rename", Expected: []string{"/data/full/file.txt"}},
+		{Name: "Undo mkdirall", Input: "mkdirall", Expected: []string{"/data/empty/a/b"}},
+		{Name: "Undo chtimes", Input: "chtimes", Expected: []string{"/data/file.txt"}},
+		{Name: "Undo openfile", Input: "openfile", Expected: []string{"/data/file.txt"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ops, journal, logPath := CreateTestJournal(t, "run-"+test.Input)
+
+			var err error
+			switch test.Input {
+			case "prune":
+				_, err = utils.PruneEmptyDirs("/data/empty", journal, types.PruneOptions{})
+			case "rename":
+				err = journal.Rename("/data/full", "/data/moved")
+			case "mkdirall":
+				err = journal.MkdirAll("/data/new/x/y", 0o755)
+			case "chtimes":
+				later := fixedTime.Add(time.Hour)
+				err = journal.Chtimes("/data/file.txt", later, later)
+			case "openfile":
+				var file types.File
+				if file, err = journal.OpenFile("/data/new.txt", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644); err == nil {
+					err = file.Close()
+				}
+			}
+			if err != nil {
+				t.Fatalf("%v mutation error = %v", test.Name, err)
+			}
+
+			report, err := Undo(ops, logPath, journal.RunID)
+			if err != nil || len(report.Failed) != 0 {
+				t.Fatalf("Undo() - %v = %+v, %v", test.Name, report, err)
+			}
+
+			for _, path := range test.Expected {
+				info, err := ops.Stat(path)
+				if err != nil {
+					t.Fatalf("Undo() - %v %s missing: %v", test.Name, path, err)
+				}
+				if !info.ModTime().Equal(fixedTime) {
+					t.Errorf("Undo() - %v %s mtime = %v; want %v", test.Name, path, info.ModTime(), fixedTime)
+				}
+			}
+			if test.Input == "prune" {
+				if info, _ := ops.Stat("/data/empty/a"); info.Mode().Perm() != 0o750 {
+					t.Errorf("Undo() - %v perm = %v; want 0750", test.Name, info.Mode().Perm())
+				}
+			}
+			if test.Input == "mkdirall" {
+				if _, err := ops.Stat("/data/new"); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("Undo() - %v created directories still exist", test.Name)
+				}
+			}
+			if test.Input == "openfile" {
+				if _, err := ops.Stat("/data/new.txt"); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("Undo() - %v created file still exists", test.Name)
+				}
+			}
+
+			again, err := Undo(ops, logPath, journal.RunID)
+			if err != nil || len(again.Undone) != 0 {
+				t.Errorf("Undo() - %v second run = %+v, %v; want nothing undone", test.Name, again, err)
+			}
+		})
+	}
+}
+
+// TestUndo_Skipped tests that irreversible and aborted mutations are not replayed.
+func TestUndo_Skipped(t *testing.T) {
+	ops, journal, logPath := CreateTestJournal(t, "run-skip")
+	ops.InjectError(memfs.OpRename, "/data/empty", errors.New("simulated Rename error"))
+
+	if err := journal.Remove("/data/file.txt"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := journal.RemoveAll("/data/full"); err != nil {
+		t.Fatalf("RemoveAll() error = %v", err)
+	}
+	if err := journal.Rename("/data/empty", "/data/moved"); err == nil {
+		t.Fatalf("Rename() error = nil; want simulated error")
+	}
+
+	report, err := Undo(ops, logPath, "run-skip")
+	if err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	if len(report.Undone) != 0 || len(report.Failed) != 0 {
+		t.Errorf("Undo() = %+v; want nothing undone or failed", report)
+	}
+	skipped := []string{}
+	for _, entry := range report.Skipped {
+		skipped = append(skipped, entry.Op+" "+entry.Path)
+	}
+	if !reflect.DeepEqual(skipped, []string{"removeall /data/full", "remove /data/file.txt"}) {
+		t.Errorf("Undo() skipped = %v", skipped)
+	}
+}
+
+// TestUndo_OutcomeUnknown tests that a mutation whose outcome was never written is reported rather than
+// skipped, as happens when the process stops after writing its intent.
+func TestUndo_OutcomeUnknown(t *testing.T) {
+	ops, journal, logPath := CreateTestJournal(t, "run-crash")
+
+	if err := journal.Rename("/data/full", "/data/moved"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if err := journal.MkdirAll("/data/new", 0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+
+	// Truncate the journal after the intent record of MkdirAll.
+	data, err := ops.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("failed to read journal: %v", err)
+	}
+	lines := strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+	if err := ops.WriteFile(logPath, []byte(strings.Join(lines[:len(lines)-1], "")), 0o600); err != nil {
+		t.Fatalf("failed to truncate journal: %v", err)
+	}
+
+	report, err := Undo(ops, logPath, "run-crash")
+	if err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	if len(report.Undone) != 1 || report.Undone[0].Op != OpRename {
+		t.Errorf("Undo() undone = %+v; want the rename", report.Undone)
+	}
+	if len(report.Failed) != 1 || report.Failed[0].Entry.Op != OpMkdirAll || !errors.Is(report.Failed[0].Err, ErrOutcomeUnknown) {
+		t.Errorf("Undo() failed = %+v; want the mkdirall with %v", report.Failed, ErrOutcomeUnknown)
+	}
+}
+
+// TestUndo_Errors tests Undo error handling.
+func TestUndo_Errors(t *testing.T) {
+	ops, journal, logPath := CreateTestJournal(t, "run-errors")
+
+	if _, err := Undo(ops, logPath, "unknown"); !errors.Is(err, ErrRunNotFound) {
+		t.Errorf("Undo() unknown run error = %v; want %v", err, ErrRunNotFound)
+	}
+	if _, err := Undo(ops, "/missing.log", "run-errors"); err == nil || !strings.Contains(err.Error(), "error opening journal") {
+		t.Errorf("Undo() missing journal error = %v", err)
+	}
+
+	if err := journal.Rename("/data/full", "/data/moved"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if err := ops.WriteFile("/data/full/new.txt", []byte("x"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	report, err := Undo(ops, logPath, "run-errors")
+	if err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	if len(report.Failed) != 1 || report.Failed[0].Entry.Op != OpRename {
+		t.Errorf("Undo() onto occupied path = %+v; want one failed rename", report)
+	}
+}
+
+// TestNewRunID tests that generated run IDs are unique.
+func TestNewRunID(t *testing.T) {
+	first, second := NewRunID(), NewRunID()
+	if first == second || len(first) == 0 {
+		t.Errorf("NewRunID() = %q, %q; want two different IDs", first, second)
+	}
+}
+
+// TestJournal_RealDirOps tests that the journal is written to and undone on the real filesystem.
+func TestJournal_RealDirOps(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "journal.log")
+	journal, err := New(nil, logPath, "run-real")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer journal.Close()
+
+	if err := journal.MkdirAll(filepath.Join(dir, "new"), 0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if entries, err := ReadJournal(nil, logPath); err != nil || len(entries) != 2 {
+		t.Fatalf("ReadJournal() = %+v, %v; want 2 records", entries, err)
+	}
+
+	if _, err := Undo(nil, logPath, "run-real"); err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Undo() created directory still exists: %v", err)
+	}
+}
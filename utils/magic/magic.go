@@ -0,0 +1,326 @@
+package magic
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils"
+	"github.com/ondrovic/common/utils/filetypes"
+)
+
+// HeaderSize is how many bytes from the start of a file are read to detect its content.
+const HeaderSize = 512
+
+// ErrNoSignature is returned by `Match` in `types.DetectionModes.Content` for a file type that no
+// registered signature detects, such as `types.FileTypes.Code`, since no file could ever match it.
+var ErrNoSignature = errors.New("file type cannot be detected by content")
+
+// Signature maps a pattern in the first bytes of a file to a file type.
+type Signature struct {
+	// Name describes the format, such as `PNG`.
+	Name string
+	// FileType is the file type a matching file belongs to.
+	FileType types.FileType
+	// Match reports whether header, the first bytes of a file, holds the signature. header may be
+	// shorter than `HeaderSize` for small files.
+	Match func(header []byte) bool
+	// AlsoMatches lists the extensions of formats stored in a generic container, such as `.jar` or
+	// `.cbz` for ZIP. In `types.DetectionModes.Both` content detected by the signature agrees with these
+	// extensions whatever file type they belong to.
+	AlsoMatches []string
+}
+
+var (
+	mu         sync.RWMutex
+	signatures = builtinSignatures()
+)
+
+// RegisterSignature adds sig to the signatures checked by `Detect`. Signatures registered later are
+// checked first, so they can refine or override the built-in ones.
+func RegisterSignature(sig Signature) error {
+	if sig.Name == "" || sig.FileType == "" || sig.Match == nil {
+		return fmt.Errorf("signature needs a name, file type and match func")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	signatures = append([]Signature{sig}, signatures...)
+	return nil
+}
+
+// Signatures returns the registered signatures in the order they are checked.
+func Signatures() []Signature {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return append([]Signature(nil), signatures...)
+}
+
+// Detect returns the file type of the first signature found in header, or an empty file type when the
+// content is not recognised.
+func Detect(header []byte) (types.FileType, string) {
+	sig, _ := detect(header)
+	return sig.FileType, sig.Name
+}
+
+// detect returns the first signature found in header and whether there was one.
+func detect(header []byte) (Signature, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, sig := range signatures {
+		if sig.Match(header) {
+			return sig, true
+		}
+	}
+	return Signature{}, false
+}
+
+// HasSignature reports whether a registered signature detects fileType, so that `Detect` can ever
+// return it. Of the built-in file types only `types.FileTypes.Code` has no signature, but plain text
+// formats such as `.txt`, `.csv` or `.md` documents and `.srt` subtitles, or `.iso` disk images whose
+// marker lies past `HeaderSize`, are not recognised either.
+func HasSignature(fileType types.FileType) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, sig := range signatures {
+		if sig.FileType == fileType {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectFile reads the first `HeaderSize` bytes of path and returns the file type of its content, or an
+// empty file type when the content is not recognised.
+func DetectFile(ops types.DirOps, path string) (types.FileType, error) {
+	sig, err := detectFile(ops, path)
+	return sig.FileType, err
+}
+
+// detectFile reads the first `HeaderSize` bytes of path and returns the first signature found in them,
+// or a zero Signature when the content is not recognised.
+func detectFile(ops types.DirOps, path string) (Signature, error) {
+	if ops == nil {
+		ops = &types.RealDirOps{}
+	}
+
+	file, err := ops.Open(path)
+	if err != nil {
+		return Signature{}, err
+	}
+	defer file.Close()
+
+	header := make([]byte, HeaderSize)
+	n, err := io.ReadFull(file, header)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return Signature{}, fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	sig, _ := detect(header[:n])
+	return sig, nil
+}
+
+// Match reports whether path belongs to fileType under mode and returns the file type path was
+// identified as, `types.FileTypes.Any` when nothing claims it.
+//
+//   - `types.DetectionModes.Extension`, or an empty mode, only looks at the extension and never opens
+//     the file.
+//   - `types.DetectionModes.Content` ignores the extension and reads the first bytes of the file. Only
+//     content a signature recognises can match, see `HasSignature`, and a fileType without any
+//     signature returns an error wrapping `ErrNoSignature`.
+//   - `types.DetectionModes.Both` requires the extension to be valid for fileType and the content not
+//     to be detected as another file type. Content no signature recognises, such as a `.txt` document
+//     or a source file, is matched by its extension alone, and content detected as a container agrees
+//     with the extensions of the formats stored in it, such as a `.jar` detected as ZIP, see
+//     `Signature.AlsoMatches`. The file is only read when the extension matches.
+//
+// `types.FileTypes.Any` matches every file in every mode.
+func Match(ops types.DirOps, fileType types.FileType, path string, mode types.DetectionMode) (types.FileType, bool, error) {
+	switch mode {
+	case types.DetectionModes.Content:
+		if fileType != types.FileTypes.Any && !HasSignature(fileType) {
+			return "", false, fmt.Errorf("%w: %s", ErrNoSignature, fileType)
+		}
+	case types.DetectionModes.Both:
+	case types.DetectionModes.Extension, "":
+		return utils.DetectFileType(path), utils.IsExtensionValid(fileType, path), nil
+	default:
+		return "", false, fmt.Errorf("unknown detection mode %q", mode)
+	}
+
+	if mode == types.DetectionModes.Both && !utils.IsExtensionValid(fileType, path) {
+		return utils.DetectFileType(path), false, nil
+	}
+
+	sig, err := detectFile(ops, path)
+	if err != nil {
+		return "", false, err
+	}
+	detected := sig.FileType
+	if detected == "" {
+		if mode == types.DetectionModes.Both {
+			// Nothing contradicts the extension, which has already been checked.
+			return utils.DetectFileType(path), true, nil
+		}
+		detected = types.FileTypes.Any
+	}
+	if mode == types.DetectionModes.Both && detected != fileType && fileType != types.FileTypes.Any {
+		if ext, _ := filetypes.Extension(path); ext != "" && slices.Contains(sig.AlsoMatches, strings.ToLower(ext)) {
+			return fileType, true, nil
+		}
+	}
+
+	return detected, fileType == types.FileTypes.Any || detected == fileType, nil
+}
+
+// at returns a match func checking that magic appears at offset.
+func at(offset int, magic ...byte) func([]byte) bool {
+	return func(header []byte) bool {
+		return len(header) >= offset+len(magic) && bytes.Equal(header[offset:offset+len(magic)], magic)
+	}
+}
+
+// all returns a match func checking that every match func matches.
+func all(matches ...func([]byte) bool) func([]byte) bool {
+	return func(header []byte) bool {
+		for _, match := range matches {
+			if !match(header) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// ftypBrand returns a match func for an ISO base media file (MP4, MOV, HEIC...) whose major brand is
+// one of brands.
+func ftypBrand(brands ...string) func([]byte) bool {
+	return func(header []byte) bool {
+		if !at(4, 'f', 't', 'y', 'p')(header) || len(header) < 12 {
+			return false
+		}
+		for _, brand := range brands {
+			if string(header[8:12]) == brand {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// zipEntry returns a match func for a ZIP archive whose first entry name starts with name, which is how
+// Office Open XML, OpenDocument and EPUB files are told apart from plain archives.
+func zipEntry(name string) func([]byte) bool {
+	return all(at(0, 'P', 'K', 0x03, 0x04), at(30, []byte(name)...))
+}
+
+// mpegTS matches an MPEG transport stream, which repeats a sync byte every 188 bytes.
+func mpegTS(header []byte) bool {
+	return len(header) > 188 && header[0] == 0x47 && header[188] == 0x47
+}
+
+// zipFormats are the extensions of formats stored in ZIP archives whose first entry does not tell them
+// apart, or does not come first, such as Java and Android packages or comic book archives.
+var zipFormats = []string{".jar", ".apk", ".cbz", ".pages", ".docx", ".xlsx", ".pptx", ".odt", ".epub"}
+
+// builtinSignatures returns the signatures known out of the box, more specific signatures come first.
+func builtinSignatures() []Signature {
+	return []Signature{
+		// Images and audio stored in ISO base media files.
+		{Name: "HEIC", FileType: types.FileTypes.Image, Match: ftypBrand("heic", "heix", "hevc", "mif1", "msf1", "avif")},
+		{Name: "M4A", FileType: types.FileTypes.Audio, Match: ftypBrand("M4A ", "M4B ")},
+		// Documents and ebooks stored in ZIP archives.
+		{Name: "Office Open XML", FileType: types.FileTypes.Documents, Match: zipEntry("[Content_Types].xml")},
+		{Name: "Office Open XML", FileType: types.FileTypes.Documents, Match: zipEntry("word/")},
+		{Name: "Office Open XML", FileType: types.FileTypes.Documents, Match: zipEntry("xl/")},
+		{Name: "Office Open XML", FileType: types.FileTypes.Documents, Match: zipEntry("ppt/")},
+		{Name: "OpenDocument", FileType: types.FileTypes.Documents, Match: zipEntry("mimetypeapplication/vnd.oasis.opendocument")},
+		{Name: "EPUB", FileType: types.FileTypes.Ebooks, Match: zipEntry("mimetypeapplication/epub+zip")},
+
+		// Video.
+		{Name: "MP4", FileType: types.FileTypes.Video, Match: at(4, 'f', 't', 'y', 'p'), AlsoMatches: []string{".m4a", ".m4b"}},
+		{Name: "Matroska", FileType: types.FileTypes.Video, Match: at(0, 0x1A, 0x45, 0xDF, 0xA3), AlsoMatches: []string{".mka"}},
+		{Name: "AVI", FileType: types.FileTypes.Video, Match: all(at(0, 'R', 'I', 'F', 'F'), at(8, 'A', 'V', 'I', ' '))},
+		{Name: "FLV", FileType: types.FileTypes.Video, Match: at(0, 'F', 'L', 'V', 0x01)},
+		{Name: "ASF", FileType: types.FileTypes.Video, Match: at(0, 0x30, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11), AlsoMatches: []string{".wma"}},
+		{Name: "MPEG-PS", FileType: types.FileTypes.Video, Match: at(0, 0x00, 0x00, 0x01, 0xBA)},
+		{Name: "MPEG", FileType: types.FileTypes.Video, Match: at(0, 0x00, 0x00, 0x01, 0xB3)},
+		{Name: "MPEG-TS", FileType: types.FileTypes.Video, Match: mpegTS},
+
+		// Images.
+		{Name: "JPEG", FileType: types.FileTypes.Image, Match: at(0, 0xFF, 0xD8, 0xFF)},
+		{Name: "PNG", FileType: types.FileTypes.Image, Match: at(0, 0x89, 'P', 'N', 'G', 0x0D, 0x0A, 0x1A, 0x0A)},
+		{Name: "GIF", FileType: types.FileTypes.Image, Match: at(0, 'G', 'I', 'F', '8', '7', 'a')},
+		{Name: "GIF", FileType: types.FileTypes.Image, Match: at(0, 'G', 'I', 'F', '8', '9', 'a')},
+		{Name: "WebP", FileType: types.FileTypes.Image, Match: all(at(0, 'R', 'I', 'F', 'F'), at(8, 'W', 'E', 'B', 'P'))},
+		{Name: "BMP", FileType: types.FileTypes.Image, Match: at(0, 'B', 'M')},
+		{Name: "TIFF", FileType: types.FileTypes.Image, Match: at(0, 'I', 'I', 0x2A, 0x00)},
+		{Name: "TIFF", FileType: types.FileTypes.Image, Match: at(0, 'M', 'M', 0x00, 0x2A)},
+		{Name: "ICO", FileType: types.FileTypes.Image, Match: at(0, 0x00, 0x00, 0x01, 0x00)},
+
+		// Archives.
+		{Name: "ZIP", FileType: types.FileTypes.Archive, Match: at(0, 'P', 'K', 0x03, 0x04), AlsoMatches: zipFormats},
+		{Name: "ZIP", FileType: types.FileTypes.Archive, Match: at(0, 'P', 'K', 0x05, 0x06), AlsoMatches: zipFormats},
+		{Name: "ZIP", FileType: types.FileTypes.Archive, Match: at(0, 'P', 'K', 0x07, 0x08), AlsoMatches: zipFormats},
+		{Name: "7z", FileType: types.FileTypes.Archive, Match: at(0, '7', 'z', 0xBC, 0xAF, 0x27, 0x1C)},
+		{Name: "RAR", FileType: types.FileTypes.Archive, Match: at(0, 'R', 'a', 'r', '!', 0x1A, 0x07), AlsoMatches: []string{".cbr"}},
+		{Name: "gzip", FileType: types.FileTypes.Archive, Match: at(0, 0x1F, 0x8B)},
+		{Name: "bzip2", FileType: types.FileTypes.Archive, Match: at(0, 'B', 'Z', 'h')},
+		{Name: "xz", FileType: types.FileTypes.Archive, Match: at(0, 0xFD, '7', 'z', 'X', 'Z', 0x00)},
+		{Name: "Zstandard", FileType: types.FileTypes.Archive, Match: at(0, 0x28, 0xB5, 0x2F, 0xFD)},
+		{Name: "tar", FileType: types.FileTypes.Archive, Match: at(257, 'u', 's', 't', 'a', 'r'), AlsoMatches: []string{".ova"}},
+
+		// Documents.
+		{Name: "PDF", FileType: types.FileTypes.Documents, Match: at(0, '%', 'P', 'D', 'F', '-')},
+		{Name: "OLE2", FileType: types.FileTypes.Documents, Match: at(0, 0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1), AlsoMatches: []string{".msi"}},
+		{Name: "RTF", FileType: types.FileTypes.Documents, Match: at(0, '{', '\\', 'r', 't', 'f')},
+
+		// Audio.
+		{Name: "MP3", FileType: types.FileTypes.Audio, Match: at(0, 'I', 'D', '3')},
+		{Name: "MP3", FileType: types.FileTypes.Audio, Match: at(0, 0xFF, 0xFB)},
+		{Name: "MP3", FileType: types.FileTypes.Audio, Match: at(0, 0xFF, 0xF3)},
+		{Name: "FLAC", FileType: types.FileTypes.Audio, Match: at(0, 'f', 'L', 'a', 'C')},
+		{Name: "Ogg", FileType: types.FileTypes.Audio, Match: at(0, 'O', 'g', 'g', 'S')},
+		{Name: "WAVE", FileType: types.FileTypes.Audio, Match: all(at(0, 'R', 'I', 'F', 'F'), at(8, 'W', 'A', 'V', 'E'))},
+		{Name: "AIFF", FileType: types.FileTypes.Audio, Match: all(at(0, 'F', 'O', 'R', 'M'), at(8, 'A', 'I', 'F', 'F'))},
+		{Name: "MIDI", FileType: types.FileTypes.Audio, Match: at(0, 'M', 'T', 'h', 'd')},
+
+		// Fonts.
+		{Name: "WOFF", FileType: types.FileTypes.Fonts, Match: at(0, 'w', 'O', 'F', 'F')},
+		{Name: "WOFF2", FileType: types.FileTypes.Fonts, Match: at(0, 'w', 'O', 'F', '2')},
+		{Name: "OpenType", FileType: types.FileTypes.Fonts, Match: at(0, 'O', 'T', 'T', 'O')},
+		{Name: "TrueType", FileType: types.FileTypes.Fonts, Match: at(0, 0x00, 0x01, 0x00, 0x00, 0x00)},
+
+		// Ebooks.
+		{Name: "Mobipocket", FileType: types.FileTypes.Ebooks, Match: at(60, 'B', 'O', 'O', 'K', 'M', 'O', 'B', 'I')},
+
+		// Executables.
+		{Name: "PE", FileType: types.FileTypes.Executables, Match: at(0, 'M', 'Z')},
+		{Name: "ELF", FileType: types.FileTypes.Executables, Match: at(0, 0x7F, 'E', 'L', 'F')},
+		{Name: "Mach-O", FileType: types.FileTypes.Executables, Match: at(0, 0xCF, 0xFA, 0xED, 0xFE)},
+		{Name: "Mach-O", FileType: types.FileTypes.Executables, Match: at(0, 0xCE, 0xFA, 0xED, 0xFE)},
+		{Name: "Debian package", FileType: types.FileTypes.Executables, Match: at(0, []byte("!<arch>\ndebian")...)},
+		{Name: "RPM", FileType: types.FileTypes.Executables, Match: at(0, 0xED, 0xAB, 0xEE, 0xDB)},
+
+		// Disk images.
+		{Name: "QCOW", FileType: types.FileTypes.DiskImages, Match: at(0, 'Q', 'F', 'I', 0xFB)},
+		{Name: "VMDK", FileType: types.FileTypes.DiskImages, Match: at(0, 'K', 'D', 'M', 'V')},
+		{Name: "VHDX", FileType: types.FileTypes.DiskImages, Match: at(0, []byte("vhdxfile")...)},
+		{Name: "VHD", FileType: types.FileTypes.DiskImages, Match: at(0, []byte("conectix")...)},
+		{Name: "VDI", FileType: types.FileTypes.DiskImages, Match: at(64, 0x7F, 0x10, 0xDA, 0xBE)},
+
+		// Subtitles.
+		{Name: "WebVTT", FileType: types.FileTypes.Subtitles, Match: at(0, []byte("WEBVTT")...)},
+		{Name: "WebVTT", FileType: types.FileTypes.Subtitles, Match: at(0, []byte("\xEF\xBB\xBFWEBVTT")...)},
+		{Name: "SubStation Alpha", FileType: types.FileTypes.Subtitles, Match: at(0, []byte("[Script Info]")...)},
+	}
+}
@@ -0,0 +1,276 @@
+package magic
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils/memfs"
+)
+
+// header returns magic placed at offset in an otherwise zeroed header of size bytes.
+func header(size, offset int, magic ...byte) []byte {
+	h := make([]byte, size)
+	copy(h[offset:], magic)
+	return h
+}
+
+// zipHeader returns the start of a ZIP archive whose first entry is named name.
+func zipHeader(name string) []byte {
+	h := header(30+len(name), 0, 'P', 'K', 0x03, 0x04)
+	copy(h[30:], name)
+	return h
+}
+
+// tsHeader returns the start of an MPEG transport stream.
+func tsHeader() []byte {
+	h := header(HeaderSize, 0, 0x47)
+	h[188] = 0x47
+	return h
+}
+
+// TestDetect tests the Detect func.
+func TestDetect(t *testing.T) {
+	tests := []*types.TestLayout[[]byte, types.FileType]{
+		{Name: "MP4", Input: []byte("\x00\x00\x00\x18ftypisom"), Expected: types.FileTypes.Video},
+		{Name: "QuickTime", Input: []byte("\x00\x00\x00\x14ftypqt  "), Expected: types.FileTypes.Video},
+		{Name: "Matroska", Input: []byte("\x1a\x45\xdf\xa3\x9f"), Expected: types.FileTypes.Video},
+		{Name: "AVI", Input: []byte("RIFF\x00\x00\x00\x00AVI LIST"), Expected: types.FileTypes.Video},
+		{Name: "MPEG-TS", Input: tsHeader(), Expected: types.FileTypes.Video},
+		{Name: "HEIC", Input: []byte("\x00\x00\x00\x18ftypheic"), Expected: types.FileTypes.Image},
+		{Name: "JPEG", Input: []byte("\xff\xd8\xff\xe0\x00\x10JFIF"), Expected: types.FileTypes.Image},
+		{Name: "PNG", Input: []byte("\x89PNG\r\n\x1a\n"), Expected: types.FileTypes.Image},
+		{Name: "GIF", Input: []byte("GIF89a"), Expected: types.FileTypes.Image},
+		{Name: "WebP", Input: []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), Expected: types.FileTypes.Image},
+		{Name: "ZIP", Input: zipHeader("photos/cat.jpg"), Expected: types.FileTypes.Archive},
+		{Name: "Empty ZIP", Input: []byte("PK\x05\x06"), Expected: types.FileTypes.Archive},
+		{Name: "7z", Input: []byte("7z\xbc\xaf\x27\x1c"), Expected: types.FileTypes.Archive},
+		{Name: "RAR", Input: []byte("Rar!\x1a\x07\x01\x00"), Expected: types.FileTypes.Archive},
+		{Name: "gzip", Input: []byte("\x1f\x8b\x08"), Expected: types.FileTypes.Archive},
+		{Name: "tar", Input: header(HeaderSize, 257, 'u', 's', 't', 'a', 'r'), Expected: types.FileTypes.Archive},
+		{Name: "PDF", Input: []byte("%PDF-1.7"), Expected: types.FileTypes.Documents},
+		{Name: "OLE2", Input: []byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1"), Expected: types.FileTypes.Documents},
+		{Name: "Office Open XML", Input: zipHeader("[Content_Types].xml"), Expected: types.FileTypes.Documents},
+		{Name: "OpenDocument", Input: zipHeader("mimetypeapplication/vnd.oasis.opendocument.text"), Expected: types.FileTypes.Documents},
+		{Name: "EPUB", Input: zipHeader("mimetypeapplication/epub+zip"), Expected: types.FileTypes.Ebooks},
+		{Name: "Mobipocket", Input: header(68, 60, 'B', 'O', 'O', 'K', 'M', 'O', 'B', 'I'), Expected: types.FileTypes.Ebooks},
+		{Name: "M4A", Input: []byte("\x00\x00\x00\x20ftypM4A "), Expected: types.FileTypes.Audio},
+		{Name: "MP3 with ID3 tag", Input: []byte("ID3\x04\x00"), Expected: types.FileTypes.Audio},
+		{Name: "FLAC", Input: []byte("fLaC\x00\x00\x00\x22"), Expected: types.FileTypes.Audio},
+		{Name: "WAVE", Input: []byte("RIFF\x00\x00\x00\x00WAVEfmt "), Expected: types.FileTypes.Audio},
+		{Name: "WOFF2", Input: []byte("wOF2\x00\x01\x00\x00"), Expected: types.FileTypes.Fonts},
+		{Name: "TrueType", Input: []byte("\x00\x01\x00\x00\x00\x10"), Expected: types.FileTypes.Fonts},
+		{Name: "PE", Input: []byte("MZ\x90\x00"), Expected: types.FileTypes.Executables},
+		{Name: "ELF", Input: []byte("\x7fELF\x02\x01"), Expected: types.FileTypes.Executables},
+		{Name: "Debian package", Input: []byte("!<arch>\ndebian-binary"), Expected: types.FileTypes.Executables},
+		{Name: "QCOW", Input: []byte("QFI\xfb\x00\x00\x00\x03"), Expected: types.FileTypes.DiskImages},
+		{Name: "VHDX", Input: []byte("vhdxfile"), Expected: types.FileTypes.DiskImages},
+		{Name: "WebVTT", Input: []byte("WEBVTT\n\n00:00.000 --> 00:01.000"), Expected: types.FileTypes.Subtitles},
+		{Name: "WebVTT with BOM", Input: []byte("\xef\xbb\xbfWEBVTT\n"), Expected: types.FileTypes.Subtitles},
+		{Name: "Plain text", Input: []byte("just some notes"), Expected: ""},
+		{Name: "Truncated signature", Input: []byte("\x89PN"), Expected: ""},
+		{Name: "Empty", Input: []byte{}, Expected: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if result, _ := Detect(test.Input); result != test.Expected {
+				t.Errorf("Detect() - %v = %q; want %q", test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
+// CreateTestFS creates an in-memory tree of correctly named, renamed and unrecognised files.
+//
+//	/root
+//	├── movie.mp4 (MP4)
+//	├── movie.bin (MP4)
+//	├── fake.mp4 (PNG)
+//	├── photo.png (PNG)
+//	├── notes.txt (text)
+//	└── empty.mp4 (0 B)
+func CreateTestFS(t *testing.T) *memfs.MemDirOps {
+	t.Helper()
+	mp4 := []byte("\x00\x00\x00\x18ftypisom")
+	png := []byte("\x89PNG\r\n\x1a\n")
+	zip := []byte("PK\x03\x04\x14\x00\x08\x00\x08\x00")
+	tar := make([]byte, HeaderSize)
+	copy(tar[257:], "ustar")
+	ops := memfs.New()
+	files := map[string][]byte{
+		"/root/movie.mp4": mp4,
+		"/root/movie.bin": mp4,
+		"/root/fake.mp4":  png,
+		"/root/photo.png": png,
+		"/root/notes.txt": bytes.Repeat([]byte("notes "), 200),
+		"/root/empty.mp4": {},
+		"/root/main.go":   []byte("package main\n"),
+		"/root/fake.go":   png,
+		"/root/movie.srt": []byte("1\n00:00:01,000 --> 00:00:02,000\nHello\n"),
+		"/root/app.jar":   zip,
+		"/root/app.apk":   zip,
+		"/root/comic.cbz": zip,
+		"/root/doc.pages": zip,
+		"/root/zip.mp4":   zip,
+		"/root/comic.cbr": []byte("Rar!\x1a\x07\x01\x00"),
+		"/root/vm.ova":    tar,
+		"/root/song.wma":  []byte("\x30\x26\xB2\x75\x8E\x66\xCF\x11"),
+		"/root/song.mka":  []byte("\x1A\x45\xDF\xA3"),
+		"/root/setup.msi": []byte("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1"),
+	}
+	for name, data := range files {
+		if err := ops.WriteFile(name, data, 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	return ops
+}
+
+// TestDetectFile tests the DetectFile func.
+func TestDetectFile(t *testing.T) {
+	tests := []*types.TestLayout[string, types.FileType]{
+		{Name: "Named correctly", Input: "/root/movie.mp4", Expected: types.FileTypes.Video},
+		{Name: "Renamed", Input: "/root/movie.bin", Expected: types.FileTypes.Video},
+		{Name: "Mislabelled", Input: "/root/fake.mp4", Expected: types.FileTypes.Image},
+		{Name: "Unrecognised", Input: "/root/notes.txt", Expected: ""},
+		{Name: "Empty file", Input: "/root/empty.mp4", Expected: ""},
+	}
+
+	ops := CreateTestFS(t)
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := DetectFile(ops, test.Input)
+			if err != nil {
+				t.Fatalf("DetectFile() - %v error = %v", test.Name, err)
+			}
+			if result != test.Expected {
+				t.Errorf("DetectFile() - %v = %q; want %q", test.Name, result, test.Expected)
+			}
+		})
+	}
+
+	ops.InjectError(memfs.OpOpen, "/root/movie.mp4", errors.New("simulated Open error"))
+	if _, err := DetectFile(ops, "/root/movie.mp4"); err == nil {
+		t.Errorf("DetectFile() with Open error = nil; want error")
+	}
+}
+
+// TestMatch tests the Match func in each detection mode.
+func TestMatch(t *testing.T) {
+	type InputStruct struct {
+		fileType types.FileType
+		path     string
+		mode     types.DetectionMode
+	}
+	type ExpectedResults struct {
+		fileType types.FileType
+		ok       bool
+	}
+
+	video := types.FileTypes.Video
+	tests := []*types.TestLayout[InputStruct, ExpectedResults]{
+		{Name: "Extension - named correctly", Input: InputStruct{video, "/root/movie.mp4", types.DetectionModes.Extension}, Expected: ExpectedResults{video, true}},
+		{Name: "Extension - renamed", Input: InputStruct{video, "/root/movie.bin", types.DetectionModes.Extension}, Expected: ExpectedResults{types.FileTypes.Any, false}},
+		{Name: "Extension - mislabelled", Input: InputStruct{video, "/root/fake.mp4", types.DetectionModes.Extension}, Expected: ExpectedResults{video, true}},
+		{Name: "Extension - empty mode", Input: InputStruct{video, "/root/fake.mp4", ""}, Expected: ExpectedResults{video, true}},
+		{Name: "Extension - missing file is not opened", Input: InputStruct{video, "/root/missing.mp4", types.DetectionModes.Extension}, Expected: ExpectedResults{video, true}},
+		{Name: "Content - named correctly", Input: InputStruct{video, "/root/movie.mp4", types.DetectionModes.Content}, Expected: ExpectedResults{video, true}},
+		{Name: "Content - renamed", Input: InputStruct{video, "/root/movie.bin", types.DetectionModes.Content}, Expected: ExpectedResults{video, true}},
+		{Name: "Content - mislabelled", Input: InputStruct{video, "/root/fake.mp4", types.DetectionModes.Content}, Expected: ExpectedResults{types.FileTypes.Image, false}},
+		{Name: "Content - unrecognised", Input: InputStruct{video, "/root/notes.txt", types.DetectionModes.Content}, Expected: ExpectedResults{types.FileTypes.Any, false}},
+		{Name: "Content - any", Input: InputStruct{types.FileTypes.Any, "/root/notes.txt", types.DetectionModes.Content}, Expected: ExpectedResults{types.FileTypes.Any, true}},
+		{Name: "Both - named correctly", Input: InputStruct{video, "/root/movie.mp4", types.DetectionModes.Both}, Expected: ExpectedResults{video, true}},
+		{Name: "Both - renamed", Input: InputStruct{video, "/root/movie.bin", types.DetectionModes.Both}, Expected: ExpectedResults{types.FileTypes.Any, false}},
+		{Name: "Both - mislabelled", Input: InputStruct{video, "/root/fake.mp4", types.DetectionModes.Both}, Expected: ExpectedResults{types.FileTypes.Image, false}},
+		{Name: "Both - empty file", Input: InputStruct{video, "/root/empty.mp4", types.DetectionModes.Both}, Expected: ExpectedResults{video, true}},
+		{Name: "Both - unrecognised document", Input: InputStruct{types.FileTypes.Documents, "/root/notes.txt", types.DetectionModes.Both}, Expected: ExpectedResults{types.FileTypes.Documents, true}},
+		{Name: "Both - code without a signature", Input: InputStruct{types.FileTypes.Code, "/root/main.go", types.DetectionModes.Both}, Expected: ExpectedResults{types.FileTypes.Code, true}},
+		{Name: "Both - mislabelled code", Input: InputStruct{types.FileTypes.Code, "/root/fake.go", types.DetectionModes.Both}, Expected: ExpectedResults{types.FileTypes.Image, false}},
+		{Name: "Content - unrecognised subtitles", Input: InputStruct{types.FileTypes.Subtitles, "/root/movie.srt", types.DetectionModes.Content}, Expected: ExpectedResults{types.FileTypes.Any, false}},
+		{Name: "Both - unrecognised subtitles", Input: InputStruct{types.FileTypes.Subtitles, "/root/movie.srt", types.DetectionModes.Both}, Expected: ExpectedResults{types.FileTypes.Subtitles, true}},
+		{Name: "Both - jar in ZIP", Input: InputStruct{types.FileTypes.Executables, "/root/app.jar", types.DetectionModes.Both}, Expected: ExpectedResults{types.FileTypes.Executables, true}},
+		{Name: "Both - apk in ZIP", Input: InputStruct{types.FileTypes.Executables, "/root/app.apk", types.DetectionModes.Both}, Expected: ExpectedResults{types.FileTypes.Executables, true}},
+		{Name: "Both - cbz in ZIP", Input: InputStruct{types.FileTypes.Ebooks, "/root/comic.cbz", types.DetectionModes.Both}, Expected: ExpectedResults{types.FileTypes.Ebooks, true}},
+		{Name: "Both - pages in ZIP", Input: InputStruct{types.FileTypes.Documents, "/root/doc.pages", types.DetectionModes.Both}, Expected: ExpectedResults{types.FileTypes.Documents, true}},
+		{Name: "Both - cbr in RAR", Input: InputStruct{types.FileTypes.Ebooks, "/root/comic.cbr", types.DetectionModes.Both}, Expected: ExpectedResults{types.FileTypes.Ebooks, true}},
+		{Name: "Both - ova in tar", Input: InputStruct{types.FileTypes.DiskImages, "/root/vm.ova", types.DetectionModes.Both}, Expected: ExpectedResults{types.FileTypes.DiskImages, true}},
+		{Name: "Both - wma in ASF", Input: InputStruct{types.FileTypes.Audio, "/root/song.wma", types.DetectionModes.Both}, Expected: ExpectedResults{types.FileTypes.Audio, true}},
+		{Name: "Both - mka in Matroska", Input: InputStruct{types.FileTypes.Audio, "/root/song.mka", types.DetectionModes.Both}, Expected: ExpectedResults{types.FileTypes.Audio, true}},
+		{Name: "Both - msi in OLE2", Input: InputStruct{types.FileTypes.Executables, "/root/setup.msi", types.DetectionModes.Both}, Expected: ExpectedResults{types.FileTypes.Executables, true}},
+		{Name: "Both - ZIP is not a video", Input: InputStruct{video, "/root/zip.mp4", types.DetectionModes.Both}, Expected: ExpectedResults{types.FileTypes.Archive, false}},
+		{Name: "Content - jar is an archive", Input: InputStruct{types.FileTypes.Executables, "/root/app.jar", types.DetectionModes.Content}, Expected: ExpectedResults{types.FileTypes.Archive, false}},
+		{Name: "Both - any", Input: InputStruct{types.FileTypes.Any, "/root/photo.png", types.DetectionModes.Both}, Expected: ExpectedResults{types.FileTypes.Image, true}},
+	}
+
+	ops := CreateTestFS(t)
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fileType, ok, err := Match(ops, test.Input.fileType, test.Input.path, test.Input.mode)
+			if err != nil {
+				t.Fatalf("Match() - %v error = %v", test.Name, err)
+			}
+			if fileType != test.Expected.fileType || ok != test.Expected.ok {
+				t.Errorf("Match() - %v = %q, %v; want %q, %v", test.Name, fileType, ok, test.Expected.fileType, test.Expected.ok)
+			}
+		})
+	}
+}
+
+// TestMatch_Errors tests Match error handling.
+func TestMatch_Errors(t *testing.T) {
+	ops := CreateTestFS(t)
+
+	if _, _, err := Match(ops, types.FileTypes.Video, "/root/missing.mp4", types.DetectionModes.Content); err == nil {
+		t.Errorf("Match() missing file = nil; want error")
+	}
+	if _, _, err := Match(ops, types.FileTypes.Video, "/root/movie.mp4", "unknown"); err == nil {
+		t.Errorf("Match() unknown mode = nil; want error")
+	}
+	if _, _, err := Match(ops, types.FileTypes.Code, "/root/main.go", types.DetectionModes.Content); !errors.Is(err, ErrNoSignature) {
+		t.Errorf("Match() code in Content mode error = %v; want %v", err, ErrNoSignature)
+	}
+}
+
+// TestHasSignature tests which file types content detection can recognise.
+func TestHasSignature(t *testing.T) {
+	tests := []*types.TestLayout[types.FileType, bool]{
+		{Name: "Video", Input: types.FileTypes.Video, Expected: true},
+		{Name: "Documents", Input: types.FileTypes.Documents, Expected: true},
+		{Name: "Code", Input: types.FileTypes.Code, Expected: false},
+		{Name: "Subtitles", Input: types.FileTypes.Subtitles, Expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if result := HasSignature(test.Input); result != test.Expected {
+				t.Errorf("HasSignature(%s) = %v; want %v", test.Input, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestRegisterSignature tests that registered signatures are checked before the built-in ones.
+func TestRegisterSignature(t *testing.T) {
+	defer func(saved []Signature) {
+		mu.Lock()
+		signatures = saved
+		mu.Unlock()
+	}(Signatures())
+
+	if err := RegisterSignature(Signature{Name: "Broken"}); err == nil {
+		t.Errorf("RegisterSignature() without a file type and match func = nil; want error")
+	}
+
+	sig := Signature{Name: "Custom PNG", FileType: types.FileTypes.Documents, Match: at(0, 0x89, 'P', 'N', 'G')}
+	if err := RegisterSignature(sig); err != nil {
+		t.Fatalf("RegisterSignature() error = %v", err)
+	}
+	if fileType, name := Detect([]byte("\x89PNG\r\n\x1a\n")); fileType != types.FileTypes.Documents || name != "Custom PNG" {
+		t.Errorf("Detect() after RegisterSignature = %q, %q; want %q, %q", fileType, name, types.FileTypes.Documents, "Custom PNG")
+	}
+	if len(Signatures()) != len(builtinSignatures())+1 {
+		t.Errorf("Signatures() = %d entries; want %d", len(Signatures()), len(builtinSignatures())+1)
+	}
+}
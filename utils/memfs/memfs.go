@@ -0,0 +1,816 @@
+package memfs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ondrovic/common/types"
+)
+
+// Op names a `types.DirOps` operation that an error can be injected for.
+type Op string
+
+// The operations that errors can be injected for with `InjectError`.
+const (
+	OpReadDir   Op = "readdir"
+	OpRemove    Op = "remove"
+	OpStat      Op = "stat"
+	OpLstat     Op = "lstat"
+	OpOpen      Op = "open"
+	OpOpenFile  Op = "openfile"
+	OpRename    Op = "rename"
+	OpMkdirAll  Op = "mkdirall"
+	OpRemoveAll Op = "removeall"
+	OpChtimes   Op = "chtimes"
+	OpWalkDir   Op = "walkdir"
+	OpWriteFile Op = "writefile"
+	OpSymlink   Op = "symlink"
+)
+
+// maxLinks is the number of symlinks a single lookup may follow before it fails with `ErrLoop`.
+const maxLinks = 40
+
+var (
+	// ErrNotEmpty is returned when removing a directory that still has entries.
+	ErrNotEmpty = errors.New("directory not empty")
+	// ErrNotDir is returned when a path component that must be a directory is a file.
+	ErrNotDir = errors.New("not a directory")
+	// ErrIsDir is returned when a file operation is attempted on a directory.
+	ErrIsDir = errors.New("is a directory")
+	// ErrLoop is returned when resolving a path follows too many symlinks.
+	ErrLoop = errors.New("too many levels of symbolic links")
+)
+
+// MemDirOps is an in-memory filesystem implementing `types.DirOps`. It holds real files, directories
+// and symlinks with contents, sizes, modification times and permissions, and lets tests inject an error
+// for a given path and operation. Symlinks behave like they do on disk: `Lstat`, `Remove`, `Rename` and
+// `RemoveAll` act on the link itself while every other operation follows it. Paths are always treated
+// as absolute and slash separated, so `a/b`, `/a/b` and `\a\b` all name the same entry. MemDirOps is
+// safe for concurrent use.
+type MemDirOps struct {
+	mu     sync.RWMutex
+	root   *node
+	errors map[Op]map[string]error
+
+	// Now returns the time stamped on created and modified entries, it defaults to `time.Now`.
+	Now func() time.Time
+}
+
+// node is a single file, directory or symlink held by MemDirOps.
+type node struct {
+	name     string
+	mode     fs.FileMode
+	modTime  time.Time
+	accTime  time.Time
+	chgTime  time.Time
+	data     []byte
+	target   string
+	children map[string]*node
+}
+
+var _ types.DirOps = (*MemDirOps)(nil)
+
+// New returns an empty MemDirOps containing only the root directory.
+func New() *MemDirOps {
+	m := &MemDirOps{
+		errors: map[Op]map[string]error{},
+		Now:    time.Now,
+	}
+	m.root = m.newNode("/", fs.ModeDir|0o755)
+	return m
+}
+
+// InjectError makes every call of op on name return err until `ClearErrors` is called. Passing a nil
+// err removes a previously injected error.
+func (m *MemDirOps) InjectError(op Op, name string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err == nil {
+		delete(m.errors[op], clean(name))
+		return
+	}
+	if m.errors[op] == nil {
+		m.errors[op] = map[string]error{}
+	}
+	m.errors[op][clean(name)] = err
+}
+
+// ClearErrors removes every injected error.
+func (m *MemDirOps) ClearErrors() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.errors = map[Op]map[string]error{}
+}
+
+// WriteFile creates or truncates name with data, creating any missing parent directories. Like
+// `os.WriteFile` perm only applies when name is created, an existing file keeps its permissions.
+func (m *MemDirOps) WriteFile(name string, data []byte, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.injected(OpWriteFile, name); err != nil {
+		return err
+	}
+
+	parent, err := m.mkdirAll(path.Dir(clean(name)), 0o755)
+	if err != nil {
+		return &fs.PathError{Op: string(OpWriteFile), Path: name, Err: err}
+	}
+
+	base := path.Base(clean(name))
+	if existing, ok := parent.children[base]; ok {
+		if existing.mode&fs.ModeSymlink != 0 {
+			if existing, err = m.lookup(name); err != nil {
+				return &fs.PathError{Op: string(OpWriteFile), Path: name, Err: err}
+			}
+		}
+		if existing.mode.IsDir() {
+			return &fs.PathError{Op: string(OpWriteFile), Path: name, Err: ErrIsDir}
+		}
+		existing.data = append([]byte(nil), data...)
+		existing.modTime = m.Now()
+		existing.chgTime = existing.modTime
+		return nil
+	}
+
+	n := m.newNode(base, perm.Perm())
+	n.data = append([]byte(nil), data...)
+	parent.children[base] = n
+	parent.modTime = n.modTime
+	return nil
+}
+
+// ReadFile returns the contents of name.
+func (m *MemDirOps) ReadFile(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	n, err := m.lookup(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	if n.mode.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: ErrIsDir}
+	}
+	return append([]byte(nil), n.data...), nil
+}
+
+// Chmod changes the permission bits of name.
+func (m *MemDirOps) Chmod(name string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n, err := m.lookup(name)
+	if err != nil {
+		return &fs.PathError{Op: "chmod", Path: name, Err: err}
+	}
+	n.mode = n.mode.Type() | perm.Perm()
+	return nil
+}
+
+// Symlink creates newname as a symlink to oldname, creating any missing parent directories. Like
+// `os.Symlink` the target does not need to exist and a relative target is resolved from the directory
+// holding the link.
+func (m *MemDirOps) Symlink(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.injected(OpSymlink, newname); err != nil {
+		return err
+	}
+
+	parent, err := m.mkdirAll(path.Dir(clean(newname)), 0o755)
+	if err != nil {
+		return &os.LinkError{Op: string(OpSymlink), Old: oldname, New: newname, Err: err}
+	}
+
+	base := path.Base(clean(newname))
+	if _, ok := parent.children[base]; ok {
+		return &os.LinkError{Op: string(OpSymlink), Old: oldname, New: newname, Err: fs.ErrExist}
+	}
+
+	n := m.newNode(base, fs.ModeSymlink|0o777)
+	n.target = filepath.ToSlash(oldname)
+	parent.children[base] = n
+	parent.modTime = n.modTime
+	return nil
+}
+
+// ReadDir implements the `ReadDir` function of the `types.DirOps` interface.
+func (m *MemDirOps) ReadDir(name string) ([]os.DirEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.readDir(name)
+}
+
+// Remove implements the `Remove` function of the `types.DirOps` interface.
+func (m *MemDirOps) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.injected(OpRemove, name); err != nil {
+		return err
+	}
+
+	parent, n, err := m.lookupWithParent(name)
+	if err != nil {
+		return &fs.PathError{Op: string(OpRemove), Path: name, Err: err}
+	}
+	if parent == nil {
+		return &fs.PathError{Op: string(OpRemove), Path: name, Err: fs.ErrPermission}
+	}
+	if n.mode.IsDir() && len(n.children) > 0 {
+		return &fs.PathError{Op: string(OpRemove), Path: name, Err: ErrNotEmpty}
+	}
+
+	delete(parent.children, n.name)
+	parent.modTime = m.Now()
+	return nil
+}
+
+// Stat implements the `Stat` function of the `types.DirOps` interface.
+func (m *MemDirOps) Stat(name string) (os.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.stat(OpStat, name)
+}
+
+// Lstat implements the `Lstat` function of the `types.DirOps` interface.
+func (m *MemDirOps) Lstat(name string) (os.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.stat(OpLstat, name)
+}
+
+// Open implements the `Open` function of the `types.DirOps` interface. The returned file reads from a
+// copy of the contents taken when it was opened.
+func (m *MemDirOps) Open(name string) (fs.File, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if err := m.injected(OpOpen, name); err != nil {
+		return nil, err
+	}
+
+	n, err := m.lookup(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: string(OpOpen), Path: name, Err: err}
+	}
+
+	return &file{
+		info:   n.infoNamed(path.Base(clean(name))),
+		reader: bytes.NewReader(append([]byte(nil), n.data...)),
+	}, nil
+}
+
+// OpenFile implements the `OpenFile` function of the `types.DirOps` interface. Unlike `WriteFile` the
+// parent directory must exist. Writes go straight to the entry, so they are visible to every later
+// `Open`, and `os.O_EXCL` is checked and the entry created under the lock, so only one of several
+// concurrent callers can create name.
+func (m *MemDirOps) OpenFile(name string, flag int, perm os.FileMode) (types.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.injected(OpOpenFile, name); err != nil {
+		return nil, err
+	}
+
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	_, n, err := m.lookupWithParent(name)
+	switch {
+	case err == nil && flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case err == nil && n.mode&fs.ModeSymlink != 0:
+		if n, err = m.lookup(name); err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+	case errors.Is(err, fs.ErrNotExist) && flag&os.O_CREATE != 0:
+		parent, err := m.lookup(path.Dir(clean(name)))
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		if !parent.mode.IsDir() {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: ErrNotDir}
+		}
+		n = m.newNode(path.Base(clean(name)), perm.Perm())
+		parent.children[n.name] = n
+		parent.modTime = n.modTime
+	case err != nil:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	if n.mode.IsDir() && writable {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrIsDir}
+	}
+	if writable && flag&os.O_TRUNC != 0 && len(n.data) > 0 {
+		n.data = nil
+		n.modTime = m.Now()
+		n.chgTime = n.modTime
+	}
+
+	return &openFile{
+		m:        m,
+		n:        n,
+		name:     path.Base(clean(name)),
+		readable: flag&(os.O_WRONLY|os.O_RDWR) != os.O_WRONLY,
+		writable: writable,
+		append:   flag&os.O_APPEND != 0,
+	}, nil
+}
+
+// Rename implements the `Rename` function of the `types.DirOps` interface.
+func (m *MemDirOps) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.injected(OpRename, oldpath); err != nil {
+		return err
+	}
+
+	oldParent, n, err := m.lookupWithParent(oldpath)
+	if err != nil {
+		return &os.LinkError{Op: string(OpRename), Old: oldpath, New: newpath, Err: err}
+	}
+	if oldParent == nil {
+		return &os.LinkError{Op: string(OpRename), Old: oldpath, New: newpath, Err: fs.ErrPermission}
+	}
+
+	newParent, err := m.lookup(path.Dir(clean(newpath)))
+	if err != nil {
+		return &os.LinkError{Op: string(OpRename), Old: oldpath, New: newpath, Err: err}
+	}
+	if !newParent.mode.IsDir() {
+		return &os.LinkError{Op: string(OpRename), Old: oldpath, New: newpath, Err: ErrNotDir}
+	}
+	if n.mode.IsDir() && clean(newpath) != clean(oldpath) && strings.HasPrefix(clean(newpath)+"/", clean(oldpath)+"/") {
+		return &os.LinkError{Op: string(OpRename), Old: oldpath, New: newpath, Err: fs.ErrInvalid}
+	}
+
+	base := path.Base(clean(newpath))
+	if existing, ok := newParent.children[base]; ok && existing != n {
+		if existing.mode.IsDir() != n.mode.IsDir() {
+			if existing.mode.IsDir() {
+				return &os.LinkError{Op: string(OpRename), Old: oldpath, New: newpath, Err: ErrIsDir}
+			}
+			return &os.LinkError{Op: string(OpRename), Old: oldpath, New: newpath, Err: ErrNotDir}
+		}
+		if existing.mode.IsDir() && len(existing.children) > 0 {
+			return &os.LinkError{Op: string(OpRename), Old: oldpath, New: newpath, Err: ErrNotEmpty}
+		}
+	}
+
+	delete(oldParent.children, n.name)
+	n.name = base
+	newParent.children[base] = n
+
+	now := m.Now()
+	oldParent.modTime = now
+	newParent.modTime = now
+	return nil
+}
+
+// MkdirAll implements the `MkdirAll` function of the `types.DirOps` interface.
+func (m *MemDirOps) MkdirAll(name string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.injected(OpMkdirAll, name); err != nil {
+		return err
+	}
+
+	if _, err := m.mkdirAll(clean(name), perm); err != nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	return nil
+}
+
+// RemoveAll implements the `RemoveAll` function of the `types.DirOps` interface. Like `os.RemoveAll`
+// it returns nil when name does not exist.
+func (m *MemDirOps) RemoveAll(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.injected(OpRemoveAll, name); err != nil {
+		return err
+	}
+
+	parent, n, err := m.lookupWithParent(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return &fs.PathError{Op: string(OpRemoveAll), Path: name, Err: err}
+	}
+	if parent == nil {
+		n.children = map[string]*node{}
+		return nil
+	}
+
+	delete(parent.children, n.name)
+	parent.modTime = m.Now()
+	return nil
+}
+
+// Chtimes implements the `Chtimes` function of the `types.DirOps` interface.
+func (m *MemDirOps) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.injected(OpChtimes, name); err != nil {
+		return err
+	}
+
+	n, err := m.lookup(name)
+	if err != nil {
+		return &fs.PathError{Op: string(OpChtimes), Path: name, Err: err}
+	}
+	if !atime.IsZero() {
+		n.accTime = atime
+	}
+	if !mtime.IsZero() {
+		n.modTime = mtime
+	}
+	n.chgTime = m.Now()
+	return nil
+}
+
+// WalkDir implements the `WalkDir` function of the `types.DirOps` interface with the same semantics as
+// `filepath.WalkDir`, including `fs.SkipDir` and `fs.SkipAll`. Entries are read under the lock but fn
+// is called without it, so fn may modify the filesystem.
+func (m *MemDirOps) WalkDir(root string, fn fs.WalkDirFunc) error {
+	m.mu.RLock()
+	err := m.injected(OpWalkDir, root)
+	var info os.FileInfo
+	if err == nil {
+		info, err = m.stat(OpLstat, root)
+	}
+	m.mu.RUnlock()
+
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = m.walkDir(root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+// walkDir recursively descends name, calling fn the same way `filepath.WalkDir` does.
+func (m *MemDirOps) walkDir(name string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(name, d, nil); err != nil || !d.IsDir() {
+		if errors.Is(err, fs.SkipDir) && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	m.mu.RLock()
+	entries, err := m.readDir(name)
+	m.mu.RUnlock()
+	if err != nil {
+		err = fn(name, d, err)
+		if err != nil {
+			if errors.Is(err, fs.SkipDir) && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		if err := m.walkDir(filepath.Join(name, entry.Name()), entry, fn); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// readDir lists name sorted by filename, the caller must hold the lock.
+func (m *MemDirOps) readDir(name string) ([]os.DirEntry, error) {
+	if err := m.injected(OpReadDir, name); err != nil {
+		return nil, err
+	}
+
+	n, err := m.lookup(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if !n.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdirent", Path: name, Err: ErrNotDir}
+	}
+
+	entries := make([]os.DirEntry, 0, len(n.children))
+	for _, child := range n.children {
+		entries = append(entries, fs.FileInfoToDirEntry(child.info()))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// stat returns the file information for name, the caller must hold the lock.
+func (m *MemDirOps) stat(op Op, name string) (os.FileInfo, error) {
+	if err := m.injected(op, name); err != nil {
+		return nil, err
+	}
+
+	_, n, err := m.resolve(name, op != OpLstat)
+	if err != nil {
+		return nil, &fs.PathError{Op: string(op), Path: name, Err: err}
+	}
+	return n.infoNamed(path.Base(clean(name))), nil
+}
+
+// mkdirAll creates the directory key and any missing parents, the caller must hold the lock.
+func (m *MemDirOps) mkdirAll(key string, perm os.FileMode) (*node, error) {
+	current, parts := m.root, split(key)
+	for i, part := range parts {
+		child, ok := current.children[part]
+		if !ok {
+			child = m.newNode(part, fs.ModeDir|perm.Perm())
+			current.children[part] = child
+			current.modTime = child.modTime
+		}
+		if child.mode&fs.ModeSymlink != 0 {
+			var err error
+			if child, err = m.lookup(strings.Join(parts[:i+1], "/")); err != nil {
+				return nil, err
+			}
+		}
+		if !child.mode.IsDir() {
+			return nil, ErrNotDir
+		}
+		current = child
+	}
+	return current, nil
+}
+
+// lookup finds the node for name following every symlink, the caller must hold the lock.
+func (m *MemDirOps) lookup(name string) (*node, error) {
+	_, n, err := m.resolve(name, true)
+	return n, err
+}
+
+// lookupWithParent finds the node for name and its parent without following a symlink in the last
+// component, the parent of the root is nil. The caller must hold the lock.
+func (m *MemDirOps) lookupWithParent(name string) (*node, *node, error) {
+	return m.resolve(name, false)
+}
+
+// resolve finds the node for name and its parent. Symlinks in every component but the last are always
+// followed, the last one only when follow is set. The caller must hold the lock.
+func (m *MemDirOps) resolve(name string, follow bool) (*node, *node, error) {
+	var parent *node
+	current, dir := m.root, "/"
+	parts, links := split(clean(name)), 0
+	for len(parts) > 0 {
+		part := parts[0]
+		parts = parts[1:]
+		if !current.mode.IsDir() {
+			return nil, nil, ErrNotDir
+		}
+		child, ok := current.children[part]
+		if !ok {
+			return nil, nil, fs.ErrNotExist
+		}
+
+		if child.mode&fs.ModeSymlink != 0 && (follow || len(parts) > 0) {
+			links++
+			if links > maxLinks {
+				return nil, nil, ErrLoop
+			}
+			target := child.target
+			if !path.IsAbs(target) {
+				target = path.Join(dir, target)
+			}
+			// Restart from the root with the target in place of the link.
+			parts = append(split(clean(target)), parts...)
+			parent, current, dir = nil, m.root, "/"
+			continue
+		}
+
+		parent, current, dir = current, child, path.Join(dir, part)
+	}
+	return parent, current, nil
+}
+
+// injected returns the error injected for op on name, the caller must hold the lock.
+func (m *MemDirOps) injected(op Op, name string) error {
+	return m.errors[op][clean(name)]
+}
+
+// newNode creates a node stamped with the current time.
+func (m *MemDirOps) newNode(name string, mode fs.FileMode) *node {
+	now := m.Now()
+	n := &node{name: name, mode: mode, modTime: now, accTime: now, chgTime: now}
+	if mode.IsDir() {
+		n.children = map[string]*node{}
+	}
+	return n
+}
+
+// info returns a snapshot of the node as `fs.FileInfo`.
+func (n *node) info() fs.FileInfo {
+	return n.infoNamed(n.name)
+}
+
+// infoNamed returns a snapshot of the node as `fs.FileInfo` reporting name, which differs from the
+// node name when the node was reached through a symlink. The size of a symlink is the length of its
+// target, like on disk.
+func (n *node) infoNamed(name string) fs.FileInfo {
+	size := int64(len(n.data))
+	if n.mode&fs.ModeSymlink != 0 {
+		size = int64(len(n.target))
+	}
+	return &fileInfo{
+		name:    name,
+		size:    size,
+		mode:    n.mode,
+		modTime: n.modTime,
+		accTime: n.accTime,
+		chgTime: n.chgTime,
+		node:    n,
+	}
+}
+
+// clean converts name to the slash separated absolute form used as a key.
+func clean(name string) string {
+	return path.Clean("/" + strings.ReplaceAll(name, `\`, "/"))
+}
+
+// split breaks a cleaned key into its path components.
+func split(key string) []string {
+	key = strings.Trim(key, "/")
+	if key == "" {
+		return nil
+	}
+	return strings.Split(key, "/")
+}
+
+// fileInfo implements `fs.FileInfo` for MemDirOps entries. Sys returns the underlying node so two
+// infos for the same entry can be compared. AccessTime and ChangeTime report the atime and ctime, which
+// `fs.FileInfo` has no methods for.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	accTime time.Time
+	chgTime time.Time
+	node    *node
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() interface{}   { return fi.node }
+
+func (fi *fileInfo) AccessTime() time.Time { return fi.accTime }
+func (fi *fileInfo) ChangeTime() time.Time { return fi.chgTime }
+
+// file implements `fs.File` for files opened from MemDirOps.
+type file struct {
+	info   fs.FileInfo
+	reader *bytes.Reader
+	closed bool
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if f.info.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: f.info.Name(), Err: ErrIsDir}
+	}
+	return f.reader.Read(p)
+}
+
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	return f.reader.ReadAt(p, off)
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	return f.reader.Seek(offset, whence)
+}
+
+func (f *file) Close() error {
+	if f.closed {
+		return fs.ErrClosed
+	}
+	f.closed = true
+	return nil
+}
+
+// openFile implements `types.File` for files opened with `OpenFile`. It reads and writes the node itself
+// under the lock of its MemDirOps rather than a copy.
+type openFile struct {
+	m        *MemDirOps
+	n        *node
+	name     string
+	offset   int64
+	readable bool
+	writable bool
+	append   bool
+	closed   bool
+}
+
+func (f *openFile) Stat() (fs.FileInfo, error) {
+	if f.closed {
+		return nil, fs.ErrClosed
+	}
+	f.m.mu.RLock()
+	defer f.m.mu.RUnlock()
+
+	return f.n.infoNamed(f.name), nil
+}
+
+func (f *openFile) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if !f.readable {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrPermission}
+	}
+	if f.n.mode.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: ErrIsDir}
+	}
+	f.m.mu.RLock()
+	defer f.m.mu.RUnlock()
+
+	if f.offset >= int64(len(f.n.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.n.data[f.offset:])
+	f.offset += int64(n)
+	return n, nil
+}
+
+func (f *openFile) Write(p []byte) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if !f.writable {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrPermission}
+	}
+	f.m.mu.Lock()
+	defer f.m.mu.Unlock()
+
+	if f.append {
+		f.offset = int64(len(f.n.data))
+	}
+	if end := f.offset + int64(len(p)); end > int64(len(f.n.data)) {
+		f.n.data = append(f.n.data, make([]byte, end-int64(len(f.n.data)))...)
+	}
+	copy(f.n.data[f.offset:], p)
+	f.offset += int64(len(p))
+	f.n.modTime = f.m.Now()
+	f.n.chgTime = f.n.modTime
+	return len(p), nil
+}
+
+func (f *openFile) Sync() error {
+	if f.closed {
+		return fs.ErrClosed
+	}
+	return nil
+}
+
+func (f *openFile) Close() error {
+	if f.closed {
+		return fs.ErrClosed
+	}
+	f.closed = true
+	return nil
+}
@@ -0,0 +1,538 @@
+package memfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ondrovic/common/types"
+)
+
+// fixedTime is the clock used by every test filesystem.
+var fixedTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// CreateTestFS creates an in-memory filesystem with a few files and directories for testing.
+//
+//	/
+//	├── docs/readme.md
+//	├── empty/
+//	└── media/video.mp4
+func CreateTestFS(t *testing.T) *MemDirOps {
+	t.Helper()
+	m := New()
+	m.Now = func() time.Time { return fixedTime }
+	if err := m.WriteFile("/docs/readme.md", []byte("hello"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := m.WriteFile("/media/video.mp4", []byte("0123456789"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := m.MkdirAll("/empty", 0o755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	return m
+}
+
+// TestStat tests the Stat func.
+func TestStat(t *testing.T) {
+	type ExpectedResults struct {
+		size  int64
+		isDir bool
+		perm  os.FileMode
+	}
+	tests := []*types.TestLayout[string, ExpectedResults]{
+		{Name: "File", Input: "/media/video.mp4", Expected: ExpectedResults{size: 10, perm: 0o600}},
+		{Name: "Relative path", Input: "docs/readme.md", Expected: ExpectedResults{size: 5, perm: 0o644}},
+		{Name: "Windows separators", Input: `\docs\readme.md`, Expected: ExpectedResults{size: 5, perm: 0o644}},
+		{Name: "Directory", Input: "/empty", Expected: ExpectedResults{isDir: true, perm: 0o755}},
+		{Name: "Root", Input: "/", Expected: ExpectedResults{isDir: true, perm: 0o755}},
+		{Name: "Missing", Input: "/missing", Err: fs.ErrNotExist},
+		{Name: "Through a file", Input: "/docs/readme.md/child", Err: ErrNotDir},
+	}
+
+	m := CreateTestFS(t)
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			info, err := m.Stat(test.Input)
+			if test.Err != nil {
+				if !errors.Is(err, test.Err) {
+					t.Errorf("Stat(%q) - %v error = %v; want %v", test.Input, test.Name, err, test.Err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Stat(%q) - %v error = %v; want nil", test.Input, test.Name, err)
+			}
+			got := ExpectedResults{size: info.Size(), isDir: info.IsDir(), perm: info.Mode().Perm()}
+			if got != test.Expected {
+				t.Errorf("Stat(%q) - %v = %+v; want %+v", test.Input, test.Name, got, test.Expected)
+			}
+			if !info.ModTime().Equal(fixedTime) {
+				t.Errorf("Stat(%q) - %v mtime = %v; want %v", test.Input, test.Name, info.ModTime(), fixedTime)
+			}
+		})
+	}
+}
+
+// TestReadDir tests the ReadDir func.
+func TestReadDir(t *testing.T) {
+	tests := []*types.TestLayout[string, []string]{
+		{Name: "Root is sorted", Input: "/", Expected: []string{"docs", "empty", "media"}},
+		{Name: "Empty directory", Input: "/empty", Expected: []string{}},
+		{Name: "File", Input: "/docs/readme.md", Err: ErrNotDir},
+		{Name: "Missing", Input: "/missing", Err: fs.ErrNotExist},
+	}
+
+	m := CreateTestFS(t)
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			entries, err := m.ReadDir(test.Input)
+			if test.Err != nil {
+				if !errors.Is(err, test.Err) {
+					t.Errorf("ReadDir(%q) - %v error = %v; want %v", test.Input, test.Name, err, test.Err)
+				}
+				return
+			}
+			names := []string{}
+			for _, entry := range entries {
+				names = append(names, entry.Name())
+			}
+			if !reflect.DeepEqual(names, test.Expected) {
+				t.Errorf("ReadDir(%q) - %v = %v; want %v", test.Input, test.Name, names, test.Expected)
+			}
+		})
+	}
+}
+
+// TestRemove tests the Remove func.
+func TestRemove(t *testing.T) {
+	tests := []*types.TestLayout[string, error]{
+		{Name: "Remove file", Input: "/docs/readme.md"},
+		{Name: "Remove empty directory", Input: "/empty"},
+		{Name: "Remove non-empty directory", Input: "/media", Expected: ErrNotEmpty},
+		{Name: "Remove missing", Input: "/missing", Expected: fs.ErrNotExist},
+		{Name: "Remove root", Input: "/", Expected: fs.ErrPermission},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			m := CreateTestFS(t)
+			err := m.Remove(test.Input)
+			if !errors.Is(err, test.Expected) {
+				t.Fatalf("Remove(%q) - %v error = %v; want %v", test.Input, test.Name, err, test.Expected)
+			}
+			if test.Expected == nil {
+				if _, err := m.Stat(test.Input); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("Remove(%q) - %v entry still exists", test.Input, test.Name)
+				}
+			}
+		})
+	}
+}
+
+// TestRename tests the Rename func.
+func TestRename(t *testing.T) {
+	type InputStruct struct {
+		oldpath string
+		newpath string
+	}
+	tests := []*types.TestLayout[InputStruct, error]{
+		{Name: "Rename file", Input: InputStruct{oldpath: "/docs/readme.md", newpath: "/docs/README.md"}},
+		{Name: "Move file into other directory", Input: InputStruct{oldpath: "/docs/readme.md", newpath: "/empty/readme.md"}},
+		{Name: "Move directory", Input: InputStruct{oldpath: "/media", newpath: "/empty/media"}},
+		{Name: "Replace file", Input: InputStruct{oldpath: "/docs/readme.md", newpath: "/media/video.mp4"}},
+		{Name: "Missing source", Input: InputStruct{oldpath: "/missing", newpath: "/other"}, Expected: fs.ErrNotExist},
+		{Name: "Missing target parent", Input: InputStruct{oldpath: "/docs", newpath: "/missing/docs"}, Expected: fs.ErrNotExist},
+		{Name: "Onto non-empty directory", Input: InputStruct{oldpath: "/empty", newpath: "/media"}, Expected: ErrNotEmpty},
+		{Name: "Into itself", Input: InputStruct{oldpath: "/media", newpath: "/media/sub"}, Expected: fs.ErrInvalid},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			m := CreateTestFS(t)
+			before, _ := m.Stat(test.Input.oldpath)
+
+			err := m.Rename(test.Input.oldpath, test.Input.newpath)
+			if !errors.Is(err, test.Expected) {
+				t.Fatalf("Rename(%q, %q) - %v error = %v; want %v", test.Input.oldpath, test.Input.newpath, test.Name, err, test.Expected)
+			}
+			if test.Expected != nil {
+				return
+			}
+
+			if _, err := m.Stat(test.Input.oldpath); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Rename() - %v old path still exists", test.Name)
+			}
+			after, err := m.Stat(test.Input.newpath)
+			if err != nil {
+				t.Fatalf("Rename() - %v new path error = %v", test.Name, err)
+			}
+			if after.Size() != before.Size() || after.IsDir() != before.IsDir() {
+				t.Errorf("Rename() - %v moved entry = %v; want %v", test.Name, after, before)
+			}
+		})
+	}
+}
+
+// TestOpen tests the Open func.
+func TestOpen(t *testing.T) {
+	m := CreateTestFS(t)
+
+	f, err := m.Open("/docs/readme.md")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil || string(data) != "hello" {
+		t.Errorf("Open() read = %q, %v; want %q", data, err, "hello")
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if _, err := f.Read(make([]byte, 1)); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("Read() after close error = %v; want %v", err, fs.ErrClosed)
+	}
+
+	if _, err := m.Open("/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open() missing error = %v; want %v", err, fs.ErrNotExist)
+	}
+}
+
+// TestOpenFile tests the OpenFile func with each flag.
+func TestOpenFile(t *testing.T) {
+	type InputStruct struct {
+		name  string
+		flag  int
+		write string
+	}
+	type ExpectedResults struct {
+		data string
+		err  error
+	}
+
+	tests := []*types.TestLayout[InputStruct, ExpectedResults]{
+		{Name: "Create", Input: InputStruct{"/docs/new.txt", os.O_WRONLY | os.O_CREATE, "new"}, Expected: ExpectedResults{data: "new"}},
+		{Name: "Overwrite in place", Input: InputStruct{"/docs/readme.md", os.O_WRONLY, "J"}, Expected: ExpectedResults{data: "Jello"}},
+		{Name: "Truncate", Input: InputStruct{"/docs/readme.md", os.O_WRONLY | os.O_TRUNC, "bye"}, Expected: ExpectedResults{data: "bye"}},
+		{Name: "Append", Input: InputStruct{"/docs/readme.md", os.O_WRONLY | os.O_APPEND, " world"}, Expected: ExpectedResults{data: "hello world"}},
+		{Name: "Exclusive create", Input: InputStruct{"/docs/new.txt", os.O_WRONLY | os.O_CREATE | os.O_EXCL, "new"}, Expected: ExpectedResults{data: "new"}},
+		{Name: "Exclusive create of existing file", Input: InputStruct{"/docs/readme.md", os.O_WRONLY | os.O_CREATE | os.O_EXCL, ""}, Expected: ExpectedResults{err: fs.ErrExist}},
+		{Name: "Missing without create", Input: InputStruct{"/docs/new.txt", os.O_WRONLY, ""}, Expected: ExpectedResults{err: fs.ErrNotExist}},
+		{Name: "Missing parent", Input: InputStruct{"/missing/new.txt", os.O_WRONLY | os.O_CREATE, ""}, Expected: ExpectedResults{err: fs.ErrNotExist}},
+		{Name: "Directory", Input: InputStruct{"/empty", os.O_WRONLY, ""}, Expected: ExpectedResults{err: ErrIsDir}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			m := CreateTestFS(t)
+
+			f, err := m.OpenFile(test.Input.name, test.Input.flag, 0o600)
+			if !errors.Is(err, test.Expected.err) {
+				t.Fatalf("OpenFile() - %v error = %v; want %v", test.Name, err, test.Expected.err)
+			}
+			if err != nil {
+				return
+			}
+			if _, err := io.WriteString(f, test.Input.write); err != nil {
+				t.Fatalf("Write() - %v error = %v", test.Name, err)
+			}
+			if err := f.Close(); err != nil {
+				t.Errorf("Close() - %v error = %v", test.Name, err)
+			}
+
+			if data, err := m.ReadFile(test.Input.name); err != nil || string(data) != test.Expected.data {
+				t.Errorf("OpenFile() - %v wrote %q, %v; want %q", test.Name, data, err, test.Expected.data)
+			}
+		})
+	}
+
+	t.Run("Read only", func(t *testing.T) {
+		m := CreateTestFS(t)
+		f, err := m.OpenFile("/docs/readme.md", os.O_RDONLY, 0)
+		if err != nil {
+			t.Fatalf("OpenFile() error = %v", err)
+		}
+		defer f.Close()
+
+		if _, err := f.Write([]byte("x")); !errors.Is(err, fs.ErrPermission) {
+			t.Errorf("Write() on read only file error = %v; want %v", err, fs.ErrPermission)
+		}
+		if data, err := io.ReadAll(f); err != nil || string(data) != "hello" {
+			t.Errorf("Read() = %q, %v; want %q", data, err, "hello")
+		}
+	})
+}
+
+// TestMkdirAllAndRemoveAll tests the MkdirAll and RemoveAll funcs.
+func TestMkdirAllAndRemoveAll(t *testing.T) {
+	m := CreateTestFS(t)
+
+	if err := m.MkdirAll("/a/b/c", 0o700); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	info, err := m.Stat("/a/b")
+	if err != nil || !info.IsDir() || info.Mode().Perm() != 0o700 {
+		t.Errorf("MkdirAll() created %v, %v; want directory with 0700", info, err)
+	}
+	if err := m.MkdirAll("/docs/readme.md/x", 0o755); !errors.Is(err, ErrNotDir) {
+		t.Errorf("MkdirAll() through file error = %v; want %v", err, ErrNotDir)
+	}
+
+	if err := m.RemoveAll("/a"); err != nil {
+		t.Fatalf("RemoveAll() error = %v", err)
+	}
+	if _, err := m.Stat("/a/b/c"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("RemoveAll() left /a/b/c behind")
+	}
+	if err := m.RemoveAll("/missing"); err != nil {
+		t.Errorf("RemoveAll() missing error = %v; want nil", err)
+	}
+}
+
+// TestChtimesAndChmod tests the Chtimes and Chmod funcs.
+func TestChtimesAndChmod(t *testing.T) {
+	m := CreateTestFS(t)
+	mtime := fixedTime.Add(-48 * time.Hour)
+
+	if err := m.Chtimes("/docs/readme.md", time.Time{}, mtime); err != nil {
+		t.Fatalf("Chtimes() error = %v", err)
+	}
+	if err := m.Chmod("/docs/readme.md", 0o400); err != nil {
+		t.Fatalf("Chmod() error = %v", err)
+	}
+
+	info, _ := m.Stat("/docs/readme.md")
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("Chtimes() mtime = %v; want %v", info.ModTime(), mtime)
+	}
+	if info.Mode().Perm() != 0o400 {
+		t.Errorf("Chmod() perm = %v; want %v", info.Mode().Perm(), os.FileMode(0o400))
+	}
+}
+
+// TestWriteFile_Permissions tests that WriteFile only applies perm when it creates a file, like
+// `os.WriteFile`.
+func TestWriteFile_Permissions(t *testing.T) {
+	m := CreateTestFS(t)
+
+	if err := m.WriteFile("/media/video.mp4", []byte("new"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if info, _ := m.Stat("/media/video.mp4"); info.Mode().Perm() != 0o600 || info.Size() != 3 {
+		t.Errorf("WriteFile() existing file = %v, %d bytes; want %v, 3 bytes", info.Mode().Perm(), info.Size(), os.FileMode(0o600))
+	}
+
+	if err := m.WriteFile("/media/new.mp4", nil, 0o640); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if info, _ := m.Stat("/media/new.mp4"); info.Mode().Perm() != 0o640 {
+		t.Errorf("WriteFile() new file perm = %v; want %v", info.Mode().Perm(), os.FileMode(0o640))
+	}
+}
+
+// TestWalkDir tests the WalkDir func.
+func TestWalkDir(t *testing.T) {
+	tests := []*types.TestLayout[string, []string]{
+		{Name: "Walk everything", Input: "", Expected: []string{"/", "/docs", "/docs/readme.md", "/empty", "/media", "/media/video.mp4"}},
+		{Name: "Skip directory", Input: "/docs", Expected: []string{"/", "/docs", "/empty", "/media", "/media/video.mp4"}},
+		{Name: "Skip all", Input: "/empty", Expected: []string{"/", "/docs", "/docs/readme.md", "/empty"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			m := CreateTestFS(t)
+			visited := []string{}
+			err := m.WalkDir("/", func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				visited = append(visited, filepath.ToSlash(path))
+				switch {
+				case test.Name == "Skip directory" && path == filepath.FromSlash(test.Input):
+					return fs.SkipDir
+				case test.Name == "Skip all" && path == filepath.FromSlash(test.Input):
+					return fs.SkipAll
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("WalkDir() - %v error = %v", test.Name, err)
+			}
+			if !reflect.DeepEqual(visited, test.Expected) {
+				t.Errorf("WalkDir() - %v visited = %v; want %v", test.Name, visited, test.Expected)
+			}
+		})
+	}
+}
+
+// TestSymlink tests that symlinks are followed by Stat and left alone by Lstat and Remove.
+func TestSymlink(t *testing.T) {
+	type InputStruct struct {
+		lstat bool
+		path  string
+	}
+	type ExpectedResults struct {
+		size    int64
+		isDir   bool
+		symlink bool
+	}
+
+	tests := []*types.TestLayout[InputStruct, ExpectedResults]{
+		{Name: "Stat follows absolute link", Input: InputStruct{path: "/links/docs"}, Expected: ExpectedResults{isDir: true}},
+		{Name: "Stat follows link in a parent", Input: InputStruct{path: "/links/docs/readme.md"}, Expected: ExpectedResults{size: 5}},
+		{Name: "Stat follows relative link", Input: InputStruct{path: "/links/video"}, Expected: ExpectedResults{size: 10}},
+		{Name: "Stat follows chained links", Input: InputStruct{path: "/links/chain"}, Expected: ExpectedResults{size: 10}},
+		{Name: "Lstat describes the link", Input: InputStruct{lstat: true, path: "/links/video"}, Expected: ExpectedResults{size: int64(len("../media/video.mp4")), symlink: true}},
+		{Name: "Lstat of broken link", Input: InputStruct{lstat: true, path: "/links/broken"}, Expected: ExpectedResults{size: int64(len("/missing")), symlink: true}},
+		{Name: "Stat of broken link", Input: InputStruct{path: "/links/broken"}, Err: fs.ErrNotExist},
+		{Name: "Stat of self link", Input: InputStruct{path: "/links/self"}, Err: ErrLoop},
+	}
+
+	m := CreateTestFS(t)
+	links := map[string]string{
+		"/links/docs":   "/docs",
+		"/links/video":  "../media/video.mp4",
+		"/links/chain":  "video",
+		"/links/broken": "/missing",
+		"/links/self":   "/links/self",
+	}
+	for name, target := range links {
+		if err := m.Symlink(target, name); err != nil {
+			t.Fatalf("failed to create symlink: %v", err)
+		}
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			stat := m.Stat
+			if test.Input.lstat {
+				stat = m.Lstat
+			}
+			info, err := stat(test.Input.path)
+			if test.Err != nil {
+				if !errors.Is(err, test.Err) {
+					t.Errorf("Stat(%q) - %v error = %v; want %v", test.Input.path, test.Name, err, test.Err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Stat(%q) - %v error = %v; want nil", test.Input.path, test.Name, err)
+			}
+			got := ExpectedResults{size: info.Size(), isDir: info.IsDir(), symlink: info.Mode()&fs.ModeSymlink != 0}
+			if got != test.Expected {
+				t.Errorf("Stat(%q) - %v = %+v; want %+v", test.Input.path, test.Name, got, test.Expected)
+			}
+			if info.Name() != filepath.Base(test.Input.path) {
+				t.Errorf("Stat(%q) - %v name = %q; want %q", test.Input.path, test.Name, info.Name(), filepath.Base(test.Input.path))
+			}
+		})
+	}
+
+	if err := m.Symlink("/docs", "/links/docs"); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Symlink() onto existing entry error = %v; want %v", err, fs.ErrExist)
+	}
+
+	target, _ := m.Stat("/docs")
+	linked, _ := m.Stat("/links/docs")
+	if target.Sys() != linked.Sys() {
+		t.Errorf("Stat() through link Sys = %v; want %v", linked.Sys(), target.Sys())
+	}
+
+	if err := m.Remove("/links/docs"); err != nil {
+		t.Fatalf("Remove() link error = %v", err)
+	}
+	if _, err := m.Stat("/docs/readme.md"); err != nil {
+		t.Errorf("Remove() of link removed the target: %v", err)
+	}
+}
+
+// TestInjectError tests the InjectError and ClearErrors funcs.
+func TestInjectError(t *testing.T) {
+	simulated := errors.New("simulated error")
+	tests := []*types.TestLayout[Op, error]{
+		{Name: "ReadDir", Input: OpReadDir, Expected: simulated},
+		{Name: "Remove", Input: OpRemove, Expected: simulated},
+		{Name: "Stat", Input: OpStat, Expected: simulated},
+		{Name: "Lstat", Input: OpLstat, Expected: simulated},
+		{Name: "Open", Input: OpOpen, Expected: simulated},
+		{Name: "Rename", Input: OpRename, Expected: simulated},
+		{Name: "MkdirAll", Input: OpMkdirAll, Expected: simulated},
+		{Name: "RemoveAll", Input: OpRemoveAll, Expected: simulated},
+		{Name: "Chtimes", Input: OpChtimes, Expected: simulated},
+		{Name: "WalkDir", Input: OpWalkDir, Expected: simulated},
+	}
+
+	call := func(m *MemDirOps, op Op, name string) error {
+		var err error
+		switch op {
+		case OpReadDir:
+			_, err = m.ReadDir(name)
+		case OpRemove:
+			err = m.Remove(name)
+		case OpStat:
+			_, err = m.Stat(name)
+		case OpLstat:
+			_, err = m.Lstat(name)
+		case OpOpen:
+			_, err = m.Open(name)
+		case OpRename:
+			err = m.Rename(name, name+"-renamed")
+		case OpMkdirAll:
+			err = m.MkdirAll(name, 0o755)
+		case OpRemoveAll:
+			err = m.RemoveAll(name)
+		case OpChtimes:
+			err = m.Chtimes(name, fixedTime, fixedTime)
+		case OpWalkDir:
+			err = m.WalkDir(name, func(_ string, _ fs.DirEntry, err error) error { return err })
+		}
+		return err
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			m := CreateTestFS(t)
+			m.InjectError(test.Input, "empty", test.Expected)
+
+			if err := call(m, test.Input, "/empty"); !errors.Is(err, test.Expected) {
+				t.Errorf("%v with injected error = %v; want %v", test.Name, err, test.Expected)
+			}
+
+			m.ClearErrors()
+			if err := call(m, test.Input, "/empty"); err != nil {
+				t.Errorf("%v after ClearErrors error = %v; want nil", test.Name, err)
+			}
+		})
+	}
+}
+
+// TestConcurrentAccess tests that MemDirOps can be used from several goroutines.
+func TestConcurrentAccess(t *testing.T) {
+	m := New()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := filepath.Join("/dir", string(rune('a'+i)), "file.txt")
+			if err := m.WriteFile(name, []byte("data"), 0o644); err != nil {
+				t.Errorf("WriteFile(%q) error = %v", name, err)
+			}
+			if _, err := m.ReadDir("/dir"); err != nil {
+				t.Errorf("ReadDir() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	entries, _ := m.ReadDir("/dir")
+	if len(entries) != 20 {
+		t.Errorf("concurrent WriteFile created %d directories; want 20", len(entries))
+	}
+}
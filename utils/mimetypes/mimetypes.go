@@ -0,0 +1,176 @@
+package mimetypes
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils/filetypes"
+)
+
+// DefaultType is the MIME type of files whose extension has no known MIME type, as used for an HTTP
+// Content-Type.
+const DefaultType = "application/octet-stream"
+
+var (
+	// ErrInvalidPattern is returned for a MIME type or pattern that is not of the form `type/subtype`.
+	ErrInvalidPattern = errors.New("invalid mime type")
+	// ErrNoFileType is returned when no file type has an extension matching a MIME pattern.
+	ErrNoFileType = errors.New("no file type matches mime type")
+	// ErrAmbiguous is returned when a MIME pattern matches the extensions of several file types.
+	ErrAmbiguous = errors.New("mime type matches several file types")
+)
+
+// TypeByExtension returns the MIME type of ext, such as `.mp4` or `.tar.gz`, looked up in
+// `types.MIMETypes` and then in the standard library's `mime` table. The extension is matched without
+// regard to case and the leading dot is optional. Numbered volumes such as `.7z.001` resolve through
+// their `#` pattern. It returns an empty string when the MIME type is unknown.
+func TypeByExtension(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext == "" {
+		return ""
+	}
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+
+	if mimeType, ok := types.MIMETypes[ext]; ok {
+		return mimeType
+	}
+	for pattern, mimeType := range types.MIMETypes {
+		if strings.Contains(pattern, filetypes.Digits) && filetypes.MatchPattern(pattern, ext) {
+			return mimeType
+		}
+	}
+
+	mimeType, _, err := mime.ParseMediaType(mime.TypeByExtension(ext))
+	if err != nil {
+		return ""
+	}
+	return mimeType
+}
+
+// TypeByPath returns the MIME type of path from its longest registered extension, so `site.tar.gz` is
+// `application/gzip`. It returns `DefaultType` when the MIME type is unknown, which makes it suitable
+// as an HTTP Content-Type.
+func TypeByPath(path string) string {
+	ext, _ := filetypes.Extension(path)
+	if ext == "" {
+		ext = filepath.Ext(path)
+	}
+
+	if mimeType := TypeByExtension(ext); mimeType != "" {
+		return mimeType
+	}
+	return DefaultType
+}
+
+// ExtensionsByType returns the sorted extensions of the registered file types whose MIME type matches
+// pattern, which may be a MIME type such as `image/png` or a wildcard such as `video/*`. Volume
+// patterns such as `.7z.#` are left out.
+func ExtensionsByType(pattern string) ([]string, error) {
+	pattern, err := Normalize(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	extensions := []string{}
+	for _, def := range filetypes.List() {
+		for _, ext := range def.Extensions {
+			if ext == filetypes.Wildcard || strings.Contains(ext, filetypes.Digits) || slices.Contains(extensions, ext) {
+				continue
+			}
+			if Match(pattern, TypeByExtension(ext)) {
+				extensions = append(extensions, ext)
+			}
+		}
+	}
+	sort.Strings(extensions)
+	return extensions, nil
+}
+
+// Match reports whether mimeType matches pattern. The pattern may be a MIME type, `type/*` or `*/*`.
+// Case and parameters such as `; charset=utf-8` are ignored on both sides.
+func Match(pattern, mimeType string) bool {
+	pattern, err := Normalize(pattern)
+	if err != nil {
+		return false
+	}
+	mimeType, err = Normalize(mimeType)
+	if err != nil {
+		return false
+	}
+
+	if pattern == "*/*" || pattern == mimeType {
+		return true
+	}
+	major, minor, _ := strings.Cut(pattern, "/")
+	return minor == "*" && strings.HasPrefix(mimeType, major+"/")
+}
+
+// FileTypes returns, in registration order, every file type with an extension whose MIME type matches
+// pattern. File types matching every file are left out.
+func FileTypes(pattern string) ([]types.FileType, error) {
+	pattern, err := Normalize(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	fileTypes := []types.FileType{}
+	for _, def := range filetypes.List() {
+		if slices.Contains(def.Extensions, filetypes.Wildcard) {
+			continue
+		}
+		for _, ext := range def.Extensions {
+			if Match(pattern, TypeByExtension(ext)) {
+				fileTypes = append(fileTypes, def.Name)
+				break
+			}
+		}
+	}
+	return fileTypes, nil
+}
+
+// FileType resolves pattern, such as `video/*` or `application/pdf`, to the single file type whose
+// extensions it matches. `*/*` resolves to `types.FileTypes.Any`. It returns an error wrapping
+// `ErrNoFileType` or `ErrAmbiguous` when pattern does not identify exactly one file type.
+func FileType(pattern string) (types.FileType, error) {
+	normalized, err := Normalize(pattern)
+	if err != nil {
+		return "", err
+	}
+	if normalized == "*/*" {
+		return types.FileTypes.Any, nil
+	}
+
+	fileTypes, err := FileTypes(normalized)
+	if err != nil {
+		return "", err
+	}
+	switch len(fileTypes) {
+	case 0:
+		return "", fmt.Errorf("%w: %s", ErrNoFileType, pattern)
+	case 1:
+		return fileTypes[0], nil
+	default:
+		return "", fmt.Errorf("%w: %s matches %v", ErrAmbiguous, pattern, fileTypes)
+	}
+}
+
+// Normalize lower cases mimeType, drops its parameters and checks it is of the form `type/subtype`,
+// where the subtype, or both parts, may be `*`.
+func Normalize(mimeType string) (string, error) {
+	mediaType, _, _ := strings.Cut(mimeType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	major, minor, ok := strings.Cut(mediaType, "/")
+	if !ok || major == "" || minor == "" || strings.Contains(minor, "/") || (major == "*" && minor != "*") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidPattern, mimeType)
+	}
+	return mediaType, nil
+}
@@ -0,0 +1,167 @@
+package mimetypes
+
+import (
+	"errors"
+	"mime"
+	"reflect"
+	"testing"
+
+	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils/filetypes"
+)
+
+// TestMIMETypes tests that every built-in extension has a MIME type.
+func TestMIMETypes(t *testing.T) {
+	for fileType, extensions := range types.FileExtensions {
+		for ext := range extensions {
+			if ext == filetypes.Wildcard {
+				continue
+			}
+			if _, ok := types.MIMETypes[ext]; !ok {
+				t.Errorf("types.MIMETypes is missing %s extension %q", fileType, ext)
+			}
+		}
+	}
+}
+
+// TestTypeByExtension tests the TypeByExtension func.
+func TestTypeByExtension(t *testing.T) {
+	tests := []*types.TestLayout[string, string]{
+		{Name: "Video", Input: ".mp4", Expected: "video/mp4"},
+		{Name: "Upper case", Input: ".MKV", Expected: "video/x-matroska"},
+		{Name: "Without dot", Input: "pdf", Expected: "application/pdf"},
+		{Name: "Compound extension", Input: ".tar.gz", Expected: "application/gzip"},
+		{Name: "Numbered volume", Input: ".part02.rar", Expected: "application/vnd.rar"},
+		{Name: "Numbered volume without pattern prefix", Input: ".7z.001", Expected: "application/x-7z-compressed"},
+		{Name: "Standard library fallback", Input: ".json", Expected: "application/json"},
+		{Name: "Unknown", Input: ".nope", Expected: ""},
+		{Name: "Empty", Input: "", Expected: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if result := TypeByExtension(test.Input); result != test.Expected {
+				t.Errorf("TypeByExtension(%q) - %v = %q; want %q", test.Input, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestTypeByPath tests the TypeByPath func.
+func TestTypeByPath(t *testing.T) {
+	tests := []*types.TestLayout[string, string]{
+		{Name: "Video", Input: "/media/movie.MOV", Expected: "video/quicktime"},
+		{Name: "Compound extension", Input: "/backups/site.tar.xz", Expected: "application/x-xz"},
+		{Name: "Multi-part archive", Input: "backup.zip.003", Expected: "application/zip"},
+		{Name: "Unregistered extension", Input: "data.json", Expected: "application/json"},
+		{Name: "Unknown extension", Input: "program.nope", Expected: DefaultType},
+		{Name: "No extension", Input: "README", Expected: DefaultType},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if result := TypeByPath(test.Input); result != test.Expected {
+				t.Errorf("TypeByPath(%q) - %v = %q; want %q", test.Input, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestExtensionsByType tests the ExtensionsByType func.
+func TestExtensionsByType(t *testing.T) {
+	tests := []*types.TestLayout[string, []string]{
+		{Name: "MIME type", Input: "image/jpeg", Expected: []string{".jpeg", ".jpg"}},
+		{Name: "MIME type with parameters", Input: "Text/Plain; charset=utf-8", Expected: []string{".txt"}},
+		{Name: "Compound extensions", Input: "application/gzip", Expected: []string{".gz", ".tar.gz", ".tgz"}},
+		{Name: "Wildcard", Input: "video/*", Expected: []string{".avi", ".flv", ".m4v", ".mkv", ".mov", ".mp4", ".mpeg", ".mpg", ".ts", ".webm", ".wmv"}},
+		{Name: "Audio", Input: "audio/mpeg", Expected: []string{".mp3"}},
+		{Name: "Unknown", Input: "chemical/*", Expected: []string{}},
+		{Name: "Invalid", Input: "video", Expected: nil, Err: ErrInvalidPattern},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := ExtensionsByType(test.Input)
+			if !errors.Is(err, test.Err) {
+				t.Fatalf("ExtensionsByType(%q) - %v error = %v; want %v", test.Input, test.Name, err, test.Err)
+			}
+			if !reflect.DeepEqual(result, test.Expected) {
+				t.Errorf("ExtensionsByType(%q) - %v = %v; want %v", test.Input, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestMatch tests the Match func.
+func TestMatch(t *testing.T) {
+	type InputStruct struct {
+		pattern  string
+		mimeType string
+	}
+
+	tests := []*types.TestLayout[InputStruct, bool]{
+		{Name: "Exact", Input: InputStruct{pattern: "video/mp4", mimeType: "video/mp4"}, Expected: true},
+		{Name: "Case and parameters", Input: InputStruct{pattern: "TEXT/plain", mimeType: "text/plain; charset=utf-8"}, Expected: true},
+		{Name: "Subtype wildcard", Input: InputStruct{pattern: "video/*", mimeType: "video/x-matroska"}, Expected: true},
+		{Name: "Any", Input: InputStruct{pattern: "*/*", mimeType: "application/pdf"}, Expected: true},
+		{Name: "Other type", Input: InputStruct{pattern: "video/*", mimeType: "image/png"}, Expected: false},
+		{Name: "Prefix is not a type", Input: InputStruct{pattern: "video/*", mimeType: "videos/mp4"}, Expected: false},
+		{Name: "Other subtype", Input: InputStruct{pattern: "video/mp4", mimeType: "video/webm"}, Expected: false},
+		{Name: "Invalid pattern", Input: InputStruct{pattern: "*/mp4", mimeType: "video/mp4"}, Expected: false},
+		{Name: "Empty MIME type", Input: InputStruct{pattern: "*/*", mimeType: ""}, Expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if result := Match(test.Input.pattern, test.Input.mimeType); result != test.Expected {
+				t.Errorf("Match(%q, %q) - %v = %v; want %v", test.Input.pattern, test.Input.mimeType, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestFileType tests the FileType and FileTypes funcs.
+func TestFileType(t *testing.T) {
+	type ExpectedResults struct {
+		fileType  types.FileType
+		fileTypes []types.FileType
+	}
+
+	tests := []*types.TestLayout[string, ExpectedResults]{
+		{Name: "Wildcard", Input: "video/*", Expected: ExpectedResults{fileType: types.FileTypes.Video, fileTypes: []types.FileType{types.FileTypes.Video}}},
+		{Name: "MIME type", Input: "image/svg+xml", Expected: ExpectedResults{fileType: types.FileTypes.Image, fileTypes: []types.FileType{types.FileTypes.Image}}},
+		{Name: "Audio", Input: "audio/*", Expected: ExpectedResults{fileType: types.FileTypes.Audio, fileTypes: []types.FileType{types.FileTypes.Audio}}},
+		{Name: "Fonts", Input: "font/*", Expected: ExpectedResults{fileType: types.FileTypes.Fonts, fileTypes: []types.FileType{types.FileTypes.Fonts}}},
+		{Name: "Subtitles", Input: "text/vtt", Expected: ExpectedResults{fileType: types.FileTypes.Subtitles, fileTypes: []types.FileType{types.FileTypes.Subtitles}}},
+		{Name: "Registered file type", Input: "model/*", Expected: ExpectedResults{fileType: "Models", fileTypes: []types.FileType{"Models"}}},
+		{Name: "Any", Input: "*/*", Expected: ExpectedResults{fileType: types.FileTypes.Any, fileTypes: []types.FileType{
+			types.FileTypes.Video, types.FileTypes.Image, types.FileTypes.Archive, types.FileTypes.Documents, types.FileTypes.Audio, types.FileTypes.Code,
+			types.FileTypes.Fonts, types.FileTypes.Ebooks, types.FileTypes.Executables, types.FileTypes.DiskImages, types.FileTypes.Subtitles, "Models",
+		}}},
+		{Name: "Ambiguous", Input: "text/*", Expected: ExpectedResults{fileTypes: []types.FileType{types.FileTypes.Documents, types.FileTypes.Code, types.FileTypes.Subtitles}}, Err: ErrAmbiguous},
+		{Name: "Unknown", Input: "chemical/x-pdb", Expected: ExpectedResults{fileTypes: []types.FileType{}}, Err: ErrNoFileType},
+		{Name: "Invalid", Input: "video/mp4/x", Expected: ExpectedResults{}, Err: ErrInvalidPattern},
+	}
+
+	if err := mime.AddExtensionType(".glb", "model/gltf-binary"); err != nil {
+		t.Fatalf("mime.AddExtensionType() error = %v", err)
+	}
+	if err := filetypes.Register(types.FileTypeDefinition{Name: "Models", Extensions: []string{".glb"}}); err != nil {
+		t.Fatalf("filetypes.Register() error = %v", err)
+	}
+	defer filetypes.Unregister("Models")
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fileType, err := FileType(test.Input)
+			if !errors.Is(err, test.Err) || fileType != test.Expected.fileType {
+				t.Errorf("FileType(%q) - %v = %q, %v; want %q, %v", test.Input, test.Name, fileType, err, test.Expected.fileType, test.Err)
+			}
+
+			fileTypes, _ := FileTypes(test.Input)
+			if !reflect.DeepEqual(fileTypes, test.Expected.fileTypes) {
+				t.Errorf("FileTypes(%q) - %v = %v; want %v", test.Input, test.Name, fileTypes, test.Expected.fileTypes)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package operators
+
+import (
+	"cmp"
+	"fmt"
+
+	"github.com/ondrovic/common/types"
+)
+
+// Number is the set of types `MatchWithTolerance` can widen by a tolerance, which includes
+// `time.Duration` and other types defined on a number.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// Tolerance widens the wanted value of an operator, Lower below it and Upper above it. For the range
+// operators Lower widens the lower bound and Upper the upper bound.
+//
+// @property Lower: How far below the wanted value still matches.
+// @property Upper: How far above the wanted value, or the upper bound of a range, still matches.
+type Tolerance[T Number] struct {
+	Lower T
+	Upper T
+}
+
+// Match reports whether value passes operator against wanted, such as `value < wanted` for
+// `types.OperatorTypes.LessThan`. It works with any ordered type, ints, floats, strings or
+// `time.Duration`. The range operators take their upper bound in upper and need exactly one, other
+// operators ignore it. Unknown operators behave like `types.OperatorTypes.EqualTo`, operator names and
+// aliases such as `gte` or `between` are read by `utils.ToOperatorType`.
+//
+// Example usage:
+//
+//	// Videos longer than 90 minutes.
+//	matched, err := operators.Match(types.OperatorTypes.GreaterThan, 90*time.Minute, duration)
+//	// Images between 1024 and 4096 pixels wide.
+//	matched, err := operators.Match(types.OperatorTypes.Between, 1024, width, 4096)
+func Match[T cmp.Ordered](operator types.OperatorType, wanted, value T, upper ...T) (bool, error) {
+	return MatchFunc(operator, cmp.Compare[T], wanted, value, upper...)
+}
+
+// MatchFunc is like `Match` for types that are ordered by compare rather than by `<`, which returns a
+// negative number when a is before b, zero when they are equal and a positive number otherwise.
+//
+// Example usage:
+//
+//	// Photos taken before 2020.
+//	matched, err := operators.MatchFunc(types.OperatorTypes.LessThan, time.Time.Compare, year2020, takenAt)
+func MatchFunc[T any](operator types.OperatorType, compare func(a, b T) int, wanted, value T, upper ...T) (bool, error) {
+	high, err := UpperBound(operator, compare, wanted, upper)
+	if err != nil {
+		return false, err
+	}
+
+	wantedCmp := compare(value, wanted)
+	return Evaluate(operator, wantedCmp, wantedCmp, compare(value, high)), nil
+}
+
+// MatchWithTolerance is like `Match` but widens wanted by tolerance for `types.OperatorTypes.EqualTo`,
+// `types.OperatorTypes.NotEqualTo` and the range operators, as `utils.GetOperatorSizeMatches` does for
+// sizes. The other operators compare against wanted itself. Bounds that would overflow T are left
+// open rather than wrapping around.
+//
+// Example usage:
+//
+//	// 24 frames per second, give or take 0.5.
+//	matched, err := operators.MatchWithTolerance(types.OperatorTypes.EqualTo, 24.0, fps, operators.Tolerance[float64]{Lower: 0.5, Upper: 0.5})
+func MatchWithTolerance[T Number](operator types.OperatorType, wanted, value T, tolerance Tolerance[T], upper ...T) (bool, error) {
+	if tolerance.Lower < 0 || tolerance.Upper < 0 {
+		return false, fmt.Errorf("tolerance cannot be negative")
+	}
+	high, err := UpperBound(operator, cmp.Compare[T], wanted, upper)
+	if err != nil {
+		return false, err
+	}
+
+	// An open bound is one value cannot reach, so it compares as if value were inside it.
+	lowerCmp, upperCmp := 1, -1
+	if low := wanted - tolerance.Lower; low <= wanted {
+		lowerCmp = cmp.Compare(value, low)
+	}
+	if top := high + tolerance.Upper; top >= high {
+		upperCmp = cmp.Compare(value, top)
+	}
+	return Evaluate(operator, cmp.Compare(value, wanted), lowerCmp, upperCmp), nil
+}
+
+// UpperBound returns the upper bound of operator, the single value of upper for the range operators
+// and wanted for the others. It returns an error when a range operator does not get exactly one upper
+// bound or when that bound is below wanted.
+func UpperBound[T any](operator types.OperatorType, compare func(a, b T) int, wanted T, upper []T) (T, error) {
+	if !IsRange(operator) {
+		return wanted, nil
+	}
+	if len(upper) != 1 {
+		return wanted, fmt.Errorf("%s needs exactly one upper bound, got %d", operator, len(upper))
+	}
+	if compare(upper[0], wanted) < 0 {
+		return wanted, fmt.Errorf("lower bound %v is greater than upper bound %v", wanted, upper[0])
+	}
+	return upper[0], nil
+}
+
+// Evaluate applies operator given how a value compares with the wanted value, wantedCmp, and with the
+// lower and upper bounds of the values that are equal to it or, for the range operators, within the
+// range, lowerCmp and upperCmp. Each is negative, zero or positive as `cmp.Compare` returns. Without a
+// tolerance the bounds of the non range operators are the wanted value itself.
+func Evaluate(operator types.OperatorType, wantedCmp, lowerCmp, upperCmp int) bool {
+	switch operator {
+	case types.OperatorTypes.NotEqualTo, types.OperatorTypes.NotBetween:
+		return lowerCmp < 0 || upperCmp > 0
+	case types.OperatorTypes.LessThan:
+		return wantedCmp < 0
+	case types.OperatorTypes.LessThanEqualTo:
+		return wantedCmp <= 0
+	case types.OperatorTypes.GreaterThan:
+		return wantedCmp > 0
+	case types.OperatorTypes.GreaterThanEqualTo:
+		return wantedCmp >= 0
+	case types.OperatorTypes.BetweenExclusive:
+		return lowerCmp > 0 && upperCmp < 0
+	case types.OperatorTypes.NotBetweenExclusive:
+		return lowerCmp <= 0 || upperCmp >= 0
+	default:
+		return lowerCmp >= 0 && upperCmp <= 0
+	}
+}
+
+// IsRange reports whether operator compares against a range, from a lower to an upper bound.
+func IsRange(operator types.OperatorType) bool {
+	switch operator {
+	case types.OperatorTypes.Between, types.OperatorTypes.BetweenExclusive, types.OperatorTypes.NotBetween, types.OperatorTypes.NotBetweenExclusive:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,158 @@
+package operators
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ondrovic/common/types"
+)
+
+// TestMatch tests the Match func with ints, floats, strings and durations.
+func TestMatch(t *testing.T) {
+	type InputStruct struct {
+		operator types.OperatorType
+		match    func(operator types.OperatorType) (bool, error)
+	}
+
+	ints := func(wanted, value int, upper ...int) func(types.OperatorType) (bool, error) {
+		return func(operator types.OperatorType) (bool, error) { return Match(operator, wanted, value, upper...) }
+	}
+	floats := func(wanted, value float64, upper ...float64) func(types.OperatorType) (bool, error) {
+		return func(operator types.OperatorType) (bool, error) { return Match(operator, wanted, value, upper...) }
+	}
+	strs := func(wanted, value string, upper ...string) func(types.OperatorType) (bool, error) {
+		return func(operator types.OperatorType) (bool, error) { return Match(operator, wanted, value, upper...) }
+	}
+	durations := func(wanted, value time.Duration, upper ...time.Duration) func(types.OperatorType) (bool, error) {
+		return func(operator types.OperatorType) (bool, error) { return Match(operator, wanted, value, upper...) }
+	}
+
+	ops := types.OperatorTypes
+	tests := []*types.TestLayout[InputStruct, bool]{
+		{Name: "Int equal", Input: InputStruct{operator: ops.EqualTo, match: ints(5, 5)}, Expected: true},
+		{Name: "Int not equal", Input: InputStruct{operator: ops.NotEqualTo, match: ints(5, 5)}, Expected: false},
+		{Name: "Int less than", Input: InputStruct{operator: ops.LessThan, match: ints(5, 4)}, Expected: true},
+		{Name: "Int less than equal", Input: InputStruct{operator: ops.LessThanEqualTo, match: ints(5, 5)}, Expected: true},
+		{Name: "Int greater than", Input: InputStruct{operator: ops.GreaterThan, match: ints(5, 5)}, Expected: false},
+		{Name: "Int greater than equal", Input: InputStruct{operator: ops.GreaterThanEqualTo, match: ints(5, 6)}, Expected: true},
+		{Name: "Int between includes bounds", Input: InputStruct{operator: ops.Between, match: ints(1024, 4096, 4096)}, Expected: true},
+		{Name: "Int between exclusive", Input: InputStruct{operator: ops.BetweenExclusive, match: ints(1024, 4096, 4096)}, Expected: false},
+		{Name: "Int not between", Input: InputStruct{operator: ops.NotBetween, match: ints(1024, 800, 4096)}, Expected: true},
+		{Name: "Int not between exclusive", Input: InputStruct{operator: ops.NotBetweenExclusive, match: ints(1024, 1024, 4096)}, Expected: true},
+		{Name: "Unknown operator is equal", Input: InputStruct{operator: "unknown", match: ints(5, 5)}, Expected: true},
+		{Name: "Float greater than", Input: InputStruct{operator: ops.GreaterThan, match: floats(23.976, 24)}, Expected: true},
+		{Name: "Float between", Input: InputStruct{operator: ops.Between, match: floats(0.5, 0.75, 1)}, Expected: true},
+		{Name: "String less than", Input: InputStruct{operator: ops.LessThan, match: strs("m", "apple")}, Expected: true},
+		{Name: "String between", Input: InputStruct{operator: ops.Between, match: strs("a", "zebra", "m")}, Expected: false},
+		{Name: "Duration greater than", Input: InputStruct{operator: ops.GreaterThan, match: durations(90*time.Minute, 2*time.Hour)}, Expected: true},
+		{Name: "Duration not between", Input: InputStruct{operator: ops.NotBetween, match: durations(time.Minute, 30*time.Second, time.Hour)}, Expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := test.Input.match(test.Input.operator)
+			if err != nil {
+				t.Fatalf("Match(%v) - %v error = %v", test.Input.operator, test.Name, err)
+			}
+			if result != test.Expected {
+				t.Errorf("Match(%v) - %v = %v; want %v", test.Input.operator, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestMatchFunc tests the MatchFunc func with times.
+func TestMatchFunc(t *testing.T) {
+	type InputStruct struct {
+		operator types.OperatorType
+		value    time.Time
+		upper    []time.Time
+	}
+
+	year2020 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	year2021 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []*types.TestLayout[InputStruct, bool]{
+		{Name: "Before", Input: InputStruct{operator: types.OperatorTypes.LessThan, value: year2020.Add(-time.Second)}, Expected: true},
+		{Name: "Not before", Input: InputStruct{operator: types.OperatorTypes.LessThan, value: year2020}, Expected: false},
+		{Name: "Same instant in another zone", Input: InputStruct{operator: types.OperatorTypes.EqualTo, value: year2020.In(time.FixedZone("EST", -5*3600))}, Expected: true},
+		{Name: "Within range", Input: InputStruct{operator: types.OperatorTypes.Between, value: year2020.AddDate(0, 6, 0), upper: []time.Time{year2021}}, Expected: true},
+		{Name: "Outside range", Input: InputStruct{operator: types.OperatorTypes.Between, value: year2021.Add(time.Second), upper: []time.Time{year2021}}, Expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := MatchFunc(test.Input.operator, time.Time.Compare, year2020, test.Input.value, test.Input.upper...)
+			if err != nil {
+				t.Fatalf("MatchFunc(%v, %v) - %v error = %v", test.Input.operator, test.Input.value, test.Name, err)
+			}
+			if result != test.Expected {
+				t.Errorf("MatchFunc(%v, %v) - %v = %v; want %v", test.Input.operator, test.Input.value, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestMatchWithTolerance tests the MatchWithTolerance func.
+func TestMatchWithTolerance(t *testing.T) {
+	type InputStruct struct {
+		operator  types.OperatorType
+		wanted    int64
+		value     int64
+		tolerance Tolerance[int64]
+		upper     []int64
+	}
+
+	ops := types.OperatorTypes
+	within := Tolerance[int64]{Lower: 10, Upper: 20}
+	tests := []*types.TestLayout[InputStruct, bool]{
+		{Name: "Equal within lower", Input: InputStruct{operator: ops.EqualTo, wanted: 100, value: 90, tolerance: within}, Expected: true},
+		{Name: "Equal below lower", Input: InputStruct{operator: ops.EqualTo, wanted: 100, value: 89, tolerance: within}, Expected: false},
+		{Name: "Equal within upper", Input: InputStruct{operator: ops.EqualTo, wanted: 100, value: 120, tolerance: within}, Expected: true},
+		{Name: "Not equal above upper", Input: InputStruct{operator: ops.NotEqualTo, wanted: 100, value: 121, tolerance: within}, Expected: true},
+		{Name: "Less than ignores tolerance", Input: InputStruct{operator: ops.LessThan, wanted: 100, value: 95, tolerance: within}, Expected: true},
+		{Name: "Greater than ignores tolerance", Input: InputStruct{operator: ops.GreaterThan, wanted: 100, value: 110, tolerance: within}, Expected: true},
+		{Name: "Between widened", Input: InputStruct{operator: ops.Between, wanted: 100, value: 220, tolerance: within, upper: []int64{200}}, Expected: true},
+		{Name: "Not between widened", Input: InputStruct{operator: ops.NotBetween, wanted: 100, value: 91, tolerance: within, upper: []int64{200}}, Expected: false},
+		{Name: "Upper bound left open", Input: InputStruct{operator: ops.EqualTo, wanted: math.MaxInt64 - 5, value: math.MaxInt64, tolerance: within}, Expected: true},
+		{Name: "Lower bound left open", Input: InputStruct{operator: ops.EqualTo, wanted: math.MinInt64 + 5, value: math.MinInt64, tolerance: within}, Expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := MatchWithTolerance(test.Input.operator, test.Input.wanted, test.Input.value, test.Input.tolerance, test.Input.upper...)
+			if err != nil {
+				t.Fatalf("MatchWithTolerance(%v, %d, %d) - %v error = %v", test.Input.operator, test.Input.wanted, test.Input.value, test.Name, err)
+			}
+			if result != test.Expected {
+				t.Errorf("MatchWithTolerance(%v, %d, %d) - %v = %v; want %v", test.Input.operator, test.Input.wanted, test.Input.value, test.Name, result, test.Expected)
+			}
+		})
+	}
+
+	t.Run("Unsigned lower bound left open", func(t *testing.T) {
+		if result, err := MatchWithTolerance(ops.EqualTo, uint(5), 0, Tolerance[uint]{Lower: 10}); err != nil || !result {
+			t.Errorf("MatchWithTolerance(EqualTo, 5, 0) = %v, %v; want true", result, err)
+		}
+	})
+}
+
+// TestMatch_Errors tests that invalid ranges and tolerances are reported.
+func TestMatch_Errors(t *testing.T) {
+	tests := []*types.TestLayout[func() (bool, error), string]{
+		{Name: "Missing upper bound", Input: func() (bool, error) { return Match(types.OperatorTypes.Between, 1, 2) }, Expected: "Between needs exactly one upper bound, got 0"},
+		{Name: "Two upper bounds", Input: func() (bool, error) { return Match(types.OperatorTypes.NotBetween, 1, 2, 3, 4) }, Expected: "Not Between needs exactly one upper bound, got 2"},
+		{Name: "Reversed bounds", Input: func() (bool, error) { return Match(types.OperatorTypes.Between, "m", "b", "a") }, Expected: "lower bound m is greater than upper bound a"},
+		{Name: "Negative tolerance", Input: func() (bool, error) {
+			return MatchWithTolerance(types.OperatorTypes.EqualTo, 1.0, 1.0, Tolerance[float64]{Lower: -1})
+		}, Expected: "tolerance cannot be negative"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if _, err := test.Input(); err == nil || err.Error() != test.Expected {
+				t.Errorf("%v error = %v; want %v", test.Name, err, test.Expected)
+			}
+		})
+	}
+}
@@ -1,12 +1,16 @@
 package results
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/ondrovic/common/utils"
 	"github.com/ondrovic/common/utils/formatters"
 	"github.com/pterm/pterm"
 )
@@ -185,3 +189,84 @@ func getHeadersAndFields(data interface{}) (headers []string, fields []string) {
 	}
 	return
 }
+
+// validationRow is a row of the table `RenderValidationErrorsTable` renders.
+type validationRow struct {
+	Path    string
+	Rule    string
+	Value   string
+	Message string
+}
+
+// FormatValidationErrors renders the failures of err, as returned by `utils.ValidateStruct`, as a pterm
+// bullet list with one item per failure. Any other error is rendered as a single item and a nil err as
+// an empty string.
+//
+// Example usage:
+//
+//	if err := utils.ValidateStruct(config); err != nil {
+//	    pterm.Print(results.FormatValidationErrors(err))
+//	}
+func FormatValidationErrors(err error) string {
+	failures := validationFailures(err)
+	if len(failures) == 0 {
+		return ""
+	}
+
+	items := []pterm.BulletListItem{}
+	for _, failure := range failures {
+		items = append(items, pterm.BulletListItem{Level: 0, Text: failure.Message})
+	}
+	list, _ := pterm.DefaultBulletList.WithItems(items).Srender()
+	return list
+}
+
+// RenderValidationErrorsTable renders the failures of err, as returned by `utils.ValidateStruct`, as a
+// table with the path, rule, offending value and message of each, see
+// `GenericRenderResultsTableInterface`. Any other error is rendered as a single row holding its message
+// and a nil err renders nothing.
+func RenderValidationErrorsTable(err error) {
+	failures := validationFailures(err)
+	if len(failures) == 0 {
+		return
+	}
+
+	rows := []validationRow{}
+	for _, failure := range failures {
+		row := validationRow{Path: failure.Path, Rule: failure.Rule, Message: failure.Message}
+		if failure.Rule != "" {
+			row.Value = formatValidationValue(failure.Value)
+		}
+		if failure.Param != "" {
+			row.Rule += "=" + failure.Param
+		}
+		rows = append(rows, row)
+	}
+	GenericRenderResultsTableInterface(rows, nil)
+}
+
+// validationFailures returns the failures held by err, or a single failure holding the message of any
+// other error.
+func validationFailures(err error) utils.ValidationErrors {
+	if err == nil {
+		return nil
+	}
+
+	var failures utils.ValidationErrors
+	if errors.As(err, &failures) {
+		return failures
+	}
+	var failure *utils.ValidationError
+	if errors.As(err, &failure) {
+		return utils.ValidationErrors{failure}
+	}
+	return utils.ValidationErrors{{Message: err.Error()}}
+}
+
+// formatValidationValue formats an offending value, quoting strings so an empty one shows.
+func formatValidationValue(value interface{}) string {
+	if text, ok := value.(string); ok {
+		return strconv.Quote(text)
+	}
+	return fmt.Sprint(value)
+}
@@ -2,6 +2,7 @@ package results
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -16,6 +17,7 @@ import (
 
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils"
 	"github.com/pterm/pterm"
 )
 
@@ -291,3 +293,56 @@ func TestGenericSortInterface(t *testing.T) {
 		})
 	}
 }
+
+// TestFormatValidationErrors tests the FormatValidationErrors func.
+func TestFormatValidationErrors(t *testing.T) {
+	failures := utils.ValidationErrors{
+		{Path: "Name", Field: "Name", Rule: "required", Value: "", Message: "Name cannot be empty"},
+		{Path: "Style.Color.Background", Field: "Background", Rule: "required", Value: "", Message: "Style.Color.Background cannot be empty"},
+	}
+
+	tests := []*types.TestLayout[error, []string]{
+		{Name: "Validation errors", Input: failures, Expected: []string{"Name cannot be empty", "Style.Color.Background cannot be empty"}},
+		{Name: "Wrapped validation errors", Input: fmt.Errorf("invalid config: %w", failures), Expected: []string{"Name cannot be empty", "Style.Color.Background cannot be empty"}},
+		{Name: "Other error", Input: errors.New("validateApp expects a struct"), Expected: []string{"validateApp expects a struct"}},
+		{Name: "Nil error", Input: nil, Expected: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result := FormatValidationErrors(test.Input)
+			if lines := strings.Count(result, "\n"); lines != len(test.Expected) {
+				t.Errorf("FormatValidationErrors() - %v = %q; want %d items", test.Name, result, len(test.Expected))
+			}
+			for _, message := range test.Expected {
+				if !contains(result, message) {
+					t.Errorf("FormatValidationErrors() - %v = %q; want it to contain %q", test.Name, result, message)
+				}
+			}
+		})
+	}
+}
+
+// TestRenderValidationErrorsTable tests the RenderValidationErrorsTable func.
+func TestRenderValidationErrorsTable(t *testing.T) {
+	// Redirect stdout to capture output
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	RenderValidationErrorsTable(utils.ValidationErrors{
+		{Path: "Style.Color.Foreground", Field: "Foreground", Rule: "oneof", Param: "white black", Value: "red", Message: `Style.Color.Foreground must be one of white, black, got "red"`},
+		{Path: "Retries", Field: "Retries", Rule: "max", Param: "5", Value: 9, Message: "Retries must be at most 5"},
+	})
+
+	w.Close()
+	out, _ := io.ReadAll(r)
+	os.Stdout = old
+
+	output := string(out)
+	for _, expected := range []string{"PATH", "RULE", "VALUE", "MESSAGE", "Style.Color.Foreground", "oneof=white black", `"red"`, "max=5", "Retries must be at most 5"} {
+		if !contains(output, expected) {
+			t.Errorf("RenderValidationErrorsTable() = %q; want it to contain %q", output, expected)
+		}
+	}
+}
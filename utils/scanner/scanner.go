@@ -0,0 +1,347 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils"
+	"github.com/ondrovic/common/utils/magic"
+	"github.com/ondrovic/common/utils/timefilter"
+)
+
+// Scan walks opts.Root with a bounded pool of workers and streams every regular file that belongs to
+// opts.FileType, by extension, content or both as set by opts.Detection (see `magic.Match`), that,
+// when an operator is set, passes `utils.GetOperatorSizeMatchesWithTolerance` and that passes every
+// filter of opts.TimeFilters (see `timefilter.Match`). The returned channel is closed once the scan
+// finishes or ctx is cancelled. Errors for individual paths do not stop the scan, they are collected
+// and returned by the wait function once the channel has been drained. Symlinks are handled according
+// to opts.Symlinks, broken links and followed links that lead back to a directory being scanned are
+// reported as errors wrapping `utils.ErrBrokenLink` and `utils.ErrSymlinkLoop`.
+//
+// Example usage:
+//
+//	matches, wait := scanner.Scan(ctx, types.ScanOptions{Root: ".", FileType: types.FileTypes.Video})
+//	for match := range matches {
+//	    fmt.Println(match.Path, formatters.FormatSize(match.Size))
+//	}
+//	scanErrors := wait()
+func Scan(ctx context.Context, opts types.ScanOptions) (<-chan types.ScanMatch, func() []types.ScanError) {
+	if opts.Ops == nil {
+		opts.Ops = &types.RealDirOps{}
+	}
+	if opts.FileType == "" {
+		opts.FileType = types.FileTypes.Any
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	s := &scan{
+		ctx:     ctx,
+		opts:    opts,
+		matches: make(chan types.ScanMatch),
+		done:    make(chan struct{}),
+	}
+	s.cond = sync.NewCond(&s.mu)
+
+	if err := checkOperator(opts); err != nil {
+		s.addError(opts.Root, err)
+	} else if info, err := opts.Ops.Stat(opts.Root); err != nil {
+		s.addError(opts.Root, err)
+	} else if !info.IsDir() {
+		s.addError(opts.Root, fmt.Errorf("%s is not a directory", opts.Root))
+	} else {
+		s.push(opts.Root, nil, info)
+	}
+
+	// Wake any waiting workers when the context is cancelled so they can exit.
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.cond.Broadcast()
+			s.mu.Unlock()
+		case <-s.done:
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work()
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		if err := ctx.Err(); err != nil {
+			s.addError(opts.Root, err)
+		}
+		close(s.done)
+		close(s.matches)
+	}()
+
+	return s.matches, func() []types.ScanError {
+		<-s.done
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.errors
+	}
+}
+
+// Collect runs `Scan` to completion and returns every match along with the collected errors.
+func Collect(ctx context.Context, opts types.ScanOptions) ([]types.ScanMatch, []types.ScanError) {
+	matches, wait := Scan(ctx, opts)
+
+	results := []types.ScanMatch{}
+	for match := range matches {
+		results = append(results, match)
+	}
+
+	return results, wait()
+}
+
+// checkOperator validates the size and time filters and the file type of opts once, so a bad filter,
+// such as a range whose lower bound is above its upper bound, or a file type content detection cannot
+// recognise, is reported once rather than for every file.
+func checkOperator(opts types.ScanOptions) error {
+	if opts.Detection == types.DetectionModes.Content && opts.FileType != types.FileTypes.Any && !magic.HasSignature(opts.FileType) {
+		return fmt.Errorf("%w: %s", magic.ErrNoSignature, opts.FileType)
+	}
+	for _, filter := range opts.TimeFilters {
+		if err := timefilter.Validate(filter); err != nil {
+			return err
+		}
+	}
+	if opts.Operator == "" {
+		return nil
+	}
+
+	_, err := sizeMatches(opts, 0)
+	return err
+}
+
+// sizeMatches applies the size filter of opts to size, using opts.Tolerance when it is set and
+// opts.ToleranceSize otherwise.
+func sizeMatches(opts types.ScanOptions, size int64) (bool, error) {
+	if opts.Tolerance != (types.Tolerance{}) {
+		return utils.GetOperatorSizeMatchesWithTolerance(opts.Operator, opts.WantedSize, opts.Tolerance, size, opts.UpperSize)
+	}
+	return utils.GetOperatorSizeMatches(opts.Operator, opts.WantedSize, opts.ToleranceSize, size, opts.UpperSize)
+}
+
+// scan holds the shared state of a running Scan.
+type scan struct {
+	ctx     context.Context
+	opts    types.ScanOptions
+	matches chan types.ScanMatch
+	done    chan struct{}
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []dirItem
+	pending int
+	errors  []types.ScanError
+}
+
+// dirItem is a queued directory. ancestors holds the directory and every directory above it, it is
+// only tracked when symlinks are followed, to detect loops.
+type dirItem struct {
+	path      string
+	ancestors []os.FileInfo
+}
+
+// work pops directories off the queue until the queue is drained or the context is cancelled.
+func (s *scan) work() {
+	for {
+		item, ok := s.pop()
+		if !ok {
+			return
+		}
+		s.scanDir(item)
+		s.finish()
+	}
+}
+
+// scanDir reads a queued directory, queues its subdirectories and sends any matching files.
+func (s *scan) scanDir(item dirItem) {
+	entries, err := s.opts.Ops.ReadDir(item.path)
+	if err != nil {
+		s.addError(item.path, err)
+		return
+	}
+
+	for _, entry := range entries {
+		if s.ctx.Err() != nil {
+			return
+		}
+
+		path := filepath.Join(item.path, entry.Name())
+		if s.opts.Ignore != nil && s.opts.Ignore.Match(path, entry.IsDir()) {
+			continue
+		}
+
+		var info os.FileInfo
+		var fileType types.FileType
+		var ok bool
+		switch {
+		case entry.Type()&os.ModeSymlink != 0:
+			info, err = utils.ResolveSymlink(path, s.opts.Ops, s.opts.Symlinks)
+			if err != nil {
+				s.addError(path, err)
+				continue
+			}
+			if info == nil {
+				continue
+			}
+			if info.IsDir() {
+				if utils.IsSymlinkLoop(item.ancestors, info) {
+					s.addError(path, fmt.Errorf("%w: %s", utils.ErrSymlinkLoop, path))
+					continue
+				}
+				s.push(path, item.ancestors, info)
+				continue
+			}
+			// Followed links must lead to a regular file, links kept as files are matched as they are.
+			if !info.Mode().IsRegular() && s.opts.Symlinks != types.SymlinkPolicies.File {
+				continue
+			}
+			if fileType, ok = s.fileType(path); !ok {
+				continue
+			}
+		case entry.IsDir():
+			if s.opts.Symlinks == types.SymlinkPolicies.Follow {
+				if info, err = entry.Info(); err != nil {
+					s.addError(path, err)
+					continue
+				}
+			}
+			s.push(path, item.ancestors, info)
+			continue
+		case !entry.Type().IsRegular():
+			continue
+		default:
+			if fileType, ok = s.fileType(path); !ok {
+				continue
+			}
+			if info, err = entry.Info(); err != nil {
+				s.addError(path, err)
+				continue
+			}
+		}
+
+		if !s.match(path, fileType, info) {
+			return
+		}
+	}
+}
+
+// fileType reports whether path belongs to the wanted file type and returns the type it was identified
+// as. Files that cannot be read for content detection are recorded as errors and skipped.
+func (s *scan) fileType(path string) (types.FileType, bool) {
+	fileType, ok, err := magic.Match(s.opts.Ops, s.opts.FileType, path, s.opts.Detection)
+	if err != nil {
+		s.addError(path, err)
+		return "", false
+	}
+	return fileType, ok
+}
+
+// match sends path when info passes the size and time filters, it returns false once the context is
+// cancelled.
+func (s *scan) match(path string, fileType types.FileType, info os.FileInfo) bool {
+	if s.opts.Operator != "" {
+		matched, err := sizeMatches(s.opts, info.Size())
+		if err != nil {
+			s.addError(path, err)
+			return true
+		}
+		if !matched {
+			return true
+		}
+	}
+	for _, filter := range s.opts.TimeFilters {
+		matched, err := timefilter.Match(filter, info)
+		if err != nil {
+			s.addError(path, err)
+			return true
+		}
+		if !matched {
+			return true
+		}
+	}
+
+	match := types.ScanMatch{
+		Path:     path,
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+		FileType: fileType,
+	}
+
+	select {
+	case s.matches <- match:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+// push queues a directory to be scanned. When symlinks are followed info, the directory itself, is
+// appended to a copy of ancestors.
+func (s *scan) push(dir string, ancestors []os.FileInfo, info os.FileInfo) {
+	if s.opts.Symlinks == types.SymlinkPolicies.Follow {
+		ancestors = append(append([]os.FileInfo(nil), ancestors...), info)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queue = append(s.queue, dirItem{path: dir, ancestors: ancestors})
+	s.pending++
+	s.cond.Signal()
+}
+
+// pop waits for a queued directory, it returns false once every directory has been scanned or the
+// context is cancelled.
+func (s *scan) pop() (dirItem, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.queue) == 0 && s.pending > 0 && s.ctx.Err() == nil {
+		s.cond.Wait()
+	}
+	if len(s.queue) == 0 || s.ctx.Err() != nil {
+		return dirItem{}, false
+	}
+
+	item := s.queue[len(s.queue)-1]
+	s.queue = s.queue[:len(s.queue)-1]
+	return item, true
+}
+
+// finish marks a popped directory as scanned and wakes the workers once nothing is left.
+func (s *scan) finish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending--
+	if s.pending == 0 {
+		s.cond.Broadcast()
+	}
+}
+
+// addError records a per-path error.
+func (s *scan) addError(path string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.errors = append(s.errors, types.ScanError{Path: path, Err: err})
+}
@@ -0,0 +1,384 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils"
+	"github.com/ondrovic/common/utils/ignore"
+	"github.com/ondrovic/common/utils/magic"
+	"github.com/ondrovic/common/utils/memfs"
+)
+
+// CreateTestFS creates an in-memory tree of media and documents for testing.
+//
+//	/root
+//	├── movies/big.mp4 (2048 B)
+//	├── movies/small.mkv (512 B)
+//	├── movies/extras/trailer.mp4 (1024 B)
+//	├── photos/cat.jpg (100 B)
+//	├── docs/notes.txt (10 B)
+//	└── locked/hidden.mp4 (4096 B)
+func CreateTestFS(t *testing.T) *memfs.MemDirOps {
+	t.Helper()
+	ops := memfs.New()
+	files := map[string]int{
+		"/root/movies/big.mp4":            2048,
+		"/root/movies/small.mkv":          512,
+		"/root/movies/extras/trailer.mp4": 1024,
+		"/root/photos/cat.jpg":            100,
+		"/root/docs/notes.txt":            10,
+		"/root/locked/hidden.mp4":         4096,
+	}
+	for name, size := range files {
+		if err := ops.WriteFile(name, make([]byte, size), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	return ops
+}
+
+// matchPaths returns the sorted slash separated paths of matches.
+func matchPaths(matches []types.ScanMatch) []string {
+	paths := []string{}
+	for _, match := range matches {
+		paths = append(paths, filepath.ToSlash(match.Path))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// TestCollect tests the Collect func.
+func TestCollect(t *testing.T) {
+	tests := []*types.TestLayout[types.ScanOptions, []string]{
+		{
+			Name:  "Every file",
+			Input: types.ScanOptions{Root: "/root"},
+			Expected: []string{
+				"/root/docs/notes.txt", "/root/locked/hidden.mp4", "/root/movies/big.mp4",
+				"/root/movies/extras/trailer.mp4", "/root/movies/small.mkv", "/root/photos/cat.jpg",
+			},
+		},
+		{
+			Name:     "Videos only",
+			Input:    types.ScanOptions{Root: "/root", FileType: types.FileTypes.Video},
+			Expected: []string{"/root/locked/hidden.mp4", "/root/movies/big.mp4", "/root/movies/extras/trailer.mp4", "/root/movies/small.mkv"},
+		},
+		{
+			Name:     "Videos at least 1 KB",
+			Input:    types.ScanOptions{Root: "/root", FileType: types.FileTypes.Video, Operator: types.OperatorTypes.GreaterThanEqualTo, WantedSize: 1024},
+			Expected: []string{"/root/locked/hidden.mp4", "/root/movies/big.mp4", "/root/movies/extras/trailer.mp4"},
+		},
+		{
+			Name:     "Videos between 1 KB and 2 KB",
+			Input:    types.ScanOptions{Root: "/root", FileType: types.FileTypes.Video, Operator: types.OperatorTypes.Between, WantedSize: 1024, UpperSize: 2048},
+			Expected: []string{"/root/movies/big.mp4", "/root/movies/extras/trailer.mp4"},
+		},
+		{
+			Name:     "Videos not between 1 KB and 2 KB",
+			Input:    types.ScanOptions{Root: "/root", FileType: types.FileTypes.Video, Operator: types.OperatorTypes.NotBetween, WantedSize: 1024, UpperSize: 2048},
+			Expected: []string{"/root/locked/hidden.mp4", "/root/movies/small.mkv"},
+		},
+		{
+			Name:     "Videos 1 KB up to 100%",
+			Input:    types.ScanOptions{Root: "/root", FileType: types.FileTypes.Video, Operator: types.OperatorTypes.EqualTo, WantedSize: 1024, Tolerance: types.Tolerance{Upper: types.ToleranceBound{Percent: 100}}},
+			Expected: []string{"/root/movies/big.mp4", "/root/movies/extras/trailer.mp4"},
+		},
+		{
+			Name:     "Single worker",
+			Input:    types.ScanOptions{Root: "/root/movies", FileType: types.FileTypes.Video, Workers: 1},
+			Expected: []string{"/root/movies/big.mp4", "/root/movies/extras/trailer.mp4", "/root/movies/small.mkv"},
+		},
+		{
+			Name:     "Images",
+			Input:    types.ScanOptions{Root: "/root", FileType: types.FileTypes.Image},
+			Expected: []string{"/root/photos/cat.jpg"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			test.Input.Ops = CreateTestFS(t)
+			matches, scanErrors := Collect(context.Background(), test.Input)
+			if len(scanErrors) != 0 {
+				t.Errorf("Collect() - %v errors = %v; want none", test.Name, scanErrors)
+			}
+			if got := matchPaths(matches); !reflect.DeepEqual(got, test.Expected) {
+				t.Errorf("Collect() - %v = %v; want %v", test.Name, got, test.Expected)
+			}
+		})
+	}
+}
+
+// TestCollect_MatchDetails tests that matches carry size, mtime and file type.
+func TestCollect_MatchDetails(t *testing.T) {
+	ops := CreateTestFS(t)
+	mtime := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	if err := ops.Chtimes("/root/photos/cat.jpg", mtime, mtime); err != nil {
+		t.Fatalf("failed to set times: %v", err)
+	}
+
+	matches, _ := Collect(context.Background(), types.ScanOptions{Root: "/root/photos", Ops: ops})
+	if len(matches) != 1 {
+		t.Fatalf("Collect() matches = %v; want 1", matches)
+	}
+
+	expected := types.ScanMatch{Path: filepath.Join("/root/photos", "cat.jpg"), Size: 100, ModTime: mtime, FileType: types.FileTypes.Image}
+	if !reflect.DeepEqual(matches[0], expected) {
+		t.Errorf("Collect() match = %+v; want %+v", matches[0], expected)
+	}
+}
+
+// TestCollect_Errors tests that per-path errors are collected without stopping the scan.
+func TestCollect_Errors(t *testing.T) {
+	tests := []*types.TestLayout[string, []string]{
+		{Name: "Unreadable directory is skipped", Input: "/root", Expected: []string{"/root/movies/big.mp4", "/root/movies/extras/trailer.mp4", "/root/movies/small.mkv"}},
+		{Name: "Missing root", Input: "/missing", Expected: []string{}},
+		{Name: "Root is a file", Input: "/root/docs/notes.txt", Expected: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ops := CreateTestFS(t)
+			ops.InjectError(memfs.OpReadDir, "/root/locked", errors.New("simulated ReadDir error"))
+
+			matches, scanErrors := Collect(context.Background(), types.ScanOptions{Root: test.Input, FileType: types.FileTypes.Video, Ops: ops})
+			if got := matchPaths(matches); !reflect.DeepEqual(got, test.Expected) {
+				t.Errorf("Collect(%q) - %v = %v; want %v", test.Input, test.Name, got, test.Expected)
+			}
+			if len(scanErrors) != 1 {
+				t.Fatalf("Collect(%q) - %v errors = %v; want 1", test.Input, test.Name, scanErrors)
+			}
+			if test.Name == "Unreadable directory is skipped" && !strings.Contains(scanErrors[0].Err.Error(), "simulated ReadDir error") {
+				t.Errorf("Collect(%q) - %v error = %v; want simulated ReadDir error", test.Input, test.Name, scanErrors[0].Err)
+			}
+		})
+	}
+}
+
+// TestCollect_InvalidRange tests that an invalid size range is reported once and matches nothing.
+func TestCollect_InvalidRange(t *testing.T) {
+	opts := types.ScanOptions{Root: "/root", Operator: types.OperatorTypes.Between, WantedSize: 2048, UpperSize: 1024, Ops: CreateTestFS(t)}
+	matches, scanErrors := Collect(context.Background(), opts)
+	if len(matches) != 0 {
+		t.Errorf("Collect() matches = %v; want none", matchPaths(matches))
+	}
+	if len(scanErrors) != 1 || !strings.Contains(scanErrors[0].Err.Error(), "lower bound 2048 is greater than upper bound 1024") {
+		t.Errorf("Collect() errors = %v; want one lower bound error", scanErrors)
+	}
+}
+
+// TestCollect_TimeFilters tests that matches are filtered by age and date.
+func TestCollect_TimeFilters(t *testing.T) {
+	now := time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	ops := CreateTestFS(t)
+	if err := ops.Chtimes("/root/movies/big.mp4", now, now.AddDate(0, -2, 0)); err != nil {
+		t.Fatalf("failed to set times: %v", err)
+	}
+	if err := ops.Chtimes("/root/movies/small.mkv", now, now.AddDate(-1, 0, 0)); err != nil {
+		t.Fatalf("failed to set times: %v", err)
+	}
+
+	tests := []*types.TestLayout[[]types.TimeFilter, []string]{
+		{
+			Name:     "Older than 30 days",
+			Input:    []types.TimeFilter{{Field: types.TimeFields.Modified, Operator: types.OperatorTypes.GreaterThan, Age: 30 * 24 * time.Hour, Now: clock}},
+			Expected: []string{"/root/movies/big.mp4", "/root/movies/small.mkv"},
+		},
+		{
+			Name: "Older than 30 days and after 2024",
+			Input: []types.TimeFilter{
+				{Field: types.TimeFields.Modified, Operator: types.OperatorTypes.GreaterThan, Age: 30 * 24 * time.Hour, Now: clock},
+				{Field: types.TimeFields.Modified, Operator: types.OperatorTypes.GreaterThanEqualTo, Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			},
+			Expected: []string{"/root/movies/big.mp4"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			matches, scanErrors := Collect(context.Background(), types.ScanOptions{Root: "/root/movies", Ops: ops, TimeFilters: test.Input})
+			if got := matchPaths(matches); !reflect.DeepEqual(got, test.Expected) {
+				t.Errorf("Collect() - %v = %v; want %v", test.Name, got, test.Expected)
+			}
+			if len(scanErrors) != 0 {
+				t.Errorf("Collect() - %v errors = %v; want none", test.Name, scanErrors)
+			}
+		})
+	}
+}
+
+// TestScan_Cancel tests that cancelling the context stops the scan and records the cancellation.
+func TestScan_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	matches, wait := Scan(ctx, types.ScanOptions{Root: "/root", Ops: CreateTestFS(t), Workers: 2})
+
+	<-matches
+	cancel()
+	for range matches {
+	}
+
+	scanErrors := wait()
+	if len(scanErrors) == 0 || !errors.Is(scanErrors[len(scanErrors)-1].Err, context.Canceled) {
+		t.Errorf("Scan() after cancel errors = %v; want context.Canceled", scanErrors)
+	}
+}
+
+// TestCollect_Ignore tests that ignored files and directories are left out of the scan.
+func TestCollect_Ignore(t *testing.T) {
+	tests := []*types.TestLayout[[]string, []string]{
+		{Name: "Ignore directory", Input: []string{"extras/"}, Expected: []string{"/root/locked/hidden.mp4", "/root/movies/big.mp4", "/root/movies/small.mkv"}},
+		{Name: "Ignore extension with exception", Input: []string{"*.mp4", "!big.mp4"}, Expected: []string{"/root/movies/big.mp4", "/root/movies/small.mkv"}},
+		{Name: "Anchored pattern", Input: []string{"/locked"}, Expected: []string{"/root/movies/big.mp4", "/root/movies/extras/trailer.mp4", "/root/movies/small.mkv"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			matcher, err := ignore.New("/root", test.Input...)
+			if err != nil {
+				t.Fatalf("ignore.New() error = %v", err)
+			}
+
+			matches, scanErrors := Collect(context.Background(), types.ScanOptions{Root: "/root", FileType: types.FileTypes.Video, Ops: CreateTestFS(t), Ignore: matcher})
+			if len(scanErrors) != 0 {
+				t.Errorf("Collect() - %v errors = %v; want none", test.Name, scanErrors)
+			}
+			if got := matchPaths(matches); !reflect.DeepEqual(got, test.Expected) {
+				t.Errorf("Collect() - %v = %v; want %v", test.Name, got, test.Expected)
+			}
+		})
+	}
+}
+
+// TestCollect_Symlinks tests each symlink policy along with broken link and loop reporting.
+func TestCollect_Symlinks(t *testing.T) {
+	type ExpectedResults struct {
+		paths  []string
+		errors []error
+	}
+
+	base := []string{"/root/locked/hidden.mp4", "/root/movies/big.mp4", "/root/movies/extras/trailer.mp4", "/root/movies/small.mkv"}
+	tests := []*types.TestLayout[types.SymlinkPolicy, ExpectedResults]{
+		{
+			Name:     "Skip",
+			Input:    types.SymlinkPolicies.Skip,
+			Expected: ExpectedResults{paths: base, errors: []error{utils.ErrBrokenLink}},
+		},
+		{
+			Name:     "File",
+			Input:    types.SymlinkPolicies.File,
+			Expected: ExpectedResults{paths: append([]string{"/root/links/movie.mp4"}, base...), errors: []error{utils.ErrBrokenLink}},
+		},
+		{
+			Name:  "Follow",
+			Input: types.SymlinkPolicies.Follow,
+			Expected: ExpectedResults{
+				paths: append([]string{
+					"/root/links/movie.mp4", "/root/links/movies/big.mp4", "/root/links/movies/extras/trailer.mp4", "/root/links/movies/small.mkv",
+				}, base...),
+				errors: []error{utils.ErrBrokenLink, utils.ErrSymlinkLoop},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ops := CreateTestFS(t)
+			links := map[string]string{
+				"/root/links/movie.mp4":  "/root/movies/big.mp4",
+				"/root/links/movies":     "../movies",
+				"/root/links/loop":       "/root",
+				"/root/links/broken.mp4": "/missing.mp4",
+			}
+			for name, target := range links {
+				if err := ops.Symlink(target, name); err != nil {
+					t.Fatalf("failed to create symlink: %v", err)
+				}
+			}
+
+			matches, scanErrors := Collect(context.Background(), types.ScanOptions{Root: "/root", FileType: types.FileTypes.Video, Ops: ops, Symlinks: test.Input, Workers: 1})
+			if got := matchPaths(matches); !reflect.DeepEqual(got, test.Expected.paths) {
+				t.Errorf("Collect() - %v = %v; want %v", test.Name, got, test.Expected.paths)
+			}
+			if len(scanErrors) != len(test.Expected.errors) {
+				t.Fatalf("Collect() - %v errors = %v; want %v", test.Name, scanErrors, test.Expected.errors)
+			}
+			for i, scanError := range scanErrors {
+				if !errors.Is(scanError.Err, test.Expected.errors[i]) {
+					t.Errorf("Collect() - %v error = %v; want %v", test.Name, scanError.Err, test.Expected.errors[i])
+				}
+			}
+
+			for _, match := range matches {
+				if match.Path != filepath.Join("/root/links", "movie.mp4") {
+					continue
+				}
+				expected := int64(2048)
+				if test.Input == types.SymlinkPolicies.File {
+					expected = int64(len("/root/movies/big.mp4"))
+				}
+				if match.Size != expected {
+					t.Errorf("Collect() - %v link size = %d; want %d", test.Name, match.Size, expected)
+				}
+			}
+		})
+	}
+}
+
+// TestCollect_Detection tests matching files by extension, content or both.
+func TestCollect_Detection(t *testing.T) {
+	tests := []*types.TestLayout[types.DetectionMode, []string]{
+		{Name: "Extension", Input: types.DetectionModes.Extension, Expected: []string{"/root/clips/fake.mp4", "/root/clips/real.mp4"}},
+		{Name: "Content", Input: types.DetectionModes.Content, Expected: []string{"/root/clips/real.mp4", "/root/clips/renamed"}},
+		{Name: "Both", Input: types.DetectionModes.Both, Expected: []string{"/root/clips/real.mp4"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ops := memfs.New()
+			files := map[string]string{
+				"/root/clips/real.mp4": "\x00\x00\x00\x18ftypisom",
+				"/root/clips/renamed":  "\x1a\x45\xdf\xa3",
+				"/root/clips/fake.mp4": "%PDF-1.7",
+			}
+			for name, data := range files {
+				if err := ops.WriteFile(name, []byte(data), 0o644); err != nil {
+					t.Fatalf("failed to write file: %v", err)
+				}
+			}
+
+			matches, scanErrors := Collect(context.Background(), types.ScanOptions{Root: "/root", FileType: types.FileTypes.Video, Ops: ops, Detection: test.Input})
+			if len(scanErrors) != 0 {
+				t.Errorf("Collect() - %v errors = %v; want none", test.Name, scanErrors)
+			}
+			if got := matchPaths(matches); !reflect.DeepEqual(got, test.Expected) {
+				t.Errorf("Collect() - %v = %v; want %v", test.Name, got, test.Expected)
+			}
+			for _, match := range matches {
+				if match.FileType != types.FileTypes.Video {
+					t.Errorf("Collect() - %v %s file type = %q; want %q", test.Name, match.Path, match.FileType, types.FileTypes.Video)
+				}
+			}
+		})
+	}
+
+	t.Run("Content without a signature", func(t *testing.T) {
+		ops := memfs.New()
+		if err := ops.WriteFile("/root/main.go", []byte("package main\n"), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+
+		_, scanErrors := Collect(context.Background(), types.ScanOptions{Root: "/root", FileType: types.FileTypes.Code, Ops: ops, Detection: types.DetectionModes.Content})
+		if len(scanErrors) != 1 || !errors.Is(scanErrors[0].Err, magic.ErrNoSignature) {
+			t.Errorf("Collect() code by content errors = %v; want one %v", scanErrors, magic.ErrNoSignature)
+		}
+	})
+}
@@ -0,0 +1,325 @@
+package sizes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils"
+	"github.com/ondrovic/common/utils/formatters"
+)
+
+var (
+	// ErrSyntax is returned for a size that does not start with a number.
+	ErrSyntax = errors.New("invalid size")
+	// ErrUnit is returned for a size whose unit is unknown.
+	ErrUnit = errors.New("unknown size unit")
+	// ErrNegative is returned for a negative size.
+	ErrNegative = errors.New("size cannot be negative")
+	// ErrOverflow is returned for a size larger than `math.MaxInt64` bytes.
+	ErrOverflow = errors.New("size overflows int64")
+)
+
+// prefixes are the unit prefixes in increasing order of magnitude, `k` being 1000 or 1024.
+const prefixes = "kmgtpe"
+
+// labels are the unit labels `Format` writes for each system, from bytes up to exabytes.
+var labels = map[types.SizeSystem][]string{
+	types.SizeSystems.IEC: {"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"},
+	types.SizeSystems.SI:  {"B", "kB", "MB", "GB", "TB", "PB", "EB"},
+}
+
+// Parse converts a size such as `700MB`, `1.5 GiB`, `512` or `100 Mbit` to bytes. The unit is a
+// prefix, `k`, `M`, `G`, `T`, `P` or `E` in either case, followed by `B`, `byte` or `bytes` for bytes or
+// by `b`, `bit` or `bits` for bits. The prefix alone stands for bytes and no unit at all means bytes. A
+// prefix followed by `i`, such as `KiB`, is always a power of 1024, other prefixes are powers of 1000
+// for `types.SizeSystems.SI` and powers of 1024 for `types.SizeSystems.IEC`, which an empty system
+// behaves like. Fractions of a byte are rounded to the nearest byte.
+//
+// Example usage:
+//
+//	sizes.Parse("1.5 GB", types.SizeSystems.SI)   // 1500000000
+//	sizes.Parse("1.5 GB", types.SizeSystems.IEC)  // 1610612736
+//	sizes.Parse("1.5 GiB", types.SizeSystems.SI)  // 1610612736
+//	sizes.Parse("8 Mbit", types.SizeSystems.SI)   // 1000000
+func Parse(size string, system types.SizeSystem) (int64, error) {
+	trimmed := strings.TrimSpace(size)
+	end := 0
+	for end < len(trimmed) && (trimmed[end] >= '0' && trimmed[end] <= '9' || trimmed[end] == '.' || (end == 0 && (trimmed[end] == '-' || trimmed[end] == '+'))) {
+		end++
+	}
+	numStr, unitStr := trimmed[:end], strings.TrimSpace(trimmed[end:])
+	if numStr == "" {
+		return 0, fmt.Errorf("%w: %q", ErrSyntax, size)
+	}
+
+	multiplier, perBits, err := unit(unitStr, system)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", err, size)
+	}
+
+	// Whole numbers are multiplied exactly, so that sizes above 2^53 bytes keep every digit.
+	if whole, err := strconv.ParseInt(numStr, 10, 64); err == nil {
+		if whole < 0 {
+			return 0, fmt.Errorf("%w: %q", ErrNegative, size)
+		}
+		hi, lo := bits.Mul64(uint64(whole), multiplier)
+		if hi != 0 || lo > math.MaxInt64 {
+			return 0, fmt.Errorf("%w: %q", ErrOverflow, size)
+		}
+		bytes := lo / perBits
+		if lo%perBits*2 >= perBits {
+			bytes++
+		}
+		return int64(bytes), nil
+	} else if errors.Is(err, strconv.ErrRange) {
+		if strings.HasPrefix(numStr, "-") {
+			return 0, fmt.Errorf("%w: %q", ErrNegative, size)
+		}
+		return 0, fmt.Errorf("%w: %q", ErrOverflow, size)
+	}
+
+	value, err := strconv.ParseFloat(numStr, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("%w: %q", ErrSyntax, size)
+	}
+	if value < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrNegative, size)
+	}
+	bytes := math.Round(value * float64(multiplier) / float64(perBits))
+	if bytes >= math.MaxInt64 {
+		return 0, fmt.Errorf("%w: %q", ErrOverflow, size)
+	}
+	return int64(bytes), nil
+}
+
+// MustParse is like `Parse` but panics on a size with an unknown unit, a negative size or one that
+// overflows an int64, which suits constants such as `sizes.MustParse("4 GiB", types.SizeSystems.IEC)`.
+func MustParse(size string, system types.SizeSystem) int64 {
+	bytes, err := Parse(size, system)
+	if err != nil {
+		panic(err)
+	}
+	return bytes
+}
+
+// Format converts bytes to a human-readable size with two decimals, such as `1.50 GiB` for
+// `types.SizeSystems.IEC`, which an empty system behaves like, or `1.61 GB` for `types.SizeSystems.SI`.
+// Sizes below 1 kB or 1 KiB are written in whole bytes. Parsing the result with `Parse` and the same
+// system gives back bytes to within a hundredth of the unit it is written in.
+func Format(bytes int64, system types.SizeSystem) string {
+	if bytes < 0 {
+		// Negating math.MinInt64 overflows, so work on the magnitude as a uint64.
+		return "-" + format(uint64(-(bytes+1))+1, system)
+	}
+	return format(uint64(bytes), system)
+}
+
+// format writes a size of magnitude bytes.
+func format(bytes uint64, system types.SizeSystem) string {
+	base := uint64(1024)
+	if system == types.SizeSystems.SI {
+		base = 1000
+	}
+	units, ok := labels[system]
+	if !ok {
+		units = labels[types.SizeSystems.IEC]
+	}
+
+	if bytes < base {
+		return fmt.Sprintf("%d %s", bytes, units[0])
+	}
+
+	exp, unitSize := 0, uint64(1)
+	for exp < len(units)-1 && bytes/unitSize >= base {
+		exp, unitSize = exp+1, unitSize*base
+	}
+
+	hundredths := roundedHundredths(bytes, unitSize)
+	if hundredths >= 100*base && exp < len(units)-1 {
+		// 1023.999 KiB reads better as 1.00 MiB.
+		exp, unitSize = exp+1, unitSize*base
+		hundredths = roundedHundredths(bytes, unitSize)
+	}
+
+	formatted := fmt.Sprintf("%d.%02d %s", hundredths/100, hundredths%100, units[exp])
+	if _, err := Parse(formatted, system); errors.Is(err, ErrOverflow) {
+		// Rounding up would not parse back, so round down instead.
+		hundredths--
+		formatted = fmt.Sprintf("%d.%02d %s", hundredths/100, hundredths%100, units[exp])
+	}
+	return formatted
+}
+
+// roundedHundredths returns bytes in hundredths of unitSize, rounded to the nearest hundredth. It works
+// on integers since a float64 cannot tell math.MaxInt64 from 2^63.
+func roundedHundredths(bytes, unitSize uint64) uint64 {
+	hi, lo := bits.Mul64(bytes, 100)
+	hundredths, remainder := bits.Div64(hi, lo, unitSize)
+	if remainder >= unitSize-remainder {
+		hundredths++
+	}
+	return hundredths
+}
+
+// Size is a number of bytes that reads and writes itself as a human-readable size, so it can be used
+// directly as a cobra flag, see `pflag.Value`, and in JSON, YAML or any other format that goes through
+// `encoding.TextUnmarshaler`.
+//
+// Example usage:
+//
+//	var minSize sizes.Size
+//	cmd.Flags().Var(&minSize, "min-size", "smallest file to match, such as 1.5GB")
+//	// --min-size 1.5GB sets minSize to 1610612736
+//
+//	var config struct {
+//		MinSize sizes.Size `json:"min_size"`
+//	}
+//	json.Unmarshal([]byte(`{"min_size": "1.5GB"}`), &config)
+type Size int64
+
+// Bytes returns s as a number of bytes.
+func (s Size) Bytes() int64 {
+	return int64(s)
+}
+
+// String formats s with `formatters.FormatSize`, such as `1.50 GB`.
+func (s Size) String() string {
+	return formatters.FormatSize(int64(s))
+}
+
+// Set reads s from a size such as `1.5GB`. Sizes `utils.ConvertStringSizeToBytes` accepts are read
+// the same way, so `1 kb` is 1024 bytes, other sizes, such as `512` or `1.5 GiB`, are read by `Parse`
+// with `types.SizeSystems.IEC`.
+func (s *Size) Set(value string) error {
+	size, err := utils.ConvertStringSizeToBytes(value)
+	if err != nil {
+		if size, err = Parse(value, types.SizeSystems.IEC); err != nil {
+			return err
+		}
+	}
+	*s = Size(size)
+	return nil
+}
+
+// Type names the value in flag usage.
+func (s *Size) Type() string {
+	return "size"
+}
+
+// MarshalText writes s as `String` does when that reads back to exactly s, such as `1.50 GB`, and as
+// a number of bytes, such as `1610612737`, otherwise, so that sizes survive a round trip. Sizes below
+// 1 KB are always written as a number of bytes.
+func (s Size) MarshalText() ([]byte, error) {
+	if formatted := s.String(); s >= 1024 {
+		var parsed Size
+		if err := parsed.Set(formatted); err == nil && parsed == s {
+			return []byte(formatted), nil
+		}
+	}
+	return strconv.AppendInt(nil, int64(s), 10), nil
+}
+
+// UnmarshalText reads s as `Set` does.
+func (s *Size) UnmarshalText(text []byte) error {
+	return s.Set(string(text))
+}
+
+// MarshalJSON writes s as a JSON string, as `MarshalText` does, such as `"1.50 GB"` or `"1610612737"`.
+func (s Size) MarshalJSON() ([]byte, error) {
+	text, err := s.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON reads s from a JSON string, as `Set` does, or from a JSON number of bytes.
+func (s *Size) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		return s.Set(value)
+	}
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	var size int64
+	if err := json.Unmarshal(data, &size); err != nil {
+		return fmt.Errorf("%w: %s", ErrSyntax, data)
+	}
+	if size < 0 {
+		return fmt.Errorf("%w: %s", ErrNegative, data)
+	}
+	*s = Size(size)
+	return nil
+}
+
+// unit returns how many bytes, multiplied by perBits, one unit stands for. perBits is 8 for bit units
+// and 1 for byte units.
+func unit(unitStr string, system types.SizeSystem) (multiplier, perBits uint64, err error) {
+	isBits := func(s string) (bool, bool) {
+		switch {
+		case s == "" || s == "B" || strings.EqualFold(s, "byte") || strings.EqualFold(s, "bytes"):
+			return false, true
+		case s == "b" || strings.EqualFold(s, "bit") || strings.EqualFold(s, "bits"):
+			return true, true
+		default:
+			return false, false
+		}
+	}
+
+	if bitUnit, ok := isBits(unitStr); ok {
+		return 1, perBitsOf(bitUnit), nil
+	}
+
+	exp := strings.IndexByte(prefixes, toLower(unitStr[0])) + 1
+	if exp == 0 {
+		return 0, 0, ErrUnit
+	}
+	rest := unitStr[1:]
+
+	base := uint64(1024)
+	if system == types.SizeSystems.SI {
+		base = 1000
+	}
+	if strings.HasPrefix(rest, "i") || strings.HasPrefix(rest, "I") {
+		base, rest = 1024, rest[1:]
+	}
+
+	bitUnit, ok := isBits(rest)
+	if !ok {
+		return 0, 0, ErrUnit
+	}
+
+	multiplier = 1
+	for i := 0; i < exp; i++ {
+		multiplier *= base
+	}
+	return multiplier, perBitsOf(bitUnit), nil
+}
+
+// perBitsOf returns the perBits of a bit or byte unit.
+func perBitsOf(bitUnit bool) uint64 {
+	if bitUnit {
+		return 8
+	}
+	return 1
+}
+
+// toLower lower cases an ASCII letter.
+func toLower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
@@ -0,0 +1,246 @@
+package sizes
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/ondrovic/common/types"
+	"github.com/spf13/pflag"
+)
+
+var (
+	_ pflag.Value              = (*Size)(nil)
+	_ encoding.TextMarshaler   = Size(0)
+	_ encoding.TextUnmarshaler = (*Size)(nil)
+	_ json.Marshaler           = Size(0)
+	_ json.Unmarshaler         = (*Size)(nil)
+	_ fmt.Stringer             = Size(0)
+)
+
+// TestParse tests the Parse func.
+func TestParse(t *testing.T) {
+	type InputStruct struct {
+		size   string
+		system types.SizeSystem
+	}
+
+	si, iec := types.SizeSystems.SI, types.SizeSystems.IEC
+	tests := []*types.TestLayout[InputStruct, int64]{
+		{Name: "Bytes", Input: InputStruct{size: "512", system: si}, Expected: 512},
+		{Name: "Bytes unit", Input: InputStruct{size: "512 B", system: si}, Expected: 512},
+		{Name: "Bytes word", Input: InputStruct{size: "2 Bytes", system: iec}, Expected: 2},
+		{Name: "SI kilobyte", Input: InputStruct{size: "1kB", system: si}, Expected: 1000},
+		{Name: "IEC kilobyte", Input: InputStruct{size: "1 kB", system: iec}, Expected: 1024},
+		{Name: "Empty system is IEC", Input: InputStruct{size: "1 KB", system: ""}, Expected: 1024},
+		{Name: "Kibibyte in SI", Input: InputStruct{size: "1 KiB", system: si}, Expected: 1024},
+		{Name: "Mebibyte", Input: InputStruct{size: "1.5MiB", system: si}, Expected: 1572864},
+		{Name: "Prefix only", Input: InputStruct{size: "3 M", system: si}, Expected: 3000000},
+		{Name: "Lower case prefix only", Input: InputStruct{size: "2k", system: iec}, Expected: 2048},
+		{Name: "SI gigabyte", Input: InputStruct{size: "1.5 GB", system: si}, Expected: 1500000000},
+		{Name: "IEC gigabyte", Input: InputStruct{size: "1.5 GB", system: iec}, Expected: 1610612736},
+		{Name: "Bits", Input: InputStruct{size: "16 b", system: si}, Expected: 2},
+		{Name: "Kilobit", Input: InputStruct{size: "1 kb", system: si}, Expected: 125},
+		{Name: "Megabit word", Input: InputStruct{size: "8 Mbit", system: si}, Expected: 1000000},
+		{Name: "Kibibit", Input: InputStruct{size: "1 Kibit", system: si}, Expected: 128},
+		{Name: "Rounded to nearest byte", Input: InputStruct{size: "0.0015 kB", system: si}, Expected: 2},
+		{Name: "Exact above 2^53", Input: InputStruct{size: "9007199254740993", system: si}, Expected: 9007199254740993},
+		{Name: "Largest size", Input: InputStruct{size: "9223372036854775807 B", system: si}, Expected: math.MaxInt64},
+		{Name: "Exabyte", Input: InputStruct{size: "7 EiB", system: si}, Expected: 7 << 60},
+		{Name: "Plus sign", Input: InputStruct{size: "+1 MB", system: si}, Expected: 1000000},
+		{Name: "Empty", Input: InputStruct{size: " ", system: si}, Err: ErrSyntax},
+		{Name: "No number", Input: InputStruct{size: "MB", system: si}, Err: ErrSyntax},
+		{Name: "NaN", Input: InputStruct{size: "NaN", system: si}, Err: ErrSyntax},
+		{Name: "Two dots", Input: InputStruct{size: "1.2.3 MB", system: si}, Err: ErrSyntax},
+		{Name: "Unknown unit", Input: InputStruct{size: "5 XB", system: si}, Err: ErrUnit},
+		{Name: "Unknown suffix", Input: InputStruct{size: "5 MiBs", system: si}, Err: ErrUnit},
+		{Name: "Negative", Input: InputStruct{size: "-1 kB", system: si}, Err: ErrNegative},
+		{Name: "Negative fraction", Input: InputStruct{size: "-0.5", system: si}, Err: ErrNegative},
+		{Name: "Overflow", Input: InputStruct{size: "8 EiB", system: si}, Err: ErrOverflow},
+		{Name: "Overflow fraction", Input: InputStruct{size: "9.3 EB", system: si}, Err: ErrOverflow},
+		{Name: "Overflow digits", Input: InputStruct{size: "99999999999999999999", system: si}, Err: ErrOverflow},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := Parse(test.Input.size, test.Input.system)
+			if !errors.Is(err, test.Err) {
+				t.Fatalf("Parse(%q, %v) - %v error = %v; want %v", test.Input.size, test.Input.system, test.Name, err, test.Err)
+			}
+			if result != test.Expected {
+				t.Errorf("Parse(%q, %v) - %v = %v; want %v", test.Input.size, test.Input.system, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestFormat tests the Format func.
+func TestFormat(t *testing.T) {
+	type InputStruct struct {
+		bytes  int64
+		system types.SizeSystem
+	}
+
+	si, iec := types.SizeSystems.SI, types.SizeSystems.IEC
+	tests := []*types.TestLayout[InputStruct, string]{
+		{Name: "Zero", Input: InputStruct{bytes: 0, system: si}, Expected: "0 B"},
+		{Name: "Bytes", Input: InputStruct{bytes: 999, system: si}, Expected: "999 B"},
+		{Name: "IEC bytes", Input: InputStruct{bytes: 1000, system: iec}, Expected: "1000 B"},
+		{Name: "SI kilobyte", Input: InputStruct{bytes: 1000, system: si}, Expected: "1.00 kB"},
+		{Name: "IEC kibibyte", Input: InputStruct{bytes: 1024, system: iec}, Expected: "1.00 KiB"},
+		{Name: "Empty system is IEC", Input: InputStruct{bytes: 1536, system: ""}, Expected: "1.50 KiB"},
+		{Name: "SI gigabyte", Input: InputStruct{bytes: 1610612736, system: si}, Expected: "1.61 GB"},
+		{Name: "IEC gibibyte", Input: InputStruct{bytes: 1610612736, system: iec}, Expected: "1.50 GiB"},
+		{Name: "Rounds up to next unit", Input: InputStruct{bytes: 999999, system: si}, Expected: "1.00 MB"},
+		{Name: "Negative", Input: InputStruct{bytes: -2048, system: iec}, Expected: "-2.00 KiB"},
+		{Name: "Largest size", Input: InputStruct{bytes: math.MaxInt64, system: iec}, Expected: "7.99 EiB"},
+		{Name: "Largest SI size", Input: InputStruct{bytes: math.MaxInt64, system: si}, Expected: "9.22 EB"},
+		{Name: "Smallest size", Input: InputStruct{bytes: math.MinInt64, system: iec}, Expected: "-7.99 EiB"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if result := Format(test.Input.bytes, test.Input.system); result != test.Expected {
+				t.Errorf("Format(%v, %v) - %v = %q; want %q", test.Input.bytes, test.Input.system, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestFormat_RoundTrip tests that parsing a formatted size gives back the size to within rounding.
+func TestFormat_RoundTrip(t *testing.T) {
+	sizes := []int64{0, 1, 999, 1000, 1023, 1024, 1025, 999999, 1 << 20, 1<<53 + 1, math.MaxInt64 - 1, math.MaxInt64}
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		sizes = append(sizes, random.Int63()>>random.Intn(63))
+	}
+
+	for _, system := range []types.SizeSystem{types.SizeSystems.SI, types.SizeSystems.IEC} {
+		for _, size := range sizes {
+			formatted := Format(size, system)
+			parsed, err := Parse(formatted, system)
+			if err != nil {
+				t.Fatalf("Parse(Format(%d, %v) = %q) error = %v", size, system, formatted, err)
+			}
+
+			diff := parsed - size
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff > size/100 {
+				t.Errorf("Parse(Format(%d, %v) = %q) = %d; off by %d", size, system, formatted, parsed, diff)
+			}
+		}
+	}
+}
+
+// TestSize_Set tests that Size reads sizes from flags.
+func TestSize_Set(t *testing.T) {
+	tests := []*types.TestLayout[string, Size]{
+		{Name: "Legacy unit", Input: "1.5GB", Expected: 1610612736},
+		{Name: "Legacy lower case unit", Input: "1 kb", Expected: 1024},
+		{Name: "Bytes", Input: "512", Expected: 512},
+		{Name: "IEC unit", Input: "2 MiB", Expected: 2 << 20},
+		{Name: "Formatted size", Input: "1.50 GB", Expected: 1610612736},
+		{Name: "Invalid", Input: "big", Expected: 0, Err: ErrSyntax},
+		{Name: "Negative", Input: "-1", Expected: 0, Err: ErrNegative},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var size Size
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			flags.Var(&size, "min-size", "smallest file to match")
+
+			// pflag does not wrap the error of Set, so only check that there is one.
+			err := flags.Parse([]string{"--min-size", test.Input})
+			if (err != nil) != (test.Err != nil) {
+				t.Fatalf("--min-size %q - %v error = %v; want %v", test.Input, test.Name, err, test.Err)
+			}
+			if err = size.Set(test.Input); !errors.Is(err, test.Err) {
+				t.Fatalf("Size.Set(%q) - %v error = %v; want %v", test.Input, test.Name, err, test.Err)
+			}
+			if size != test.Expected {
+				t.Errorf("Size.Set(%q) - %v = %d; want %d", test.Input, test.Name, size, test.Expected)
+			}
+			if flag := flags.Lookup("min-size"); flag.Value.Type() != "size" || flag.Value.String() != test.Expected.String() {
+				t.Errorf("--min-size %q - %v flag = %q of type %q; want %q", test.Input, test.Name, flag.Value.String(), flag.Value.Type(), test.Expected.String())
+			}
+		})
+	}
+}
+
+// TestSize_JSON tests that Size is written to and read from JSON.
+func TestSize_JSON(t *testing.T) {
+	type Config struct {
+		MinSize Size `json:"min_size"`
+	}
+
+	tests := []*types.TestLayout[string, Config]{
+		{Name: "String", Input: `{"min_size": "1.5GB"}`, Expected: Config{MinSize: 1610612736}},
+		{Name: "Number", Input: `{"min_size": 4096}`, Expected: Config{MinSize: 4096}},
+		{Name: "Null", Input: `{"min_size": null}`, Expected: Config{}},
+		{Name: "Invalid string", Input: `{"min_size": "huge"}`, Expected: Config{}, Err: ErrSyntax},
+		{Name: "Fractional number", Input: `{"min_size": 1.5}`, Expected: Config{}, Err: ErrSyntax},
+		{Name: "Negative number", Input: `{"min_size": -1}`, Expected: Config{}, Err: ErrNegative},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var config Config
+			if err := json.Unmarshal([]byte(test.Input), &config); !errors.Is(err, test.Err) {
+				t.Fatalf("json.Unmarshal(%s) - %v error = %v; want %v", test.Input, test.Name, err, test.Err)
+			}
+			if config != test.Expected {
+				t.Errorf("json.Unmarshal(%s) - %v = %+v; want %+v", test.Input, test.Name, config, test.Expected)
+			}
+		})
+	}
+
+	data, err := json.Marshal(Config{MinSize: 1610612736})
+	if err != nil || string(data) != `{"min_size":"1.50 GB"}` {
+		t.Errorf("json.Marshal() = %s, %v; want {\"min_size\":\"1.50 GB\"}", data, err)
+	}
+}
+
+// TestSize_Text tests that Size round trips exactly through its text and JSON forms.
+func TestSize_Text(t *testing.T) {
+	tests := []*types.TestLayout[Size, string]{
+		{Name: "Zero", Input: 0, Expected: "0"},
+		{Name: "Bytes", Input: 1000, Expected: "1000"},
+		{Name: "Just below a kilobyte", Input: 1023, Expected: "1023"},
+		{Name: "Kilobyte", Input: 1024, Expected: "1.00 KB"},
+		{Name: "Exact fraction", Input: 1536, Expected: "1.50 KB"},
+		{Name: "Gigabyte", Input: 1 << 30, Expected: "1.00 GB"},
+		{Name: "Inexact gigabytes", Input: 1610612737, Expected: "1610612737"},
+		{Name: "Inexact terabyte", Input: 1<<40 + 1, Expected: "1099511627777"},
+		{Name: "Largest size", Input: math.MaxInt64, Expected: "9223372036854775807"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			text, err := test.Input.MarshalText()
+			if err != nil || string(text) != test.Expected {
+				t.Fatalf("Size(%d).MarshalText() - %v = %q, %v; want %q", test.Input, test.Name, text, err, test.Expected)
+			}
+
+			var parsed Size
+			if err := parsed.UnmarshalText(text); err != nil || parsed != test.Input {
+				t.Errorf("Size.UnmarshalText(%q) - %v = %d, %v; want %d", text, test.Name, parsed, err, test.Input)
+			}
+
+			data, err := json.Marshal(test.Input)
+			if err != nil {
+				t.Fatalf("json.Marshal(%d) - %v error = %v", test.Input, test.Name, err)
+			}
+			parsed = 0
+			if err := json.Unmarshal(data, &parsed); err != nil || parsed != test.Input {
+				t.Errorf("json.Unmarshal(%s) - %v = %d, %v; want %d", data, test.Name, parsed, err, test.Input)
+			}
+		})
+	}
+}
@@ -0,0 +1,345 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils"
+	"github.com/ondrovic/common/utils/formatters"
+	"github.com/ondrovic/common/utils/results"
+	"github.com/pterm/pterm"
+)
+
+// Version is the snapshot format written by `Save` and accepted by `Load`.
+const Version = 1
+
+// The ChangeRow struct is a single rendered row of the changes table.
+type ChangeRow struct {
+	Change string
+	Path   string
+	Before string
+	Size   int64
+	Delta  string
+}
+
+// Take records the state of every file and directory below root. Errors for individual paths, such as
+// unreadable directories, broken symlinks or symlink loops, do not stop the snapshot, they are
+// collected and returned alongside it in the same way as `scanner.Collect`.
+//
+// Example usage:
+//
+//	before, _ := snapshot.Take("/media", types.SnapshotOptions{Hash: true})
+//	// ... cleanup runs ...
+//	after, _ := snapshot.Take("/media", types.SnapshotOptions{Hash: true})
+//	snapshot.RenderResults(snapshot.Diff(before, after))
+func Take(root string, opts types.SnapshotOptions) (types.Snapshot, []types.ScanError) {
+	if opts.Ops == nil {
+		opts.Ops = &types.RealDirOps{}
+	}
+
+	t := &take{
+		opts:     opts,
+		snapshot: types.Snapshot{Version: Version, Root: root, Taken: time.Now(), Entries: []types.SnapshotEntry{}},
+		errors:   []types.ScanError{},
+	}
+
+	info, err := opts.Ops.Stat(root)
+	if err != nil {
+		t.addError(root, err)
+		return t.snapshot, t.errors
+	}
+	if !info.IsDir() {
+		t.addError(root, fmt.Errorf("%s is not a directory", root))
+		return t.snapshot, t.errors
+	}
+
+	var ancestors []os.FileInfo
+	if opts.Symlinks == types.SymlinkPolicies.Follow {
+		ancestors = []os.FileInfo{info}
+	}
+	t.walk(root, "", ancestors)
+
+	sort.Slice(t.snapshot.Entries, func(i, j int) bool {
+		return t.snapshot.Entries[i].Path < t.snapshot.Entries[j].Path
+	})
+
+	return t.snapshot, t.errors
+}
+
+// Diff compares two snapshots of the same tree and returns every entry that was added, removed or
+// changed, sorted by path. A file whose size changed is `Grown` or `Shrunk`. A file of the same size is
+// `Modified` when its hash differs, or, when either snapshot was taken without hashes, when its
+// modification time differs. Any entry whose type or permissions changed is `Modified`. Directories
+// are otherwise never reported as changed, since their modification time follows their content.
+func Diff(before, after types.Snapshot) []types.SnapshotChange {
+	old := make(map[string]types.SnapshotEntry, len(before.Entries))
+	for _, entry := range before.Entries {
+		old[entry.Path] = entry
+	}
+
+	changes := []types.SnapshotChange{}
+	for _, entry := range after.Entries {
+		previous, ok := old[entry.Path]
+		if !ok {
+			changes = append(changes, newChange(types.ChangeTypes.Added, types.SnapshotEntry{}, entry))
+			continue
+		}
+		delete(old, entry.Path)
+
+		if change, changed := compare(previous, entry); changed {
+			changes = append(changes, newChange(change, previous, entry))
+		}
+	}
+	for _, entry := range old {
+		changes = append(changes, newChange(types.ChangeTypes.Removed, entry, types.SnapshotEntry{}))
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes
+}
+
+// TotalDelta returns the net change in size across changes, negative when the tree shrunk.
+func TotalDelta(changes []types.SnapshotChange) int64 {
+	var total int64
+	for _, change := range changes {
+		total += change.Delta
+	}
+	return total
+}
+
+// Save writes snapshot to w as JSON.
+func Save(w io.Writer, snapshot types.Snapshot) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(snapshot); err != nil {
+		return fmt.Errorf("error writing snapshot: %w", err)
+	}
+	return nil
+}
+
+// Load reads a snapshot written by `Save` from r.
+func Load(r io.Reader) (types.Snapshot, error) {
+	var snapshot types.Snapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return types.Snapshot{}, fmt.Errorf("error reading snapshot: %w", err)
+	}
+	if snapshot.Version != Version {
+		return types.Snapshot{}, fmt.Errorf("unsupported snapshot version %d", snapshot.Version)
+	}
+	return snapshot, nil
+}
+
+// SaveFile writes snapshot to the file name on ops, replacing it if it exists. A nil ops uses
+// `types.RealDirOps`.
+func SaveFile(ops types.DirOps, name string, snapshot types.Snapshot) error {
+	if ops == nil {
+		ops = &types.RealDirOps{}
+	}
+
+	file, err := ops.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o666)
+	if err != nil {
+		return fmt.Errorf("error creating snapshot: %w", err)
+	}
+
+	if err := Save(file, snapshot); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadFile reads a snapshot written by `SaveFile` from the file name on ops. A nil ops uses
+// `types.RealDirOps`.
+func LoadFile(ops types.DirOps, name string) (types.Snapshot, error) {
+	if ops == nil {
+		ops = &types.RealDirOps{}
+	}
+
+	file, err := ops.Open(name)
+	if err != nil {
+		return types.Snapshot{}, fmt.Errorf("error opening snapshot: %w", err)
+	}
+	defer file.Close()
+
+	return Load(file)
+}
+
+// ToRows converts changes into rows for `results.GenericRenderResultsTableInterface`. Size holds the
+// size after the change, or before it for removed entries.
+func ToRows(changes []types.SnapshotChange) []ChangeRow {
+	rows := make([]ChangeRow, 0, len(changes))
+	for _, change := range changes {
+		row := ChangeRow{
+			Change: string(change.Change),
+			Path:   change.Path,
+			Size:   change.After.Size,
+			Delta:  formatDelta(change.Delta),
+		}
+		if change.Change == types.ChangeTypes.Removed {
+			row.Size = change.Before.Size
+		}
+		if change.Change != types.ChangeTypes.Added && change.Change != types.ChangeTypes.Removed {
+			row.Before = formatters.FormatSize(change.Before.Size)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// RenderResults renders changes as a results table with the net change in size in the footer.
+func RenderResults(changes []types.SnapshotChange) {
+	if len(changes) == 0 {
+		pterm.Println("No changes found")
+		return
+	}
+
+	results.GenericRenderResultsTableInterface(ToRows(changes), map[string]interface{}{
+		"Change": "Total",
+		"Delta":  formatDelta(TotalDelta(changes)),
+	})
+}
+
+// take holds the state of a running Take.
+type take struct {
+	opts     types.SnapshotOptions
+	snapshot types.Snapshot
+	errors   []types.ScanError
+}
+
+// walk records the entries of dir, whose path relative to the root is rel. ancestors is only tracked
+// when symlinks are followed, to detect loops.
+func (t *take) walk(dir, rel string, ancestors []os.FileInfo) {
+	entries, err := t.opts.Ops.ReadDir(dir)
+	if err != nil {
+		t.addError(dir, err)
+		return
+	}
+
+	for _, entry := range entries {
+		name := filepath.Join(dir, entry.Name())
+		entryRel := path.Join(rel, entry.Name())
+		if t.opts.Ignore != nil && t.opts.Ignore.Match(name, entry.IsDir()) {
+			continue
+		}
+
+		var info os.FileInfo
+		if entry.Type()&os.ModeSymlink != 0 {
+			info, err = utils.ResolveSymlink(name, t.opts.Ops, t.opts.Symlinks)
+			if err == nil && info != nil && info.IsDir() && utils.IsSymlinkLoop(ancestors, info) {
+				err = fmt.Errorf("%w: %s", utils.ErrSymlinkLoop, name)
+			}
+		} else {
+			info, err = entry.Info()
+		}
+		if err != nil {
+			t.addError(name, err)
+			continue
+		}
+		if info == nil {
+			continue
+		}
+
+		record := types.SnapshotEntry{
+			Path:    entryRel,
+			ModTime: info.ModTime(),
+			Mode:    info.Mode(),
+		}
+		if !info.IsDir() {
+			record.Size = info.Size()
+		}
+		if t.opts.Hash && info.Mode().IsRegular() {
+			if record.Hash, err = hashFile(t.opts.Ops, name); err != nil {
+				t.addError(name, err)
+			}
+		}
+		t.snapshot.Entries = append(t.snapshot.Entries, record)
+
+		if info.IsDir() {
+			var childAncestors []os.FileInfo
+			if t.opts.Symlinks == types.SymlinkPolicies.Follow {
+				childAncestors = append(append([]os.FileInfo(nil), ancestors...), info)
+			}
+			t.walk(name, entryRel, childAncestors)
+		}
+	}
+}
+
+// addError records a per-path error.
+func (t *take) addError(path string, err error) {
+	t.errors = append(t.errors, types.ScanError{Path: path, Err: err})
+}
+
+// compare classifies how after differs from before, it returns false when the entry is unchanged.
+func compare(before, after types.SnapshotEntry) (types.ChangeType, bool) {
+	if before.Mode != after.Mode {
+		return types.ChangeTypes.Modified, true
+	}
+	if after.Mode.IsDir() {
+		return "", false
+	}
+
+	switch {
+	case after.Size > before.Size:
+		return types.ChangeTypes.Grown, true
+	case after.Size < before.Size:
+		return types.ChangeTypes.Shrunk, true
+	case before.Hash != "" && after.Hash != "":
+		return types.ChangeTypes.Modified, before.Hash != after.Hash
+	default:
+		return types.ChangeTypes.Modified, !before.ModTime.Equal(after.ModTime)
+	}
+}
+
+// newChange builds a change between before and after.
+func newChange(change types.ChangeType, before, after types.SnapshotEntry) types.SnapshotChange {
+	p := after.Path
+	if p == "" {
+		p = before.Path
+	}
+	return types.SnapshotChange{
+		Path:   p,
+		Change: change,
+		Before: before,
+		After:  after,
+		Delta:  after.Size - before.Size,
+	}
+}
+
+// formatDelta formats a change in size with its sign.
+func formatDelta(delta int64) string {
+	switch {
+	case delta > 0:
+		return "+" + formatters.FormatSize(delta)
+	case delta < 0:
+		return "-" + formatters.FormatSize(-delta)
+	default:
+		return formatters.FormatSize(0)
+	}
+}
+
+// hashFile returns the hex encoded SHA-256 of the content of name.
+func hashFile(ops types.DirOps, name string) (string, error) {
+	file, err := ops.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("error hashing %s: %w", name, err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,325 @@
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils"
+	"github.com/ondrovic/common/utils/ignore"
+	"github.com/ondrovic/common/utils/memfs"
+)
+
+// fixedTime is the modification time of every entry in the test filesystem.
+var fixedTime = time.Date(2024, 6, 7, 8, 9, 10, 0, time.UTC)
+
+// CreateTestFS creates an in-memory tree for testing.
+//
+//	/data
+//	├── movies/big.mp4 (2048 B)
+//	├── movies/small.mkv (512 B)
+//	├── notes.txt ("hello")
+//	└── tmp/
+func CreateTestFS(t *testing.T) *memfs.MemDirOps {
+	t.Helper()
+	ops := memfs.New()
+	ops.Now = func() time.Time { return fixedTime }
+	files := map[string][]byte{
+		"/data/movies/big.mp4":   make([]byte, 2048),
+		"/data/movies/small.mkv": make([]byte, 512),
+		"/data/notes.txt":        []byte("hello"),
+	}
+	for name, data := range files {
+		if err := ops.WriteFile(name, data, 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	if err := ops.MkdirAll("/data/tmp", 0o755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	return ops
+}
+
+// entryPaths returns the paths of entries.
+func entryPaths(entries []types.SnapshotEntry) []string {
+	paths := []string{}
+	for _, entry := range entries {
+		paths = append(paths, entry.Path)
+	}
+	return paths
+}
+
+// TestTake tests the Take func.
+func TestTake(t *testing.T) {
+	ops := CreateTestFS(t)
+	snapshot, scanErrors := Take("/data", types.SnapshotOptions{Ops: ops, Hash: true})
+	if len(scanErrors) != 0 {
+		t.Fatalf("Take() errors = %v; want none", scanErrors)
+	}
+
+	expected := []string{"movies", "movies/big.mp4", "movies/small.mkv", "notes.txt", "tmp"}
+	if got := entryPaths(snapshot.Entries); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Take() paths = %v; want %v", got, expected)
+	}
+	if snapshot.Version != Version || snapshot.Root != "/data" {
+		t.Errorf("Take() = version %d root %q; want version %d root %q", snapshot.Version, snapshot.Root, Version, "/data")
+	}
+
+	notes := snapshot.Entries[3]
+	want := types.SnapshotEntry{
+		Path:    "notes.txt",
+		Size:    5,
+		ModTime: fixedTime,
+		Mode:    0o644,
+		Hash:    "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+	}
+	if !reflect.DeepEqual(notes, want) {
+		t.Errorf("Take() entry = %+v; want %+v", notes, want)
+	}
+	if movies := snapshot.Entries[0]; movies.Size != 0 || movies.Hash != "" || !movies.Mode.IsDir() {
+		t.Errorf("Take() directory entry = %+v; want a directory with no size or hash", movies)
+	}
+}
+
+// TestTake_Options tests that Take honours the ignore rules and symlink policy.
+func TestTake_Options(t *testing.T) {
+	type ExpectedResults struct {
+		paths  []string
+		errors []error
+	}
+
+	tests := []*types.TestLayout[types.SymlinkPolicy, ExpectedResults]{
+		{
+			Name:     "Skip",
+			Input:    types.SymlinkPolicies.Skip,
+			Expected: ExpectedResults{paths: []string{"movies", "movies/big.mp4", "movies/small.mkv", "notes.txt"}, errors: []error{utils.ErrBrokenLink}},
+		},
+		{
+			Name:  "File",
+			Input: types.SymlinkPolicies.File,
+			Expected: ExpectedResults{
+				paths:  []string{"loop", "movies", "movies/big.mp4", "movies/small.mkv", "notes.txt", "videos"},
+				errors: []error{utils.ErrBrokenLink},
+			},
+		},
+		{
+			Name:  "Follow",
+			Input: types.SymlinkPolicies.Follow,
+			Expected: ExpectedResults{
+				paths:  []string{"movies", "movies/big.mp4", "movies/small.mkv", "notes.txt", "videos", "videos/big.mp4", "videos/small.mkv"},
+				errors: []error{utils.ErrBrokenLink, utils.ErrSymlinkLoop},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ops := CreateTestFS(t)
+			links := map[string]string{"/data/videos": "movies", "/data/loop": "/data", "/data/broken": "/missing"}
+			for name, target := range links {
+				if err := ops.Symlink(target, name); err != nil {
+					t.Fatalf("failed to create symlink: %v", err)
+				}
+			}
+			matcher, err := ignore.New("/data", "tmp/")
+			if err != nil {
+				t.Fatalf("ignore.New() error = %v", err)
+			}
+
+			snapshot, scanErrors := Take("/data", types.SnapshotOptions{Ops: ops, Ignore: matcher, Symlinks: test.Input})
+			if got := entryPaths(snapshot.Entries); !reflect.DeepEqual(got, test.Expected.paths) {
+				t.Errorf("Take() - %v paths = %v; want %v", test.Name, got, test.Expected.paths)
+			}
+			if len(scanErrors) != len(test.Expected.errors) {
+				t.Fatalf("Take() - %v errors = %v; want %v", test.Name, scanErrors, test.Expected.errors)
+			}
+			for i, scanError := range scanErrors {
+				if !errors.Is(scanError.Err, test.Expected.errors[i]) {
+					t.Errorf("Take() - %v error = %v; want %v", test.Name, scanError.Err, test.Expected.errors[i])
+				}
+			}
+		})
+	}
+}
+
+// TestTake_Errors tests Take error handling.
+func TestTake_Errors(t *testing.T) {
+	tests := []*types.TestLayout[string, string]{
+		{Name: "Missing root", Input: "/missing", Expected: "/missing"},
+		{Name: "Root is a file", Input: "/data/notes.txt", Expected: "/data/notes.txt"},
+		{Name: "Unreadable directory", Input: "/data", Expected: "/data/movies"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ops := CreateTestFS(t)
+			ops.InjectError(memfs.OpReadDir, "/data/movies", errors.New("simulated ReadDir error"))
+
+			_, scanErrors := Take(test.Input, types.SnapshotOptions{Ops: ops})
+			if len(scanErrors) != 1 || filepath.ToSlash(scanErrors[0].Path) != test.Expected {
+				t.Errorf("Take(%q) - %v errors = %v; want one for %s", test.Input, test.Name, scanErrors, test.Expected)
+			}
+		})
+	}
+}
+
+// TestDiff tests that each kind of change is classified.
+func TestDiff(t *testing.T) {
+	type ExpectedResults struct {
+		changes map[string]types.ChangeType
+		delta   int64
+	}
+
+	later := fixedTime.Add(time.Hour)
+	tests := []*types.TestLayout[string, ExpectedResults]{
+		{Name: "No changes", Input: "none", Expected: ExpectedResults{changes: map[string]types.ChangeType{}}},
+		{
+			Name:     "Added and removed",
+			Input:    "add-remove",
+			Expected: ExpectedResults{changes: map[string]types.ChangeType{"new.txt": types.ChangeTypes.Added, "tmp": types.ChangeTypes.Removed}, delta: 3},
+		},
+		{
+			Name:     "Grown and shrunk",
+			Input:    "resize",
+			Expected: ExpectedResults{changes: map[string]types.ChangeType{"movies/big.mp4": types.ChangeTypes.Grown, "movies/small.mkv": types.ChangeTypes.Shrunk}, delta: 1024 - 256},
+		},
+		{Name: "Touched without hashes", Input: "touch", Expected: ExpectedResults{changes: map[string]types.ChangeType{"notes.txt": types.ChangeTypes.Modified}}},
+		{Name: "Touched with hashes", Input: "touch-hash", Expected: ExpectedResults{changes: map[string]types.ChangeType{}}},
+		{Name: "Rewritten with hashes", Input: "rewrite-hash", Expected: ExpectedResults{changes: map[string]types.ChangeType{"notes.txt": types.ChangeTypes.Modified}}},
+		{Name: "Permissions changed", Input: "chmod", Expected: ExpectedResults{changes: map[string]types.ChangeType{"notes.txt": types.ChangeTypes.Modified}}},
+		{Name: "Directory contents changed", Input: "dir-mtime", Expected: ExpectedResults{changes: map[string]types.ChangeType{}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ops := CreateTestFS(t)
+			opts := types.SnapshotOptions{Ops: ops, Hash: strings.HasSuffix(test.Input, "-hash")}
+			before, _ := Take("/data", opts)
+
+			var err error
+			switch test.Input {
+			case "add-remove":
+				if err = ops.WriteFile("/data/new.txt", []byte("new"), 0o644); err == nil {
+					err = ops.Remove("/data/tmp")
+				}
+			case "resize":
+				if err = ops.WriteFile("/data/movies/big.mp4", make([]byte, 3072), 0o644); err == nil {
+					err = ops.WriteFile("/data/movies/small.mkv", make([]byte, 256), 0o644)
+				}
+			case "touch", "touch-hash":
+				err = ops.Chtimes("/data/notes.txt", later, later)
+			case "rewrite-hash":
+				err = ops.WriteFile("/data/notes.txt", []byte("world"), 0o644)
+			case "chmod":
+				err = ops.Chmod("/data/notes.txt", 0o600)
+			case "dir-mtime":
+				err = ops.Chtimes("/data/movies", later, later)
+			}
+			if err != nil {
+				t.Fatalf("%v mutation error = %v", test.Name, err)
+			}
+
+			after, _ := Take("/data", opts)
+			changes := Diff(before, after)
+
+			got := map[string]types.ChangeType{}
+			for _, change := range changes {
+				got[change.Path] = change.Change
+			}
+			if !reflect.DeepEqual(got, test.Expected.changes) {
+				t.Errorf("Diff() - %v = %v; want %v", test.Name, got, test.Expected.changes)
+			}
+			if delta := TotalDelta(changes); delta != test.Expected.delta {
+				t.Errorf("TotalDelta() - %v = %d; want %d", test.Name, delta, test.Expected.delta)
+			}
+		})
+	}
+}
+
+// TestSaveLoad tests that a snapshot survives a round trip through Save and Load.
+func TestSaveLoad(t *testing.T) {
+	snapshot, _ := Take("/data", types.SnapshotOptions{Ops: CreateTestFS(t), Hash: true})
+	snapshot.Taken = fixedTime
+
+	var buf bytes.Buffer
+	if err := Save(&buf, snapshot); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, snapshot) {
+		t.Errorf("Load() = %+v; want %+v", loaded, snapshot)
+	}
+
+	name := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := SaveFile(nil, name, snapshot); err != nil {
+		t.Fatalf("SaveFile() error = %v", err)
+	}
+	if loaded, err = LoadFile(nil, name); err != nil || len(Diff(snapshot, loaded)) != 0 {
+		t.Errorf("LoadFile() = %+v, %v; want %+v", loaded, err, snapshot)
+	}
+
+	// A snapshot replacing a longer file on an in-memory filesystem.
+	ops := memfs.New()
+	if err := ops.WriteFile("/snapshot.json", bytes.Repeat([]byte(" "), buf.Cap()+1024), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := SaveFile(ops, "/snapshot.json", snapshot); err != nil {
+		t.Fatalf("SaveFile() in memory error = %v", err)
+	}
+	buf.Reset()
+	_ = Save(&buf, snapshot)
+	if data, err := ops.ReadFile("/snapshot.json"); err != nil || !bytes.Equal(data, buf.Bytes()) {
+		t.Errorf("SaveFile() in memory wrote %d bytes, %v; want %d", len(data), err, buf.Len())
+	}
+	if loaded, err = LoadFile(ops, "/snapshot.json"); err != nil || !reflect.DeepEqual(loaded, snapshot) {
+		t.Errorf("LoadFile() in memory = %+v, %v; want %+v", loaded, err, snapshot)
+	}
+}
+
+// TestLoad_Errors tests Load error handling.
+func TestLoad_Errors(t *testing.T) {
+	tests := []*types.TestLayout[string, string]{
+		{Name: "Invalid JSON", Input: "{", Expected: "error reading snapshot"},
+		{Name: "Unsupported version", Input: `{"version": 99}`, Expected: "unsupported snapshot version 99"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if _, err := Load(strings.NewReader(test.Input)); err == nil || !strings.Contains(err.Error(), test.Expected) {
+				t.Errorf("Load(%q) - %v error = %v; want error containing %v", test.Input, test.Name, err, test.Expected)
+			}
+		})
+	}
+
+	if _, err := LoadFile(memfs.New(), "/missing.json"); err == nil || !strings.Contains(err.Error(), "error opening snapshot") {
+		t.Errorf("LoadFile() missing file error = %v", err)
+	}
+}
+
+// TestToRows tests the ToRows func.
+func TestToRows(t *testing.T) {
+	changes := []types.SnapshotChange{
+		{Path: "a.mp4", Change: types.ChangeTypes.Added, After: types.SnapshotEntry{Size: 2048}, Delta: 2048},
+		{Path: "b.mp4", Change: types.ChangeTypes.Removed, Before: types.SnapshotEntry{Size: 1024}, Delta: -1024},
+		{Path: "c.mp4", Change: types.ChangeTypes.Grown, Before: types.SnapshotEntry{Size: 1024}, After: types.SnapshotEntry{Size: 2048}, Delta: 1024},
+		{Path: "d.txt", Change: types.ChangeTypes.Modified, Before: types.SnapshotEntry{Size: 5}, After: types.SnapshotEntry{Size: 5}},
+	}
+
+	expected := []ChangeRow{
+		{Change: "Added", Path: "a.mp4", Size: 2048, Delta: "+2.00 KB"},
+		{Change: "Removed", Path: "b.mp4", Size: 1024, Delta: "-1.00 KB"},
+		{Change: "Grown", Path: "c.mp4", Before: "1.00 KB", Size: 2048, Delta: "+1.00 KB"},
+		{Change: "Modified", Path: "d.txt", Before: "5.00 B", Size: 5, Delta: "0 B"},
+	}
+	if rows := ToRows(changes); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("ToRows() = %+v; want %+v", rows, expected)
+	}
+}
@@ -0,0 +1,248 @@
+package timefilter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils"
+	"github.com/ondrovic/common/utils/operators"
+)
+
+var (
+	// ErrDuration is returned for a duration that cannot be read.
+	ErrDuration = errors.New("invalid duration")
+	// ErrValue is returned for a value that is neither a duration nor a date.
+	ErrValue = errors.New("invalid age or date")
+	// ErrUnsupported is returned when a timestamp is not available for a file, such as the ctime of a
+	// file on a filesystem that does not record it.
+	ErrUnsupported = errors.New("timestamp not available")
+)
+
+// durationUnits are the units `ParseDuration` reads. Years and months have a fixed length, so ages do
+// not depend on the calendar.
+var durationUnits = map[string]time.Duration{
+	"y":   365 * 24 * time.Hour,
+	"mo":  30 * 24 * time.Hour,
+	"w":   7 * 24 * time.Hour,
+	"d":   24 * time.Hour,
+	"h":   time.Hour,
+	"m":   time.Minute,
+	"min": time.Minute,
+	"s":   time.Second,
+}
+
+// dateLayouts are the layouts `Parse` reads absolute times with, in local time unless they hold a zone.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+// dayLayout is the layout of a date without a time, which stands for the whole day.
+const dayLayout = "2006-01-02"
+
+// ParseDuration reads a human duration, a sequence of numbers each followed by a unit, such as `30d`,
+// `6w`, `1y2mo` or `1h30m`. The units are `y` (365 days), `mo` (30 days), `w`, `d`, `h`, `m` or `min`
+// and `s`. Numbers may have a fraction, such as `1.5d`.
+func ParseDuration(duration string) (time.Duration, error) {
+	s := strings.ToLower(strings.TrimSpace(duration))
+	if s == "" {
+		return 0, fmt.Errorf("%w: %q", ErrDuration, duration)
+	}
+
+	var total time.Duration
+	for s != "" {
+		i := strings.IndexFunc(s, func(r rune) bool { return r != '.' && !unicode.IsDigit(r) })
+		if i <= 0 {
+			return 0, fmt.Errorf("%w: %q", ErrDuration, duration)
+		}
+		n, err := strconv.ParseFloat(s[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrDuration, duration)
+		}
+		s = s[i:]
+
+		j := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsLetter(r) })
+		if j < 0 {
+			j = len(s)
+		}
+		unit, ok := durationUnits[s[:j]]
+		if !ok {
+			return 0, fmt.Errorf("%w: unknown unit %q in %q", ErrDuration, s[:j], duration)
+		}
+		s = s[j:]
+
+		part := n * float64(unit)
+		if part >= math.MaxInt64 || total > math.MaxInt64-time.Duration(part) {
+			return 0, fmt.Errorf("%w: %q is too long", ErrDuration, duration)
+		}
+		total += time.Duration(part)
+	}
+	return total, nil
+}
+
+// Parse builds a `types.TimeFilter` from strings, such as `mtime`, `<` and `30d` for files modified in
+// the last 30 days. field is read by `utils.ToTimeField` and operator by `utils.ToOperatorType`. value
+// is a duration read by `ParseDuration`, an age, or an absolute time such as `2024-05-06` or
+// `2024-05-06T15:04:05Z`. A date without a time stands for the whole day, so `=` matches any time that
+// day and `>` matches from the next day on. The range operators take both bounds in value, separated by
+// `..`, such as `7d..30d`.
+//
+// Example usage:
+//
+//	filter, err := timefilter.Parse("atime", ">", "1y2mo")
+//	matched, err := timefilter.Match(filter, info)
+func Parse(field, operator, value string) (types.TimeFilter, error) {
+	filter := types.TimeFilter{Field: utils.ToTimeField(field), Operator: utils.ToOperatorType(operator)}
+	if filter.Field == "" {
+		return types.TimeFilter{}, fmt.Errorf("unknown time field %q", field)
+	}
+	if filter.Operator == "" {
+		return types.TimeFilter{}, fmt.Errorf("unknown operator %q", operator)
+	}
+
+	lowerStr, upperStr := value, value
+	if operators.IsRange(filter.Operator) {
+		var ok bool
+		if lowerStr, upperStr, ok = strings.Cut(value, ".."); !ok {
+			return types.TimeFilter{}, fmt.Errorf("%s needs a range such as 7d..30d, got %q", filter.Operator, value)
+		}
+	}
+
+	if age, err := ParseDuration(lowerStr); err == nil {
+		upperAge, err := ParseDuration(upperStr)
+		if err != nil {
+			return types.TimeFilter{}, fmt.Errorf("%w: %q", ErrValue, upperStr)
+		}
+		filter.Age, filter.UpperAge = age, upperAge
+		return filter, Validate(filter)
+	}
+
+	lower, lowerDay, err := parseTime(lowerStr)
+	if err != nil {
+		return types.TimeFilter{}, err
+	}
+	upper, upperDay, err := parseTime(upperStr)
+	if err != nil {
+		return types.TimeFilter{}, err
+	}
+	filter.Time, filter.UpperTime = lower, upper
+	if upperDay {
+		// The upper bound of a day is its last instant.
+		filter.UpperTime = upper.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	if lowerDay && !operators.IsRange(filter.Operator) {
+		switch filter.Operator {
+		case types.OperatorTypes.EqualTo:
+			filter.Operator = types.OperatorTypes.Between
+		case types.OperatorTypes.NotEqualTo:
+			filter.Operator = types.OperatorTypes.NotBetween
+		case types.OperatorTypes.LessThanEqualTo, types.OperatorTypes.GreaterThan:
+			filter.Time = filter.UpperTime
+		}
+	}
+	return filter, Validate(filter)
+}
+
+// FileTime returns the timestamp field of info. The ctime and atime come from the platform specific
+// `Sys` of info, or from `ChangeTime` and `AccessTime` methods such as those of `memfs` infos. It
+// returns an error wrapping `ErrUnsupported` when the timestamp is not available. On Windows, which
+// does not record a ctime, `types.TimeFields.Changed` is the creation time.
+func FileTime(info os.FileInfo, field types.TimeField) (time.Time, error) {
+	switch field {
+	case "", types.TimeFields.Modified:
+		return info.ModTime(), nil
+	case types.TimeFields.Accessed:
+		if timed, ok := info.(interface{ AccessTime() time.Time }); ok {
+			return timed.AccessTime(), nil
+		}
+		if atime, _, ok := statTimes(info); ok {
+			return atime, nil
+		}
+	case types.TimeFields.Changed:
+		if timed, ok := info.(interface{ ChangeTime() time.Time }); ok {
+			return timed.ChangeTime(), nil
+		}
+		if _, ctime, ok := statTimes(info); ok {
+			return ctime, nil
+		}
+	default:
+		return time.Time{}, fmt.Errorf("unknown time field %q", field)
+	}
+	return time.Time{}, fmt.Errorf("%w: %s of %s", ErrUnsupported, field, info.Name())
+}
+
+// Match reports whether info passes filter. Ages are measured from `filter.Now`, or the current time
+// when it is nil.
+func Match(filter types.TimeFilter, info os.FileInfo) (bool, error) {
+	if err := Validate(filter); err != nil {
+		return false, err
+	}
+	fileTime, err := FileTime(info, filter.Field)
+	if err != nil {
+		return false, err
+	}
+
+	if !filter.Time.IsZero() {
+		return operators.MatchFunc(filter.Operator, time.Time.Compare, filter.Time, fileTime, upperBounds(filter.Operator, filter.UpperTime)...)
+	}
+
+	now := time.Now
+	if filter.Now != nil {
+		now = filter.Now
+	}
+	age := now().Sub(fileTime)
+	return operators.Match(filter.Operator, filter.Age, age, upperBounds(filter.Operator, filter.UpperAge)...)
+}
+
+// Validate checks that filter reads a known timestamp and, for the range operators, that its lower
+// bound is not above its upper bound.
+func Validate(filter types.TimeFilter) error {
+	switch filter.Field {
+	case "", types.TimeFields.Modified, types.TimeFields.Accessed, types.TimeFields.Changed:
+	default:
+		return fmt.Errorf("unknown time field %q", filter.Field)
+	}
+	if !operators.IsRange(filter.Operator) {
+		return nil
+	}
+	if filter.Time.IsZero() && filter.UpperAge < filter.Age {
+		return fmt.Errorf("lower bound %s is greater than upper bound %s", filter.Age, filter.UpperAge)
+	}
+	if !filter.Time.IsZero() && filter.UpperTime.Before(filter.Time) {
+		return fmt.Errorf("lower bound %s is after upper bound %s", filter.Time.Format(time.RFC3339), filter.UpperTime.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// upperBounds returns upper as the upper bound of the range operators and no bound for the others.
+func upperBounds[T any](operator types.OperatorType, upper T) []T {
+	if operators.IsRange(operator) {
+		return []T{upper}
+	}
+	return nil
+}
+
+// parseTime reads an absolute time and reports whether it is a date without a time.
+func parseTime(value string) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if day, err := time.ParseInLocation(dayLayout, value, time.Local); err == nil {
+		return day, true, nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, false, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("%w: %q", ErrValue, value)
+}
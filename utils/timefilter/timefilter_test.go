@@ -0,0 +1,178 @@
+package timefilter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils/memfs"
+)
+
+// TestParseDuration tests the ParseDuration func.
+func TestParseDuration(t *testing.T) {
+	day := 24 * time.Hour
+
+	tests := []*types.TestLayout[string, time.Duration]{
+		{Name: "Days", Input: "30d", Expected: 30 * day},
+		{Name: "Weeks", Input: "6w", Expected: 42 * day},
+		{Name: "Years and months", Input: "1y2mo", Expected: 425 * day},
+		{Name: "Hours and minutes", Input: "1h30m", Expected: 90 * time.Minute},
+		{Name: "Minutes word", Input: "45min", Expected: 45 * time.Minute},
+		{Name: "Fraction", Input: "1.5d", Expected: 36 * time.Hour},
+		{Name: "Upper case", Input: " 2D ", Expected: 2 * day},
+		{Name: "Empty", Input: "", Err: ErrDuration},
+		{Name: "Missing unit", Input: "30", Err: ErrDuration},
+		{Name: "Unknown unit", Input: "3q", Err: ErrDuration},
+		{Name: "Missing number", Input: "d", Err: ErrDuration},
+		{Name: "Negative", Input: "-1d", Err: ErrDuration},
+		{Name: "Date", Input: "2024-05-06", Err: ErrDuration},
+		{Name: "Too long", Input: "300y", Err: ErrDuration},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := ParseDuration(test.Input)
+			if !errors.Is(err, test.Err) {
+				t.Fatalf("ParseDuration(%q) - %v error = %v; want %v", test.Input, test.Name, err, test.Err)
+			}
+			if result != test.Expected {
+				t.Errorf("ParseDuration(%q) - %v = %v; want %v", test.Input, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestMatch tests the Parse and Match funcs against a fixed clock.
+func TestMatch(t *testing.T) {
+	now := time.Date(2024, 5, 6, 12, 0, 0, 0, time.Local)
+	ops := memfs.New()
+	ops.Now = func() time.Time { return now }
+	files := map[string]time.Time{
+		"/recent.txt":    now.Add(-2 * 24 * time.Hour),
+		"/month.txt":     now.AddDate(0, -1, 0),
+		"/old.txt":       now.AddDate(-2, 0, 0),
+		"/same-day.txt":  time.Date(2024, 5, 1, 23, 59, 0, 0, time.Local),
+		"/next-day.txt":  time.Date(2024, 5, 2, 0, 0, 0, 0, time.Local),
+		"/accessed.txt":  now.AddDate(-1, 0, 0),
+		"/untouched.txt": now.AddDate(-1, 0, 0),
+	}
+	for name, mtime := range files {
+		if err := ops.WriteFile(name, nil, 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if err := ops.Chtimes(name, mtime, mtime); err != nil {
+			t.Fatalf("failed to set times: %v", err)
+		}
+	}
+	if err := ops.Chtimes("/accessed.txt", now.Add(-time.Hour), time.Time{}); err != nil {
+		t.Fatalf("failed to set times: %v", err)
+	}
+
+	type InputStruct struct {
+		field    string
+		operator string
+		value    string
+		path     string
+	}
+
+	tests := []*types.TestLayout[InputStruct, bool]{
+		{Name: "Newer than 30 days", Input: InputStruct{field: "mtime", operator: "<", value: "30d", path: "/recent.txt"}, Expected: true},
+		{Name: "Not newer than 30 days", Input: InputStruct{field: "mtime", operator: "<", value: "30d", path: "/month.txt"}, Expected: false},
+		{Name: "Older than 1y2mo", Input: InputStruct{field: "mtime", operator: "gt", value: "1y2mo", path: "/old.txt"}, Expected: true},
+		{Name: "Not older than 1y2mo", Input: InputStruct{field: "mtime", operator: "gt", value: "1y2mo", path: "/month.txt"}, Expected: false},
+		{Name: "Age between", Input: InputStruct{field: "mtime", operator: "between", value: "1w..6w", path: "/month.txt"}, Expected: true},
+		{Name: "Age not between", Input: InputStruct{field: "mtime", operator: "not between", value: "1w..6w", path: "/recent.txt"}, Expected: true},
+		{Name: "Before date", Input: InputStruct{field: "modified", operator: "<", value: "2024-01-01", path: "/old.txt"}, Expected: true},
+		{Name: "Before date is exclusive", Input: InputStruct{field: "mtime", operator: "<", value: "2024-05-02", path: "/next-day.txt"}, Expected: false},
+		{Name: "Up to date includes the day", Input: InputStruct{field: "mtime", operator: "<=", value: "2024-05-01", path: "/same-day.txt"}, Expected: true},
+		{Name: "After date excludes the day", Input: InputStruct{field: "mtime", operator: ">", value: "2024-05-01", path: "/same-day.txt"}, Expected: false},
+		{Name: "After date", Input: InputStruct{field: "mtime", operator: ">", value: "2024-05-01", path: "/next-day.txt"}, Expected: true},
+		{Name: "On date", Input: InputStruct{field: "mtime", operator: "=", value: "2024-05-01", path: "/same-day.txt"}, Expected: true},
+		{Name: "Not on date", Input: InputStruct{field: "mtime", operator: "!=", value: "2024-05-01", path: "/next-day.txt"}, Expected: true},
+		{Name: "Date range", Input: InputStruct{field: "mtime", operator: "..", value: "2024-04-01..2024-05-01", path: "/same-day.txt"}, Expected: true},
+		{Name: "Exact time", Input: InputStruct{field: "mtime", operator: ">=", value: "2024-05-02T00:00:00", path: "/next-day.txt"}, Expected: true},
+		{Name: "Accessed recently", Input: InputStruct{field: "atime", operator: "<", value: "1d", path: "/accessed.txt"}, Expected: true},
+		{Name: "Not accessed recently", Input: InputStruct{field: "atime", operator: "<", value: "1d", path: "/untouched.txt"}, Expected: false},
+		{Name: "Changed by Chtimes", Input: InputStruct{field: "ctime", operator: "<=", value: "0s", path: "/old.txt"}, Expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			filter, err := Parse(test.Input.field, test.Input.operator, test.Input.value)
+			if err != nil {
+				t.Fatalf("Parse(%q, %q, %q) - %v error = %v", test.Input.field, test.Input.operator, test.Input.value, test.Name, err)
+			}
+			filter.Now = ops.Now
+
+			info, err := ops.Stat(test.Input.path)
+			if err != nil {
+				t.Fatalf("Stat(%q) error = %v", test.Input.path, err)
+			}
+			result, err := Match(filter, info)
+			if err != nil {
+				t.Fatalf("Match(%q) - %v error = %v", test.Input.path, test.Name, err)
+			}
+			if result != test.Expected {
+				t.Errorf("Match(%s %s %s, %q) - %v = %v; want %v", test.Input.field, test.Input.operator, test.Input.value, test.Input.path, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestParse_Errors tests that Parse rejects invalid filters.
+func TestParse_Errors(t *testing.T) {
+	type InputStruct struct {
+		field    string
+		operator string
+		value    string
+	}
+
+	tests := []*types.TestLayout[InputStruct, string]{
+		{Name: "Unknown field", Input: InputStruct{field: "btime", operator: "<", value: "1d"}, Expected: `unknown time field "btime"`},
+		{Name: "Unknown operator", Input: InputStruct{field: "mtime", operator: "~", value: "1d"}, Expected: `unknown operator "~"`},
+		{Name: "Invalid value", Input: InputStruct{field: "mtime", operator: "<", value: "yesterday"}, Expected: `invalid age or date: "yesterday"`},
+		{Name: "Missing range", Input: InputStruct{field: "mtime", operator: "between", value: "1d"}, Expected: `Between needs a range such as 7d..30d, got "1d"`},
+		{Name: "Mixed range", Input: InputStruct{field: "mtime", operator: "between", value: "1d..2024-01-01"}, Expected: `invalid age or date: "2024-01-01"`},
+		{Name: "Reversed ages", Input: InputStruct{field: "mtime", operator: "between", value: "6w..1w"}, Expected: "lower bound 1008h0m0s is greater than upper bound 168h0m0s"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := Parse(test.Input.field, test.Input.operator, test.Input.value)
+			if err == nil || err.Error() != test.Expected {
+				t.Errorf("Parse(%q, %q, %q) - %v error = %v; want %v", test.Input.field, test.Input.operator, test.Input.value, test.Name, err, test.Expected)
+			}
+		})
+	}
+}
+
+// TestFileTime tests that FileTime reads the timestamps of real files.
+func TestFileTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	atime, mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(path, atime, mtime); err != nil {
+		t.Fatalf("failed to set times: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat(%q) error = %v", path, err)
+	}
+
+	if result, err := FileTime(info, types.TimeFields.Modified); err != nil || !result.Equal(mtime) {
+		t.Errorf("FileTime(Modified) = %v, %v; want %v", result, err, mtime)
+	}
+	if result, err := FileTime(info, types.TimeFields.Accessed); errors.Is(err, ErrUnsupported) {
+		t.Skipf("atime not available: %v", err)
+	} else if err != nil || !result.Equal(atime) {
+		t.Errorf("FileTime(Accessed) = %v, %v; want %v", result, err, atime)
+	}
+	if result, err := FileTime(info, types.TimeFields.Changed); err != nil || result.IsZero() {
+		t.Errorf("FileTime(Changed) = %v, %v; want a ctime", result, err)
+	}
+}
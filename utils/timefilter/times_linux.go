@@ -0,0 +1,18 @@
+//go:build linux
+
+package timefilter
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// statTimes returns the atime and ctime of info from its `syscall.Stat_t`.
+func statTimes(info os.FileInfo) (time.Time, time.Time, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	return time.Unix(stat.Atim.Unix()), time.Unix(stat.Ctim.Unix()), true
+}
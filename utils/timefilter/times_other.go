@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !windows
+
+package timefilter
+
+import (
+	"os"
+	"time"
+)
+
+// statTimes reports that the atime and ctime are not available on this platform.
+func statTimes(info os.FileInfo) (time.Time, time.Time, bool) {
+	return time.Time{}, time.Time{}, false
+}
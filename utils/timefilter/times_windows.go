@@ -0,0 +1,19 @@
+//go:build windows
+
+package timefilter
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// statTimes returns the atime and, as Windows does not record a ctime, the creation time of info from
+// its `syscall.Win32FileAttributeData`.
+func statTimes(info os.FileInfo) (time.Time, time.Time, bool) {
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	return time.Unix(0, data.LastAccessTime.Nanoseconds()), time.Unix(0, data.CreationTime.Nanoseconds()), true
+}
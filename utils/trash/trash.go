@@ -0,0 +1,364 @@
+package trash
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ondrovic/common/types"
+)
+
+// deletionDateLayout is the timestamp format used in `.trashinfo` files.
+const deletionDateLayout = "2006-01-02T15:04:05"
+
+var (
+	// ErrCrossDevice is returned when an entry cannot be moved into the trash because they are on
+	// different filesystems. Use `NewWithDir` with a trash on the filesystem of the entry, such as
+	// `$topdir/.Trash-$uid` where `$topdir` is its mount point.
+	ErrCrossDevice = errors.New("trash is on another filesystem")
+	// ErrRestoreExists is returned when restoring an entry whose original path is taken.
+	ErrRestoreExists = errors.New("original path already exists")
+	// ErrInvalidName is returned for a trash name that is not a single entry of the trash, such as
+	// `..` or a name holding a path separator.
+	ErrInvalidName = errors.New("invalid trash name")
+)
+
+// TrashDirOps is a `types.DirOps` that moves removed entries to a freedesktop.org trash directory
+// instead of deleting them. Every other operation is passed through to the wrapped `Ops`, so it can be
+// used anywhere a `types.DirOps` is accepted, including `utils.RemoveEmptyDir`.
+//
+// Entries are moved with `Rename`, so the trash must live on the same filesystem as the entries being
+// removed, otherwise trashing fails with `ErrCrossDevice`.
+type TrashDirOps struct {
+	// Ops is the filesystem the entries live on and the trash is stored in.
+	Ops types.DirOps
+	// Dir is the trash directory holding the `files` and `info` subdirectories.
+	Dir string
+	// Now returns the time recorded as the deletion date, it defaults to `time.Now`.
+	Now func() time.Time
+}
+
+var _ types.DirOps = (*TrashDirOps)(nil)
+
+// New returns a TrashDirOps using the home trash, `$XDG_DATA_HOME/Trash` or `~/.local/share/Trash`
+// when `XDG_DATA_HOME` is not set.
+func New(ops types.DirOps) (*TrashDirOps, error) {
+	dir, err := HomeTrashDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithDir(ops, dir), nil
+}
+
+// NewWithDir returns a TrashDirOps storing trashed entries in dir, a nil ops uses `types.RealDirOps`.
+func NewWithDir(ops types.DirOps, dir string) *TrashDirOps {
+	if ops == nil {
+		ops = &types.RealDirOps{}
+	}
+	return &TrashDirOps{Ops: ops, Dir: dir, Now: time.Now}
+}
+
+// HomeTrashDir returns the home trash directory as defined by the freedesktop.org trash specification.
+func HomeTrashDir() (string, error) {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		return filepath.Join(dataHome, "Trash"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "share", "Trash"), nil
+}
+
+// Trash moves name into the trash, writing its `.trashinfo` metadata first. It returns the name of the
+// entry inside the trash, which can be passed to `Restore`.
+func (t *TrashDirOps) Trash(name string) (string, error) {
+	original, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := t.Ops.Lstat(original); err != nil {
+		return "", err
+	}
+
+	for _, dir := range []string{t.filesDir(), t.infoDir()} {
+		if err := t.Ops.MkdirAll(dir, 0o700); err != nil {
+			return "", fmt.Errorf("error creating trash directory: %w", err)
+		}
+	}
+
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: filepath.ToSlash(original)}).EscapedPath(),
+		t.now().Format(deletionDateLayout),
+	)
+	trashName, err := t.claimName(filepath.Base(original), []byte(info))
+	if err != nil {
+		return "", err
+	}
+
+	if err := t.Ops.Rename(original, filepath.Join(t.filesDir(), trashName)); err != nil {
+		_ = t.Ops.Remove(t.infoPath(trashName))
+		if errors.Is(err, syscall.EXDEV) {
+			return "", fmt.Errorf("error moving %s to trash %s: %w: %w", original, t.Dir, ErrCrossDevice, err)
+		}
+		return "", fmt.Errorf("error moving %s to trash: %w", original, err)
+	}
+
+	return trashName, nil
+}
+
+// Restore moves the trashed entry back to its original path, recreating missing parent directories.
+// It fails with `ErrRestoreExists` rather than overwrite something at the original path, and with
+// `ErrInvalidName` for a trashName that would point outside the trash.
+func (t *TrashDirOps) Restore(trashName string) error {
+	if err := checkName(trashName); err != nil {
+		return err
+	}
+
+	item, err := t.readInfo(trashName)
+	if err != nil {
+		return err
+	}
+
+	if _, err := t.Ops.Lstat(item.OriginalPath); err == nil {
+		return fmt.Errorf("%s: %w", item.OriginalPath, ErrRestoreExists)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err := t.Ops.MkdirAll(filepath.Dir(item.OriginalPath), 0o755); err != nil {
+		return fmt.Errorf("error recreating parent directory: %w", err)
+	}
+	if err := t.Ops.Rename(filepath.Join(t.filesDir(), trashName), item.OriginalPath); err != nil {
+		return fmt.Errorf("error restoring %s: %w", item.OriginalPath, err)
+	}
+
+	return t.Ops.Remove(t.infoPath(trashName))
+}
+
+// List returns every entry in the trash, oldest first and then by name.
+func (t *TrashDirOps) List() ([]types.TrashItem, error) {
+	entries, err := t.Ops.ReadDir(t.infoDir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return []types.TrashItem{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items := []types.TrashItem{}
+	for _, entry := range entries {
+		trashName, ok := strings.CutSuffix(entry.Name(), ".trashinfo")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		item, err := t.readInfo(trashName)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].DeletionDate.Equal(items[j].DeletionDate) {
+			return items[i].DeletionDate.Before(items[j].DeletionDate)
+		}
+		return items[i].Name < items[j].Name
+	})
+	return items, nil
+}
+
+// ReadDir implements the `ReadDir` function of the `types.DirOps` interface.
+func (t *TrashDirOps) ReadDir(name string) ([]os.DirEntry, error) {
+	return t.Ops.ReadDir(name)
+}
+
+// Remove implements the `Remove` function of the `types.DirOps` interface by moving name to the trash.
+// Like `os.Remove` it refuses to remove a directory that is not empty.
+func (t *TrashDirOps) Remove(name string) error {
+	info, err := t.Ops.Lstat(name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		entries, err := t.Ops.ReadDir(name)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return fmt.Errorf("directory %s is not empty", name)
+		}
+	}
+
+	_, err = t.Trash(name)
+	return err
+}
+
+// Stat implements the `Stat` function of the `types.DirOps` interface.
+func (t *TrashDirOps) Stat(name string) (os.FileInfo, error) {
+	return t.Ops.Stat(name)
+}
+
+// Lstat implements the `Lstat` function of the `types.DirOps` interface.
+func (t *TrashDirOps) Lstat(name string) (os.FileInfo, error) {
+	return t.Ops.Lstat(name)
+}
+
+// Open implements the `Open` function of the `types.DirOps` interface.
+func (t *TrashDirOps) Open(name string) (fs.File, error) {
+	return t.Ops.Open(name)
+}
+
+// OpenFile implements the `OpenFile` function of the `types.DirOps` interface.
+func (t *TrashDirOps) OpenFile(name string, flag int, perm os.FileMode) (types.File, error) {
+	return t.Ops.OpenFile(name, flag, perm)
+}
+
+// Rename implements the `Rename` function of the `types.DirOps` interface.
+func (t *TrashDirOps) Rename(oldpath, newpath string) error {
+	return t.Ops.Rename(oldpath, newpath)
+}
+
+// MkdirAll implements the `MkdirAll` function of the `types.DirOps` interface.
+func (t *TrashDirOps) MkdirAll(path string, perm os.FileMode) error {
+	return t.Ops.MkdirAll(path, perm)
+}
+
+// RemoveAll implements the `RemoveAll` function of the `types.DirOps` interface by moving path and
+// everything it contains to the trash. Like `os.RemoveAll` it returns nil when path does not exist.
+func (t *TrashDirOps) RemoveAll(path string) error {
+	if _, err := t.Ops.Lstat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	_, err := t.Trash(path)
+	return err
+}
+
+// Chtimes implements the `Chtimes` function of the `types.DirOps` interface.
+func (t *TrashDirOps) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return t.Ops.Chtimes(name, atime, mtime)
+}
+
+// WalkDir implements the `WalkDir` function of the `types.DirOps` interface.
+func (t *TrashDirOps) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return t.Ops.WalkDir(root, fn)
+}
+
+// readInfo parses the `.trashinfo` file for trashName.
+func (t *TrashDirOps) readInfo(trashName string) (types.TrashItem, error) {
+	if err := checkName(trashName); err != nil {
+		return types.TrashItem{}, err
+	}
+
+	file, err := t.Ops.Open(t.infoPath(trashName))
+	if err != nil {
+		return types.TrashItem{}, err
+	}
+	defer file.Close()
+
+	item := types.TrashItem{Name: trashName}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "Path":
+			unescaped, err := url.PathUnescape(value)
+			if err != nil {
+				return types.TrashItem{}, fmt.Errorf("invalid trash info path %q: %w", value, err)
+			}
+			item.OriginalPath = filepath.FromSlash(unescaped)
+		case "DeletionDate":
+			date, err := time.ParseInLocation(deletionDateLayout, value, time.Local)
+			if err != nil {
+				return types.TrashItem{}, fmt.Errorf("invalid trash info date %q: %w", value, err)
+			}
+			item.DeletionDate = date
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return types.TrashItem{}, err
+	}
+	if item.OriginalPath == "" {
+		return types.TrashItem{}, fmt.Errorf("trash info for %s has no path", trashName)
+	}
+
+	return item, nil
+}
+
+// claimName returns base, or base with a numeric suffix, that is not used in the trash yet, and writes
+// info as its `.trashinfo` file. The file is created with `os.O_EXCL`, so of several callers trashing
+// entries with the same name, in this process or another, each claims a different one.
+func (t *TrashDirOps) claimName(base string, info []byte) (string, error) {
+	for i := 1; ; i++ {
+		name := base
+		if i > 1 {
+			name = base + "." + strconv.Itoa(i)
+		}
+
+		if _, err := t.Ops.Lstat(filepath.Join(t.filesDir(), name)); err == nil {
+			continue
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+
+		file, err := t.Ops.OpenFile(t.infoPath(name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("error writing trash info: %w", err)
+		}
+		_, err = file.Write(info)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = t.Ops.Remove(t.infoPath(name))
+			return "", fmt.Errorf("error writing trash info: %w", err)
+		}
+		return name, nil
+	}
+}
+
+// checkName returns an error wrapping `ErrInvalidName` unless trashName names an entry directly inside
+// the trash.
+func checkName(trashName string) error {
+	if trashName == "" || trashName == "." || trashName == ".." || trashName != filepath.Base(trashName) || strings.ContainsRune(trashName, '/') {
+		return fmt.Errorf("%q: %w", trashName, ErrInvalidName)
+	}
+	return nil
+}
+
+// now returns the current time from the configured clock.
+func (t *TrashDirOps) now() time.Time {
+	if t.Now == nil {
+		return time.Now()
+	}
+	return t.Now()
+}
+
+func (t *TrashDirOps) filesDir() string {
+	return filepath.Join(t.Dir, "files")
+}
+
+func (t *TrashDirOps) infoDir() string {
+	return filepath.Join(t.Dir, "info")
+}
+
+func (t *TrashDirOps) infoPath(trashName string) string {
+	return filepath.Join(t.infoDir(), trashName+".trashinfo")
+}
@@ -0,0 +1,342 @@
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils"
+	"github.com/ondrovic/common/utils/journal"
+	"github.com/ondrovic/common/utils/memfs"
+)
+
+// fixedTime is the deletion date recorded by every test trash.
+var fixedTime = time.Date(2024, 8, 31, 22, 32, 8, 0, time.Local)
+
+// CreateTestTrash creates an in-memory filesystem and a trash stored inside it for testing.
+func CreateTestTrash(t *testing.T) (*memfs.MemDirOps, *TrashDirOps) {
+	t.Helper()
+	ops := memfs.New()
+	for _, name := range []string{"/data/movie.mp4", "/data/other/movie.mp4", "/data/full/file.txt"} {
+		if err := ops.WriteFile(name, []byte("content"), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	if err := ops.MkdirAll("/data/empty dir", 0o755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	trash := NewWithDir(ops, "/home/user/.local/share/Trash")
+	trash.Now = func() time.Time { return fixedTime }
+	return ops, trash
+}
+
+// TestHomeTrashDir tests the HomeTrashDir func.
+func TestHomeTrashDir(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "/xdg/data")
+	dir, err := HomeTrashDir()
+	if err != nil || dir != filepath.Join("/xdg/data", "Trash") {
+		t.Errorf("HomeTrashDir() = %q, %v; want %q", dir, err, filepath.Join("/xdg/data", "Trash"))
+	}
+
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("HOME", "/home/someone")
+	dir, err = HomeTrashDir()
+	if err != nil || dir != filepath.Join("/home/someone", ".local", "share", "Trash") {
+		t.Errorf("HomeTrashDir() without XDG_DATA_HOME = %q, %v", dir, err)
+	}
+}
+
+// TestRemove tests the Remove func.
+func TestRemove(t *testing.T) {
+	tests := []*types.TestLayout[string, string]{
+		{Name: "Trash file", Input: "/data/movie.mp4", Expected: "movie.mp4"},
+		{Name: "Trash empty directory with space", Input: "/data/empty dir", Expected: "empty dir"},
+		{Name: "Non-empty directory", Input: "/data/full", Err: errors.New("is not empty")},
+		{Name: "Missing", Input: "/data/missing", Err: fs.ErrNotExist},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ops, trash := CreateTestTrash(t)
+
+			err := trash.Remove(test.Input)
+			if test.Err != nil {
+				if err == nil || (!errors.Is(err, test.Err) && !strings.Contains(err.Error(), test.Err.Error())) {
+					t.Errorf("Remove(%q) - %v error = %v; want %v", test.Input, test.Name, err, test.Err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Remove(%q) - %v error = %v", test.Input, test.Name, err)
+			}
+
+			if _, err := ops.Stat(test.Input); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Remove(%q) - %v original still exists", test.Input, test.Name)
+			}
+			if _, err := ops.Stat(filepath.Join(trash.Dir, "files", test.Expected)); err != nil {
+				t.Errorf("Remove(%q) - %v trashed entry missing: %v", test.Input, test.Name, err)
+			}
+
+			info, err := ops.ReadFile(filepath.Join(trash.Dir, "info", test.Expected+".trashinfo"))
+			if err != nil {
+				t.Fatalf("Remove(%q) - %v trash info missing: %v", test.Input, test.Name, err)
+			}
+			expectedInfo := "[Trash Info]\nPath=" + strings.ReplaceAll(test.Input, " ", "%20") + "\nDeletionDate=2024-08-31T22:32:08\n"
+			if string(info) != expectedInfo {
+				t.Errorf("Remove(%q) - %v trash info = %q; want %q", test.Input, test.Name, info, expectedInfo)
+			}
+		})
+	}
+}
+
+// TestTrash_NameCollision tests that entries with the same name get unique trash names.
+func TestTrash_NameCollision(t *testing.T) {
+	_, trash := CreateTestTrash(t)
+
+	first, err := trash.Trash("/data/movie.mp4")
+	if err != nil {
+		t.Fatalf("Trash() error = %v", err)
+	}
+	second, err := trash.Trash("/data/other/movie.mp4")
+	if err != nil {
+		t.Fatalf("Trash() error = %v", err)
+	}
+
+	if first != "movie.mp4" || second != "movie.mp4.2" {
+		t.Errorf("Trash() names = %q, %q; want %q, %q", first, second, "movie.mp4", "movie.mp4.2")
+	}
+
+	items, err := trash.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	expected := []types.TrashItem{
+		{Name: "movie.mp4", OriginalPath: "/data/movie.mp4", DeletionDate: fixedTime},
+		{Name: "movie.mp4.2", OriginalPath: "/data/other/movie.mp4", DeletionDate: fixedTime},
+	}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("List() = %+v; want %+v", items, expected)
+	}
+}
+
+// TestRestore tests the Restore func.
+func TestRestore(t *testing.T) {
+	tests := []*types.TestLayout[string, error]{
+		{Name: "Restore file", Input: "/data/movie.mp4"},
+		{Name: "Restore into removed parent", Input: "/data/other/movie.mp4"},
+		{Name: "Original path taken", Input: "/data/full/file.txt", Expected: ErrRestoreExists},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ops, trash := CreateTestTrash(t)
+
+			trashName, err := trash.Trash(test.Input)
+			if err != nil {
+				t.Fatalf("Trash(%q) error = %v", test.Input, err)
+			}
+			switch test.Name {
+			case "Restore into removed parent":
+				if err := ops.RemoveAll("/data/other"); err != nil {
+					t.Fatalf("failed to remove directory: %v", err)
+				}
+			case "Original path taken":
+				if err := ops.WriteFile(test.Input, []byte("new"), 0o644); err != nil {
+					t.Fatalf("failed to write file: %v", err)
+				}
+			}
+
+			err = trash.Restore(trashName)
+			if !errors.Is(err, test.Expected) {
+				t.Fatalf("Restore(%q) - %v error = %v; want %v", trashName, test.Name, err, test.Expected)
+			}
+			if test.Expected != nil {
+				return
+			}
+
+			if data, err := ops.ReadFile(test.Input); err != nil || string(data) != "content" {
+				t.Errorf("Restore(%q) - %v restored = %q, %v", trashName, test.Name, data, err)
+			}
+			if items, _ := trash.List(); len(items) != 0 {
+				t.Errorf("Restore(%q) - %v trash still lists %v", trashName, test.Name, items)
+			}
+		})
+	}
+}
+
+// TestRestore_InvalidName tests that names pointing outside the trash are rejected before anything is
+// read or moved.
+func TestRestore_InvalidName(t *testing.T) {
+	tests := []*types.TestLayout[string, error]{
+		{Name: "Empty", Input: "", Expected: ErrInvalidName},
+		{Name: "Current directory", Input: ".", Expected: ErrInvalidName},
+		{Name: "Parent directory", Input: "..", Expected: ErrInvalidName},
+		{Name: "Escapes the trash", Input: "../x", Expected: ErrInvalidName},
+		{Name: "Nested", Input: "a/b", Expected: ErrInvalidName},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ops, trash := CreateTestTrash(t)
+			// A planted entry that `../x` would restore to /data/stolen.
+			planted := map[string]string{
+				filepath.Join(trash.Dir, "x"):            "secret",
+				filepath.Join(trash.Dir, "x.trashinfo"):  "[Trash Info]\nPath=/data/stolen\nDeletionDate=2024-08-31T22:32:08\n",
+				filepath.Join(trash.Dir, "files", "a/b"): "nested",
+			}
+			for name, data := range planted {
+				if err := ops.WriteFile(name, []byte(data), 0o600); err != nil {
+					t.Fatalf("failed to write file: %v", err)
+				}
+			}
+
+			if err := trash.Restore(test.Input); !errors.Is(err, test.Expected) {
+				t.Errorf("Restore(%q) - %v error = %v; want %v", test.Input, test.Name, err, test.Expected)
+			}
+			if _, err := ops.Lstat("/data/stolen"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Restore(%q) - %v moved a file outside the trash", test.Input, test.Name)
+			}
+		})
+	}
+}
+
+// TestRemoveEmptyDir_Trash tests that utils.RemoveEmptyDir works with a TrashDirOps.
+func TestRemoveEmptyDir_Trash(t *testing.T) {
+	ops, trash := CreateTestTrash(t)
+
+	removed, err := utils.RemoveEmptyDir("/data/empty dir", trash)
+	if !removed || err != nil {
+		t.Fatalf("RemoveEmptyDir() = %v, %v; want true, nil", removed, err)
+	}
+	if _, err := ops.Stat(filepath.Join(trash.Dir, "files", "empty dir")); err != nil {
+		t.Errorf("RemoveEmptyDir() did not move directory to trash: %v", err)
+	}
+
+	if err := trash.RemoveAll("/data/full"); err != nil {
+		t.Fatalf("RemoveAll() error = %v", err)
+	}
+	if _, err := ops.Stat(filepath.Join(trash.Dir, "files", "full", "file.txt")); err != nil {
+		t.Errorf("RemoveAll() did not move tree to trash: %v", err)
+	}
+	if err := trash.RemoveAll("/data/missing"); err != nil {
+		t.Errorf("RemoveAll() missing error = %v; want nil", err)
+	}
+}
+
+// TestTrash_RealDirOps tests trashing and restoring on the real filesystem.
+func TestTrash_RealDirOps(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "xdg"))
+	file := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(file, []byte("content"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	trash, err := New(nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := trash.Remove(file); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "xdg", "Trash", "files", "file.txt")); err != nil {
+		t.Errorf("Remove() did not move file to trash: %v", err)
+	}
+	if err := trash.Restore("file.txt"); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("Restore() did not restore file: %v", err)
+	}
+}
+
+// TestTrash_Concurrent tests that entries with the same name trashed at the same time each get their
+// own trash name.
+func TestTrash_Concurrent(t *testing.T) {
+	ops, trash := CreateTestTrash(t)
+	originals := []string{}
+	for i := 0; i < 8; i++ {
+		name := fmt.Sprintf("/data/%d/clip.mp4", i)
+		if err := ops.WriteFile(name, []byte(name), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		originals = append(originals, name)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(originals))
+	for i, name := range originals {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			_, errs[i] = trash.Trash(name)
+		}(i, name)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		t.Fatalf("Trash() error = %v", err)
+	}
+
+	items, err := trash.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(items) != len(originals) {
+		t.Fatalf("List() = %d entries; want %d", len(items), len(originals))
+	}
+	for _, item := range items {
+		data, err := ops.ReadFile(filepath.Join(trash.Dir, "files", item.Name))
+		if err != nil || string(data) != filepath.ToSlash(item.OriginalPath) {
+			t.Errorf("trashed %s holds %q, %v; want %q", item.Name, data, err, item.OriginalPath)
+		}
+	}
+}
+
+// TestTrash_CrossDevice tests that moving an entry to a trash on another filesystem is explained.
+func TestTrash_CrossDevice(t *testing.T) {
+	ops, trash := CreateTestTrash(t)
+	ops.InjectError(memfs.OpRename, "/data/movie.mp4", &os.LinkError{Op: "rename", Old: "/data/movie.mp4", New: "/trash", Err: syscall.EXDEV})
+
+	if _, err := trash.Trash("/data/movie.mp4"); !errors.Is(err, ErrCrossDevice) || !errors.Is(err, syscall.EXDEV) {
+		t.Errorf("Trash() error = %v; want %v", err, ErrCrossDevice)
+	}
+	if items, err := trash.List(); err != nil || len(items) != 0 {
+		t.Errorf("List() after failed Trash = %+v, %v; want no entries", items, err)
+	}
+}
+
+// TestTrash_Journal tests trashing through a JournalDirOps, which can then undo it.
+func TestTrash_Journal(t *testing.T) {
+	ops, _ := CreateTestTrash(t)
+	logPath := "/journal.log"
+	journaled, err := journal.New(ops, logPath, "run")
+	if err != nil {
+		t.Fatalf("journal.New() error = %v", err)
+	}
+	defer journaled.Close()
+
+	trash := NewWithDir(journaled, "/home/user/.local/share/Trash")
+	if _, err := trash.Trash("/data/movie.mp4"); err != nil {
+		t.Fatalf("Trash() error = %v", err)
+	}
+	if _, err := journal.Undo(ops, logPath, "run"); err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+
+	if _, err := ops.Stat("/data/movie.mp4"); err != nil {
+		t.Errorf("Undo() did not restore the file: %v", err)
+	}
+	if items, err := trash.List(); err != nil || len(items) != 0 {
+		t.Errorf("List() after Undo = %+v, %v; want no entries", items, err)
+	}
+}
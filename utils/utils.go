@@ -1,21 +1,43 @@
 package utils
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils/filetypes"
 	"github.com/ondrovic/common/utils/formatters"
+	"github.com/ondrovic/common/utils/mimetypes"
+	"github.com/ondrovic/common/utils/operators"
+)
+
+var (
+	// ErrBrokenLink is wrapped by errors reported for symlinks whose target cannot be resolved.
+	ErrBrokenLink = errors.New("broken symlink")
+	// ErrSymlinkLoop is wrapped by errors reported for followed symlinks that lead back to a directory
+	// already being walked.
+	ErrSymlinkLoop = errors.New("symlink loop")
+	// ErrValidation is matched by every failure `ValidateStruct` reports, so
+	// `errors.Is(err, utils.ErrValidation)` tells a failed validation from other errors.
+	ErrValidation = errors.New("validation failed")
 )
 
 var (
-	osStatFunc     = os.Stat
 	ToLowerWrapper = func(input interface{}) (string, error) {
 		return formatters.ToLower(input)
 	}
@@ -40,8 +62,104 @@ func validateStructField(fieldName string, value reflect.Value) error {
 	return nil
 }
 
-// Validate function using reflection.
+// The ValidationError struct describes a field that failed validation.
+// @property {string} Path - The `Path` property is the full path of the field, such as
+// `Style.Color.Background` or `Servers[0].Host`.
+// @property {string} Field - The `Field` property is the name of the field itself, such as `Background`.
+// @property {string} Rule - The `Rule` property is the rule that failed, such as `required` or `min`.
+// @property {string} Param - The `Param` property is the value of the rule, such as `3` for `min=3`.
+// @property {interface{}} Value - The `Value` property is the offending value.
+// @property {string} Message - The `Message` property describes the failure, such as
+// `Name cannot be empty`.
+type ValidationError struct {
+	Path    string
+	Field   string
+	Rule    string
+	Param   string
+	Value   interface{}
+	Message string
+}
+
+// Error returns the message of e.
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// Is reports whether e matches target, `ErrValidation` or a `*ValidationError` whose non-empty Path and
+// Rule equal those of e, so `errors.Is(err, &utils.ValidationError{Rule: "required"})` finds any
+// missing field.
+func (e *ValidationError) Is(target error) bool {
+	if target == ErrValidation {
+		return true
+	}
+	t, ok := target.(*ValidationError)
+	return ok && (t.Path == "" || t.Path == e.Path) && (t.Rule == "" || t.Rule == e.Rule)
+}
+
+// ValidationErrors holds every failure `ValidateStruct` found, in field order. Use `errors.As` to get
+// it back from an error, and `errors.As` or `errors.Is` to reach a single `*ValidationError`.
+type ValidationErrors []*ValidationError
+
+// Error returns the messages of e, one per line.
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Message)
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Unwrap returns the failures of e, so `errors.Is` and `errors.As` look at each of them.
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// The function `ValidateStruct` validates app, a struct or a pointer to one, and returns every failure
+// it finds as `ValidationErrors`, or nil. Fields are checked by the rules of their `validate` struct
+// tag, separated by commas:
+//
+//   - `required` fails on a zero value, an empty slice or map, or a nil pointer.
+//   - `omitempty` skips every other rule, and nested fields, when the value is zero.
+//   - `min=n`, `max=n` and `len=n` bound the number of characters of a string, the length of a slice,
+//     array or map, or the value of a number. For a `time.Duration` n is a duration such as `1m30s`.
+//   - `oneof=a b c` requires the value to be one of the space separated values.
+//   - `regexp=pattern` requires a string to match pattern. It takes the rest of the tag, so it comes
+//     last and its pattern may hold commas.
+//   - `url` requires an absolute URL, `email` a bare email address and `path-exists` a path that
+//     exists on disk, see `ValidateStructWithOps` to check paths through another `types.DirOps`.
+//
+// Validation recurses into nested structs, pointers, and the elements of slices, arrays and maps, whose
+// fields are named by their path, such as `Servers[0].Host`. Rules apply to a pointer's target and to a
+// slice or map itself rather than to its elements. Unexported fields and fields tagged `validate:"-"`
+// are skipped. For compatibility, top level fields without a `validate` tag keep the old checks, a
+// string must not be empty and a struct must not be zero. A malformed tag is returned as a plain error
+// without checking the remaining fields.
+//
+// Example usage:
+//
+//	type Config struct {
+//	    Name    string   `validate:"required,max=64"`
+//	    Mode    string   `validate:"omitempty,oneof=fast safe"`
+//	    Webhook string   `validate:"omitempty,url"`
+//	    Roots   []string `validate:"min=1"`
+//	}
+//	var failures utils.ValidationErrors
+//	if err := utils.ValidateStruct(config); errors.As(err, &failures) {
+//	    for _, failure := range failures {
+//	        fmt.Println(failure.Path, failure.Rule, failure.Message)
+//	    }
+//	}
 func ValidateStruct(app interface{}) error {
+	return ValidateStructWithOps(app, &types.RealDirOps{})
+}
+
+// The function `ValidateStructWithOps` is like `ValidateStruct` but checks `path-exists` rules with
+// ops, so that paths can be validated against a `memfs` tree or any other `types.DirOps`.
+func ValidateStructWithOps(app interface{}, ops types.DirOps) error {
 	v := reflect.ValueOf(app)
 
 	// If it's a pointer, dereference it
@@ -53,49 +171,341 @@ func ValidateStruct(app interface{}) error {
 		return fmt.Errorf("validateApp expects a struct")
 	}
 
+	s := &validator{ops: ops, seen: map[visit]bool{}}
+	if err := s.fields(v, "", true); err != nil {
+		return err
+	}
+	if len(s.failures) > 0 {
+		return s.failures
+	}
+	return nil
+}
+
+// validator collects the failures of a `ValidateStruct` call. ops checks `path-exists` rules and seen
+// holds the pointers, maps and slices being followed, so that cyclic data does not recurse forever.
+type validator struct {
+	ops      types.DirOps
+	failures ValidationErrors
+	seen     map[visit]bool
+}
+
+// visit identifies a pointer, map or slice by its type and address, since a slice and a pointer to its
+// first element share an address.
+type visit struct {
+	typ reflect.Type
+	ptr uintptr
+}
+
+// fail records that value, the field at path, failed rule.
+func (s *validator) fail(path string, value reflect.Value, rule validateRule, message string) {
+	field := path[strings.LastIndex(path, ".")+1:]
+	var offending interface{}
+	if value.IsValid() && value.CanInterface() {
+		offending = value.Interface()
+	}
+	s.failures = append(s.failures, &ValidationError{Path: path, Field: field, Rule: rule.name, Param: rule.param, Value: offending, Message: message})
+}
+
+// fields validates the fields of the struct v, whose path is prefix. Only the top level struct applies
+// the legacy checks to untagged fields.
+func (s *validator) fields(v reflect.Value, prefix string, topLevel bool) error {
 	t := v.Type()
 
 	for i := 0; i < v.NumField(); i++ {
 		field := t.Field(i)
 		value := v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, tagged := field.Tag.Lookup("validate")
+		if tag == "-" {
+			continue
+		}
+		path := prefix + field.Name
+		if tagged {
+			if err := s.value(path, value, tag); err != nil {
+				return err
+			}
+			continue
+		}
 
 		var err error
-		switch value.Kind() {
-		case reflect.String:
+		switch {
+		case topLevel && value.Kind() == reflect.String:
 			err = validateStringField(field.Name, value.String())
-		case reflect.Struct:
+		case topLevel && value.Kind() == reflect.Struct:
 			err = validateStructField(field.Name, value)
 		}
 
+		if err != nil {
+			s.fail(path, value, validateRule{name: "required"}, err.Error())
+		} else if err := s.nested(path, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// value applies the rules of tag to value, then validates what value holds.
+func (s *validator) value(path string, value reflect.Value, tag string) error {
+	rules, err := parseValidateTag(tag)
+	if err != nil {
+		return fmt.Errorf("invalid validate tag on %s: %w", path, err)
+	}
+
+	for _, rule := range rules {
+		if rule.name == "omitempty" && isEmptyValue(value) {
+			return nil
+		}
+	}
+	for _, rule := range rules {
+		if rule.name == "required" && isEmptyValue(value) {
+			s.fail(path, value, rule, fmt.Sprintf("%s cannot be empty", path))
+			return nil
+		}
+	}
+
+	target := value
+	for target.Kind() == reflect.Ptr || target.Kind() == reflect.Interface {
+		if target.IsNil() {
+			return nil
+		}
+		target = target.Elem()
+	}
+	for _, rule := range rules {
+		message, err := checkRule(s.ops, path, target, rule)
 		if err != nil {
 			return err
 		}
+		if message != "" {
+			s.fail(path, target, rule, message)
+		}
+	}
+
+	return s.nested(path, value)
+}
+
+// nested validates the fields of the structs value holds, directly, through pointers or as the
+// elements of a slice, array or map.
+func (s *validator) nested(path string, value reflect.Value) error {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		// Maps and slices can hold themselves, through an interface, just as pointers can.
+		if value.IsNil() || (value.Kind() != reflect.Ptr && value.Len() == 0) {
+			return nil
+		}
+		seen := visit{typ: value.Type(), ptr: value.Pointer()}
+		if s.seen[seen] {
+			return nil
+		}
+		s.seen[seen] = true
+		defer delete(s.seen, seen)
 	}
 
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		return s.nested(path, value.Elem())
+	case reflect.Struct:
+		return s.fields(value, path+".", false)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := s.nested(fmt.Sprintf("%s[%d]", path, i), value.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, key := range keys {
+			if err := s.nested(fmt.Sprintf("%s[%v]", path, key), value.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
-// The function `ToFileType` converts a string representation of a file type to a corresponding enum
-// value from the `types.FileType` enum.
+// validateRule is a single rule of a `validate` tag, such as `min=3`.
+type validateRule struct {
+	name  string
+	param string
+}
+
+// validateRules are the rules a `validate` tag may hold, and whether each takes a parameter.
+var validateRules = map[string]bool{
+	"required":    false,
+	"omitempty":   false,
+	"min":         true,
+	"max":         true,
+	"len":         true,
+	"oneof":       true,
+	"regexp":      true,
+	"url":         false,
+	"email":       false,
+	"path-exists": false,
+}
+
+// parseValidateTag splits tag into its rules.
+func parseValidateTag(tag string) ([]validateRule, error) {
+	rules := []validateRule{}
+	for tag != "" {
+		var part string
+		if strings.HasPrefix(strings.TrimSpace(tag), "regexp=") {
+			part, tag = tag, ""
+		} else {
+			part, tag, _ = strings.Cut(tag, ",")
+		}
+
+		name, param, hasParam := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "" {
+			continue
+		}
+		needsParam, ok := validateRules[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown rule %q", name)
+		}
+		if needsParam != hasParam || (needsParam && param == "") {
+			if needsParam {
+				return nil, fmt.Errorf("rule %q needs a value", name)
+			}
+			return nil, fmt.Errorf("rule %q does not take a value", name)
+		}
+		rules = append(rules, validateRule{name: name, param: param})
+	}
+	return rules, nil
+}
+
+// checkRule applies rule to value, whose path is name, checking paths with ops. It returns a message
+// describing the failure, or an error when the rule cannot check value.
+func checkRule(ops types.DirOps, name string, value reflect.Value, rule validateRule) (string, error) {
+	switch rule.name {
+	case "min", "max", "len":
+		return checkBound(name, value, rule)
+	case "oneof":
+		text := fmt.Sprint(value.Interface())
+		if !slices.Contains(strings.Fields(rule.param), text) {
+			return fmt.Sprintf("%s must be one of %s, got %q", name, strings.Join(strings.Fields(rule.param), ", "), text), nil
+		}
+	case "regexp":
+		if value.Kind() != reflect.String {
+			return "", fmt.Errorf("invalid validate tag on %s: rule %q needs a string, got %s", name, rule.name, value.Kind())
+		}
+		pattern, err := regexp.Compile(rule.param)
+		if err != nil {
+			return "", fmt.Errorf("invalid validate tag on %s: %w", name, err)
+		}
+		if !pattern.MatchString(value.String()) {
+			return fmt.Sprintf("%s must match %s", name, rule.param), nil
+		}
+	case "url":
+		if value.Kind() != reflect.String {
+			return "", fmt.Errorf("invalid validate tag on %s: rule %q needs a string, got %s", name, rule.name, value.Kind())
+		}
+		if parsed, err := url.ParseRequestURI(value.String()); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Sprintf("%s must be a URL, got %q", name, value.String()), nil
+		}
+	case "email":
+		if value.Kind() != reflect.String {
+			return "", fmt.Errorf("invalid validate tag on %s: rule %q needs a string, got %s", name, rule.name, value.Kind())
+		}
+		if address, err := mail.ParseAddress(value.String()); err != nil || address.Address != value.String() {
+			return fmt.Sprintf("%s must be an email address, got %q", name, value.String()), nil
+		}
+	case "path-exists":
+		if value.Kind() != reflect.String {
+			return "", fmt.Errorf("invalid validate tag on %s: rule %q needs a string, got %s", name, rule.name, value.Kind())
+		}
+		if _, err := ops.Stat(value.String()); err != nil {
+			return fmt.Sprintf("%s must be an existing path, got %q", name, value.String()), nil
+		}
+	}
+	return "", nil
+}
+
+// checkBound applies a `min`, `max` or `len` rule to the length of a string, slice, array or map, or
+// to the value of a number, as `checkRule` does.
+func checkBound(name string, value reflect.Value, rule validateRule) (string, error) {
+	var actual, bound float64
+	var err error
+	what := ""
+
+	switch value.Kind() {
+	case reflect.String:
+		actual, what = float64(utf8.RuneCountInString(value.String())), " characters"
+		bound, err = strconv.ParseFloat(rule.param, 64)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		actual, what = float64(value.Len()), " items"
+		bound, err = strconv.ParseFloat(rule.param, 64)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		actual = float64(value.Int())
+		if value.Type() == reflect.TypeOf(time.Duration(0)) {
+			var d time.Duration
+			d, err = time.ParseDuration(rule.param)
+			bound = float64(d)
+		} else {
+			bound, err = strconv.ParseFloat(rule.param, 64)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		actual = float64(value.Uint())
+		bound, err = strconv.ParseFloat(rule.param, 64)
+	case reflect.Float32, reflect.Float64:
+		actual = value.Float()
+		bound, err = strconv.ParseFloat(rule.param, 64)
+	default:
+		return "", fmt.Errorf("invalid validate tag on %s: rule %q cannot check a %s", name, rule.name, value.Kind())
+	}
+	if err != nil {
+		return "", fmt.Errorf("invalid validate tag on %s: %s=%s is not a number", name, rule.name, rule.param)
+	}
+
+	switch {
+	case rule.name == "min" && actual < bound:
+		return fmt.Sprintf("%s must be at least %s%s", name, rule.param, what), nil
+	case rule.name == "max" && actual > bound:
+		return fmt.Sprintf("%s must be at most %s%s", name, rule.param, what), nil
+	case rule.name == "len" && actual != bound:
+		return fmt.Sprintf("%s must be exactly %s%s", name, rule.param, what), nil
+	}
+	return "", nil
+}
+
+// isEmptyValue reports whether value is zero, or an empty slice or map.
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	default:
+		return value.IsZero()
+	}
+}
+
+// The function `ToFileType` converts a string representation of a file type, its name or one of its
+// aliases, to the file type registered under it in the `filetypes` registry. A MIME type or pattern
+// such as `video/*` resolves to the single file type it identifies, see `mimetypes.FileType`.
 func ToFileType(fileType string) types.FileType {
 	fileTypeToLower, err := ToLowerWrapper(fileType)
 	if err != nil {
 		return ""
 	}
-	switch fileTypeToLower {
-	case "any":
-		return types.FileTypes.Any
-	case "video":
-		return types.FileTypes.Video
-	case "image":
-		return types.FileTypes.Image
-	case "archive":
-		return types.FileTypes.Archive
-	case "documents":
-		return types.FileTypes.Documents
-	default:
+
+	if strings.Contains(fileTypeToLower, "/") {
+		resolved, err := mimetypes.FileType(fileTypeToLower)
+		if err != nil {
+			return ""
+		}
+		return resolved
+	}
+
+	resolved, ok := filetypes.Resolve(fileTypeToLower)
+	if !ok {
 		return ""
 	}
+	return resolved
 }
 
 // The function `ToOperatorType` converts a string representation of an operator type to its
@@ -106,8 +516,10 @@ func ToOperatorType(operatorType string) types.OperatorType {
 		return ""
 	}
 	switch operatorTypeToLower {
-	case "et", "equal to", "equalto", "equal", "==":
+	case "et", "equal to", "equalto", "equal", "==", "=":
 		return types.OperatorTypes.EqualTo
+	case "ne", "net", "not equal to", "notequalto", "not equal", "!=", "<>":
+		return types.OperatorTypes.NotEqualTo
 	case "gt", "greater", "greater than", "greaterthan", ">":
 		return types.OperatorTypes.GreaterThan
 	case "gte", "greater than or equal to", "greaterthanorequalto", ">=":
@@ -116,39 +528,153 @@ func ToOperatorType(operatorType string) types.OperatorType {
 		return types.OperatorTypes.LessThan
 	case "lte", "less than or equal to", "lessthanorequalto", "<=":
 		return types.OperatorTypes.LessThanEqualTo
+	case "bt", "between", "betweeninclusive", "between inclusive", "in", "..", "[..]":
+		return types.OperatorTypes.Between
+	case "btx", "between exclusive", "betweenexclusive", "(..)":
+		return types.OperatorTypes.BetweenExclusive
+	case "nbt", "not between", "notbetween", "not between inclusive", "notbetweeninclusive", "not in", "!..", "![..]":
+		return types.OperatorTypes.NotBetween
+	case "nbtx", "not between exclusive", "notbetweenexclusive", "!(..)":
+		return types.OperatorTypes.NotBetweenExclusive
+	default:
+		return ""
+	}
+}
+
+// The function `ToSymlinkPolicy` converts a string representation of a symlink policy to its
+// corresponding enum value.
+func ToSymlinkPolicy(symlinkPolicy string) types.SymlinkPolicy {
+	symlinkPolicyToLower, err := ToLowerWrapper(symlinkPolicy)
+	if err != nil {
+		return ""
+	}
+	switch symlinkPolicyToLower {
+	case "skip", "ignore":
+		return types.SymlinkPolicies.Skip
+	case "follow":
+		return types.SymlinkPolicies.Follow
+	case "file", "as file", "asfile":
+		return types.SymlinkPolicies.File
+	default:
+		return ""
+	}
+}
+
+// The function `ToDetectionMode` converts a string representation of a detection mode to its
+// corresponding enum value.
+func ToDetectionMode(detectionMode string) types.DetectionMode {
+	detectionModeToLower, err := ToLowerWrapper(detectionMode)
+	if err != nil {
+		return ""
+	}
+	switch detectionModeToLower {
+	case "extension", "ext":
+		return types.DetectionModes.Extension
+	case "content", "magic":
+		return types.DetectionModes.Content
+	case "both", "strict":
+		return types.DetectionModes.Both
+	default:
+		return ""
+	}
+}
+
+// The function `ToSizeSystem` converts a string representation of a size system to its corresponding
+// enum value.
+func ToSizeSystem(sizeSystem string) types.SizeSystem {
+	sizeSystemToLower, err := ToLowerWrapper(sizeSystem)
+	if err != nil {
+		return ""
+	}
+	switch sizeSystemToLower {
+	case "iec", "binary", "1024":
+		return types.SizeSystems.IEC
+	case "si", "decimal", "metric", "1000":
+		return types.SizeSystems.SI
+	default:
+		return ""
+	}
+}
+
+// The function `ToTimeField` converts a string representation of a file timestamp to its corresponding
+// enum value.
+func ToTimeField(timeField string) types.TimeField {
+	timeFieldToLower, err := ToLowerWrapper(timeField)
+	if err != nil {
+		return ""
+	}
+	switch timeFieldToLower {
+	case "mtime", "modified", "modification", "modification time":
+		return types.TimeFields.Modified
+	case "ctime", "changed", "change", "change time":
+		return types.TimeFields.Changed
+	case "atime", "accessed", "access", "access time":
+		return types.TimeFields.Accessed
 	default:
 		return ""
 	}
 }
 
 // The IsExtensionValid function checks if a given file extension is valid for a specified file type
-// based on a predefined list of allowed extensions.
+// based on the extensions registered for it in the `filetypes` registry. Compound extensions are
+// matched longest first, so `backup.tar.gz` is valid for whichever file type claims `.tar.gz`, even
+// when another claims `.gz`.
 func IsExtensionValid(fileType types.FileType, path string) bool {
-	ext, err := ToLowerWrapper(filepath.Ext(path))
+	name, err := ToLowerWrapper(filepath.Base(path))
 	if err != nil {
 		return false
 	}
-	extensions, exists := types.FileExtensions[fileType]
-	if !exists {
-		return false
-	}
 
-	// Check for wildcard entry (Any)
-	if _, found := extensions["*.*"]; found {
+	if filetypes.HasExtension(fileType, filetypes.Wildcard) {
 		return true
 	}
 
-	// Check if the file extension is explicitly allowed
-	return extensions[ext]
+	_, owners := filetypes.Extension(name)
+	return slices.Contains(owners, fileType)
 }
 
-// The function `IsDirectoryEmpty` checks if a directory is empty by listing its entries.
+// The function `SplitExtension` splits path into everything before its extension and the extension
+// itself, keeping compound extensions such as `.tar.gz` or `.part1.rar` intact so renamers can carry
+// them over. The longest extension registered in the `filetypes` registry wins, paths no file type
+// claims fall back to `filepath.Ext`. The extension is returned as written in path.
+//
+// Example usage:
+//
+//	stem, ext := utils.SplitExtension("/backups/site.TAR.GZ")
+//	// stem = "/backups/site", ext = ".TAR.GZ"
+func SplitExtension(path string) (string, string) {
+	ext, _ := filetypes.Extension(path)
+	if ext == "" {
+		ext = filepath.Ext(path)
+	}
+
+	return path[:len(path)-len(ext)], ext
+}
+
+// The function `DetectFileType` returns the file type claiming the longest extension of path, the
+// first registered one when several claim it, or `types.FileTypes.Any` when no file type claims it.
+// File types matching every file are not considered.
+func DetectFileType(path string) types.FileType {
+	if _, owners := filetypes.Extension(path); len(owners) > 0 {
+		return owners[0]
+	}
+
+	return types.FileTypes.Any
+}
+
+// The function `IsDirectoryEmpty` checks if a directory is empty by listing its entries. A symlink is
+// not a directory, even when it points to one. Every entry counts, ignore rules are only applied by
+// `PruneEmptyDirs` through `types.PruneOptions.Ignore`.
 func IsDirectoryEmpty(path string, ops types.DirOps) (bool, error) {
-	fileInfo, err := osStatFunc(path)
+	fileInfo, err := ops.Lstat(path)
 	if err != nil {
 		return false, fmt.Errorf("stat error - file not found: %s", path)
 	}
 
+	if fileInfo.Mode()&os.ModeSymlink != 0 {
+		return false, fmt.Errorf("%s is a symlink, not a directory", path)
+	}
+
 	if !fileInfo.IsDir() {
 		return false, fmt.Errorf("%s is not a directory", path)
 	}
@@ -162,62 +688,219 @@ func IsDirectoryEmpty(path string, ops types.DirOps) (bool, error) {
 }
 
 // The function `GetOperatorSizeMatches` determines if a file size matches a specified operator, wanted
-// file size, and tolerance size.
-func GetOperatorSizeMatches(operator types.OperatorType, wantedFileSize int64, toleranceSize float64, fileSize int64) (bool, error) {
-	results, err := CalculateTolerances(wantedFileSize, toleranceSize)
+// file size, and tolerance size. The range operators, such as `types.OperatorTypes.Between`, take
+// wantedFileSize as their lower bound and need the upper bound as upperFileSize, the tolerance widens
+// the range on both ends. The other operators ignore upperFileSize. toleranceSize is in KB, see
+// `GetOperatorSizeMatchesWithTolerance` for percentages and asymmetric tolerances.
+//
+// Example usage:
+//
+//	// Between 700MB and 1.5GB, bounds included.
+//	matches, err := utils.GetOperatorSizeMatches(types.OperatorTypes.Between, 700*1024*1024, 0, size, 1536*1024*1024)
+func GetOperatorSizeMatches(operator types.OperatorType, wantedFileSize int64, toleranceSize float64, fileSize int64, upperFileSize ...int64) (bool, error) {
+	tolerance, err := kilobyteTolerance(toleranceSize)
 	if err != nil {
 		return false, fmt.Errorf("error calculating tolerances %w", err)
 	}
 
-	switch operator {
-	case types.OperatorTypes.EqualTo:
-		return fileSize >= results.LowerBoundSize && fileSize <= results.UpperBoundSize, nil
-	case types.OperatorTypes.LessThan:
-		return fileSize < wantedFileSize, nil // Changed lowerBound to fileSize
-	case types.OperatorTypes.LessThanEqualTo:
-		return fileSize <= wantedFileSize, nil // Changed upperBound to fileSize
-	case types.OperatorTypes.GreaterThan:
-		return fileSize > wantedFileSize, nil // Changed upperBound to fileSize
-	case types.OperatorTypes.GreaterThanEqualTo:
-		return fileSize >= wantedFileSize, nil // Changed lowerBound to fileSize
-	default:
-		return fileSize >= results.LowerBoundSize && fileSize <= results.UpperBoundSize, nil
+	return GetOperatorSizeMatchesWithTolerance(operator, wantedFileSize, tolerance, fileSize, upperFileSize...)
+}
+
+// The function `GetOperatorSizeMatchesWithTolerance` is like `GetOperatorSizeMatches` but takes the
+// tolerance as a `types.Tolerance`, which `ParseTolerance` reads from strings such as `5%` or
+// `-1MB/+50MB`. A percentage is taken of the size it widens, so for the range operators the lower bound
+// is widened by a percentage of wantedFileSize and the upper bound by a percentage of upperFileSize.
+//
+// Example usage:
+//
+//	// 700MB, give or take 5%.
+//	tolerance, _ := utils.ParseTolerance("5%")
+//	matches, err := utils.GetOperatorSizeMatchesWithTolerance(types.OperatorTypes.EqualTo, 700*1024*1024, tolerance, size)
+func GetOperatorSizeMatchesWithTolerance(operator types.OperatorType, wantedFileSize int64, tolerance types.Tolerance, fileSize int64, upperFileSize ...int64) (bool, error) {
+	results, err := CalculateToleranceBounds(wantedFileSize, tolerance)
+	if err != nil {
+		return false, fmt.Errorf("error calculating tolerances %w", err)
+	}
+
+	upperBound, err := operators.UpperBound(operator, cmp.Compare[int64], wantedFileSize, upperFileSize)
+	if err != nil {
+		return false, err
 	}
+	if operators.IsRange(operator) {
+		upper, err := CalculateToleranceBounds(upperBound, tolerance)
+		if err != nil {
+			return false, fmt.Errorf("error calculating tolerances %w", err)
+		}
+		results.UpperBoundSize = upper.UpperBoundSize
+	}
+
+	return operators.Evaluate(operator, cmp.Compare(fileSize, wantedFileSize), cmp.Compare(fileSize, results.LowerBoundSize), cmp.Compare(fileSize, results.UpperBoundSize)), nil
 }
 
 // The CalculateTolerances function calculates upper and lower bounds based on a wanted file size and
-// tolerance size in bytes.
+// tolerance size in KB. Bounds that would overflow an int64 are clamped to `math.MaxInt64`.
 func CalculateTolerances(wantedFileSize int64, toleranceSize float64) (types.ToleranceResults, error) {
 	// Check for invalid input values
 	if wantedFileSize < 0 {
 		return types.ToleranceResults{}, fmt.Errorf("wantedFileSize cannot be negative")
 	}
-	if toleranceSize < 0 {
-		return types.ToleranceResults{}, fmt.Errorf("toleranceSize cannot be negative")
+	tolerance, err := kilobyteTolerance(toleranceSize)
+	if err != nil {
+		return types.ToleranceResults{}, err
 	}
 
-	// Calculate tolerance in bytes (using int64 directly)
-	toleranceBytes := int64(toleranceSize * 1024)
+	return CalculateToleranceBounds(wantedFileSize, tolerance)
+}
 
-	// Calculate upper and lower bounds
-	upperBoundSize := wantedFileSize + toleranceBytes
-	lowerBoundSize := wantedFileSize - toleranceBytes
+// The CalculateToleranceBounds function calculates upper and lower bounds based on a wanted file size
+// and a tolerance in bytes or percent of the wanted file size. The lower bound does not go below zero
+// and the upper bound is clamped to `math.MaxInt64` rather than overflowing.
+func CalculateToleranceBounds(wantedFileSize int64, tolerance types.Tolerance) (types.ToleranceResults, error) {
+	if wantedFileSize < 0 {
+		return types.ToleranceResults{}, fmt.Errorf("wantedFileSize cannot be negative")
+	}
+
+	lower, err := resolveToleranceBound(wantedFileSize, tolerance.Lower)
+	if err != nil {
+		return types.ToleranceResults{}, err
+	}
+	upper, err := resolveToleranceBound(wantedFileSize, tolerance.Upper)
+	if err != nil {
+		return types.ToleranceResults{}, err
+	}
 
 	// Ensure lower bound does not go below zero
+	lowerBoundSize := wantedFileSize - lower
 	if lowerBoundSize < 0 {
 		lowerBoundSize = 0
 	}
 
-	// Return the calculated tolerance results
 	return types.ToleranceResults{
-		ToleranceSize:  toleranceBytes,
-		UpperBoundSize: upperBoundSize,
-		LowerBoundSize: lowerBoundSize,
+		ToleranceSize:      upper,
+		LowerToleranceSize: lower,
+		UpperToleranceSize: upper,
+		UpperBoundSize:     addClamped(wantedFileSize, upper),
+		LowerBoundSize:     lowerBoundSize,
 	}, nil
 }
 
-// The function `ConvertStringSizeToBytes` converts a string representation of size with units to
+// The function `ParseTolerance` reads a tolerance from a string. A tolerance is a percentage such as
+// `5%`, a size such as `50MB` as read by `ConvertStringSizeToBytes`, or a number of bytes such as
+// `512`. It applies below and above the wanted size, unless it is prefixed with `-` for below only or
+// `+` for above only, a `±` prefix is allowed and changes nothing. Two tolerances separated by `/` or
+// `,` set both sides separately.
+//
+// Example usage:
+//
+//	tolerance, err := utils.ParseTolerance("-1MB/+5%")
+//	// tolerance.Lower = {Bytes: 1048576}, tolerance.Upper = {Percent: 5}
+func ParseTolerance(toleranceStr string) (types.Tolerance, error) {
+	toleranceStr = strings.TrimSpace(toleranceStr)
+	if toleranceStr == "" {
+		return types.Tolerance{}, errors.New("tolerance cannot be empty")
+	}
+
+	parts := strings.FieldsFunc(toleranceStr, func(r rune) bool { return r == '/' || r == ',' })
+	if len(parts) > 2 {
+		return types.Tolerance{}, fmt.Errorf("tolerance %q has more than two parts", toleranceStr)
+	}
+
+	var tolerance types.Tolerance
+	var setLower, setUpper bool
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		lower, upper := true, true
+		switch {
+		case strings.HasPrefix(part, "±"):
+			part = strings.TrimPrefix(part, "±")
+		case strings.HasPrefix(part, "-"):
+			part, upper = part[1:], false
+		case strings.HasPrefix(part, "+"):
+			part, lower = part[1:], false
+		}
+
+		bound, err := parseToleranceBound(strings.TrimSpace(part))
+		if err != nil {
+			return types.Tolerance{}, fmt.Errorf("invalid tolerance %q: %w", toleranceStr, err)
+		}
+		if (lower && setLower) || (upper && setUpper) {
+			return types.Tolerance{}, fmt.Errorf("tolerance %q sets a bound twice", toleranceStr)
+		}
+		if lower {
+			tolerance.Lower, setLower = bound, true
+		}
+		if upper {
+			tolerance.Upper, setUpper = bound, true
+		}
+	}
+
+	return tolerance, nil
+}
+
+// parseToleranceBound reads one side of a tolerance: a percentage, a size with a unit or a number of
 // bytes.
+func parseToleranceBound(boundStr string) (types.ToleranceBound, error) {
+	if percentStr, ok := strings.CutSuffix(boundStr, "%"); ok {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(percentStr), 64)
+		if err != nil || math.IsNaN(percent) || math.IsInf(percent, 0) || percent < 0 {
+			return types.ToleranceBound{}, fmt.Errorf("invalid percentage %q", boundStr)
+		}
+		return types.ToleranceBound{Percent: percent}, nil
+	}
+
+	bytes, err := strconv.ParseInt(boundStr, 10, 64)
+	if err != nil {
+		bytes, err = ConvertStringSizeToBytes(boundStr)
+	}
+	if err != nil {
+		return types.ToleranceBound{}, err
+	}
+	if bytes < 0 {
+		return types.ToleranceBound{}, errors.New("size cannot be negative")
+	}
+	return types.ToleranceBound{Bytes: bytes}, nil
+}
+
+// kilobyteTolerance converts a symmetric tolerance in KB to a `types.Tolerance`, clamping it to
+// `math.MaxInt64` bytes.
+func kilobyteTolerance(toleranceSize float64) (types.Tolerance, error) {
+	if toleranceSize < 0 || math.IsNaN(toleranceSize) {
+		return types.Tolerance{}, fmt.Errorf("toleranceSize cannot be negative")
+	}
+
+	bound := types.ToleranceBound{Bytes: clampToInt64(toleranceSize * 1024)}
+	return types.Tolerance{Lower: bound, Upper: bound}, nil
+}
+
+// resolveToleranceBound returns the size in bytes bound allows around wantedFileSize.
+func resolveToleranceBound(wantedFileSize int64, bound types.ToleranceBound) (int64, error) {
+	if bound.Bytes < 0 || bound.Percent < 0 || math.IsNaN(bound.Percent) {
+		return 0, fmt.Errorf("tolerance cannot be negative")
+	}
+
+	return addClamped(bound.Bytes, clampToInt64(float64(wantedFileSize)*bound.Percent/100)), nil
+}
+
+// addClamped adds two non-negative sizes, returning `math.MaxInt64` when the sum would overflow.
+func addClamped(a, b int64) int64 {
+	if b > math.MaxInt64-a {
+		return math.MaxInt64
+	}
+	return a + b
+}
+
+// clampToInt64 truncates a non-negative size to an int64, returning `math.MaxInt64` for sizes an int64
+// cannot hold.
+func clampToInt64(size float64) int64 {
+	if size >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(size)
+}
+
+// The function `ConvertStringSizeToBytes` converts a string representation of size with units to
+// bytes. Units are binary and matched without regard to case, so `1 kb` is 1024 bytes. See
+// `sizes.Parse` for SI units, IEC labels such as `KiB` and bits.
 func ConvertStringSizeToBytes(sizeStr string) (int64, error) {
 	var err error
 
@@ -257,6 +940,12 @@ func ConvertStringSizeToBytes(sizeStr string) (int64, error) {
 	// for _, unit := range types.Units {
 	for _, unit := range types.SizeUnits {
 		if unit.Label == unitStr {
+			if num < 0 {
+				return 0, errors.New("size cannot be negative")
+			}
+			if num*float64(unit.Size) >= math.MaxInt64 {
+				return 0, errors.New("size overflows int64")
+			}
 			return int64(num * float64(unit.Size)), nil
 		}
 	}
@@ -264,10 +953,12 @@ func ConvertStringSizeToBytes(sizeStr string) (int64, error) {
 	return 0, errors.New("invalid size unit")
 }
 
-// The function `RemoveEmptyDir` checks if a directory is empty and removes it if it is.
+// The function `RemoveEmptyDir` checks if a directory is empty and removes it if it is. A symlink is
+// never removed, even when it points to an empty directory. It applies no ignore rules, callers
+// holding a `types.PathMatcher` should check path themselves or use `PruneEmptyDirs`.
 func RemoveEmptyDir(path string, ops types.DirOps) (bool, error) {
-	// Check if the directory exists
-	fileInfo, err := osStatFunc(path)
+	// Check if the directory exists, without following a symlink
+	fileInfo, err := ops.Lstat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return false, fmt.Errorf("directory does not exist: %w", err)
@@ -275,6 +966,10 @@ func RemoveEmptyDir(path string, ops types.DirOps) (bool, error) {
 		return false, err
 	}
 
+	if fileInfo.Mode()&os.ModeSymlink != 0 {
+		return false, fmt.Errorf("%s is a symlink, not a directory", path)
+	}
+
 	// Check if it's a directory
 	if !fileInfo.IsDir() {
 		return false, fmt.Errorf("%s is not a directory", path)
@@ -300,6 +995,62 @@ func RemoveEmptyDir(path string, ops types.DirOps) (bool, error) {
 	return true, nil
 }
 
+// The function `ResolveSymlink` returns the file information a walk should use for path under policy.
+// Entries that are not symlinks are returned as they are. A symlink is checked first, whatever the
+// policy, and an error wrapping `ErrBrokenLink` is returned when its target cannot be resolved. After
+// that `SymlinkPolicies.Follow` returns the target, `SymlinkPolicies.File` returns the link itself and
+// `SymlinkPolicies.Skip`, or an empty policy, returns nil to tell the caller to leave the link out.
+func ResolveSymlink(path string, ops types.DirOps, policy types.SymlinkPolicy) (os.FileInfo, error) {
+	linkInfo, err := ops.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if linkInfo.Mode()&os.ModeSymlink == 0 {
+		return linkInfo, nil
+	}
+
+	targetInfo, err := ops.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %w", ErrBrokenLink, path, err)
+	}
+
+	switch policy {
+	case types.SymlinkPolicies.Follow:
+		return targetInfo, nil
+	case types.SymlinkPolicies.File:
+		return linkInfo, nil
+	default:
+		return nil, nil
+	}
+}
+
+// The function `IsSymlinkLoop` reports whether target, the directory a followed symlink points to, is
+// one of the ancestors of the link, which would make the walk loop forever.
+func IsSymlinkLoop(ancestors []os.FileInfo, target os.FileInfo) bool {
+	for _, ancestor := range ancestors {
+		if SameFile(ancestor, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// The function `SameFile` reports whether a and b describe the same file. It uses `os.SameFile` for
+// the real filesystem and falls back to comparing `Sys` values, which lets other `types.DirOps`
+// implementations identify their entries.
+func SameFile(a, b os.FileInfo) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if os.SameFile(a, b) {
+		return true
+	}
+
+	// Only pointers identify an entry, values are copies.
+	sysA := a.Sys()
+	return sysA != nil && reflect.TypeOf(sysA).Kind() == reflect.Ptr && sysA == b.Sys()
+}
+
 // InRange checks if a target string matches any string in the options slice.
 // It uses the ToLower function to ensure case-insensitive comparison.
 // It returns a boolean indicating if a match is found and an error if any conversion fails.
@@ -334,3 +1085,134 @@ func InRange(target interface{}, options interface{}) (bool, error) {
 
 	return false, nil
 }
+
+// The function `PruneEmptyDirs` walks the tree below root depth-first and removes every directory that
+// is empty, or becomes empty once its empty children have been removed. Removal is done through
+// `RemoveEmptyDir` so each directory is re-checked before it is deleted. Symlinks are never removed,
+// with `SymlinkPolicies.Follow` the directories they point to are pruned like any other, which can
+// remove directories outside root.
+func PruneEmptyDirs(root string, ops types.DirOps, opts types.PruneOptions) (types.PruneReport, error) {
+	report := types.PruneReport{}
+
+	fileInfo, err := ResolveSymlink(root, ops, opts.Symlinks)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return report, fmt.Errorf("directory does not exist: %w", err)
+		}
+		return report, err
+	}
+
+	if fileInfo == nil {
+		return report, fmt.Errorf("%s is a symlink, not a directory", root)
+	}
+
+	if !fileInfo.IsDir() {
+		return report, fmt.Errorf("%s is not a directory", root)
+	}
+
+	var ancestors []os.FileInfo
+	if opts.Symlinks == types.SymlinkPolicies.Follow {
+		ancestors = []os.FileInfo{fileInfo}
+	}
+
+	// A followed root symlink is kept like any other symlink.
+	keep := opts.KeepRoot
+	if linkInfo, err := ops.Lstat(root); err == nil && linkInfo.Mode()&os.ModeSymlink != 0 {
+		keep = true
+	}
+	pruneDir(root, 0, ancestors, keep, ops, opts, &report)
+
+	return report, nil
+}
+
+// pruneDir prunes path and its children, it returns true when path was (or would be) removed. keep
+// leaves path itself in place. ancestors holds the directories above path and is only tracked when
+// symlinks are followed, to detect loops.
+func pruneDir(path string, depth int, ancestors []os.FileInfo, keep bool, ops types.DirOps, opts types.PruneOptions, report *types.PruneReport) bool {
+	entries, err := ops.ReadDir(path)
+	if err != nil {
+		report.Failed = append(report.Failed, types.PruneFailure{Path: path, Err: err})
+		return false
+	}
+
+	follow := opts.Symlinks == types.SymlinkPolicies.Follow
+	remaining := 0
+	for _, entry := range entries {
+		child := filepath.Join(path, entry.Name())
+		isLink := entry.Type()&os.ModeSymlink != 0
+
+		var info os.FileInfo
+		if isLink {
+			// The link itself is never removed, so it always counts as content.
+			remaining++
+			info, err = ResolveSymlink(child, ops, opts.Symlinks)
+			if errors.Is(err, ErrBrokenLink) {
+				report.BrokenLinks = append(report.BrokenLinks, child)
+				continue
+			}
+			if err != nil {
+				report.Failed = append(report.Failed, types.PruneFailure{Path: child, Err: err})
+				continue
+			}
+			if !follow || !info.IsDir() {
+				continue
+			}
+		} else if !entry.IsDir() {
+			remaining++
+			continue
+		}
+
+		if opts.Ignore != nil && opts.Ignore.Match(child, true) {
+			report.Skipped = append(report.Skipped, child)
+			if !isLink {
+				remaining++
+			}
+			continue
+		}
+		if opts.MaxDepth > 0 && depth+1 > opts.MaxDepth {
+			report.Skipped = append(report.Skipped, child)
+			if !isLink {
+				remaining++
+			}
+			continue
+		}
+
+		var childAncestors []os.FileInfo
+		if follow {
+			if info == nil {
+				if info, err = entry.Info(); err != nil {
+					report.Failed = append(report.Failed, types.PruneFailure{Path: child, Err: err})
+					remaining++
+					continue
+				}
+			}
+			if isLink && IsSymlinkLoop(ancestors, info) {
+				report.Failed = append(report.Failed, types.PruneFailure{Path: child, Err: fmt.Errorf("%w: %s", ErrSymlinkLoop, child)})
+				continue
+			}
+			childAncestors = append(append([]os.FileInfo(nil), ancestors...), info)
+		}
+
+		if !pruneDir(child, depth+1, childAncestors, isLink, ops, opts, report) && !isLink {
+			remaining++
+		}
+	}
+
+	if remaining > 0 || keep {
+		report.Skipped = append(report.Skipped, path)
+		return false
+	}
+
+	if opts.DryRun {
+		report.Removed = append(report.Removed, path)
+		return true
+	}
+
+	if _, err := RemoveEmptyDir(path, ops); err != nil {
+		report.Failed = append(report.Failed, types.PruneFailure{Path: path, Err: err})
+		return false
+	}
+
+	report.Removed = append(report.Removed, path)
+	return true
+}
@@ -3,18 +3,24 @@ package utils
 import (
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"reflect"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ondrovic/common/types"
+	"github.com/ondrovic/common/utils/filetypes"
 	"github.com/ondrovic/common/utils/formatters"
+	"github.com/ondrovic/common/utils/ignore"
+	"github.com/ondrovic/common/utils/memfs"
 )
 
-// The MockDirOps type is used for mocking directory operations in Go code.
+// The MockDirOps type is used for mocking directory operations in Go code. It embeds
+// `types.RealDirOps` so any operation without a simulated error falls through to the real filesystem.
 // @property {error} readDirErr - The `readDirErr` property in the `MockDirOps` struct is used to store
 // an error that may occur when atestempting to read a directory. This error could be related to issues
 // such as permission problems, directory not found, or any other error that may occur during the
@@ -23,9 +29,13 @@ import (
 // an error that may occur when atestempting to remove a directory. This error could be related to
 // permissions, file system issues, or any other problem that prevents the directory from being removed
 // successfully.
+// @property {error} statErr - The `statErr` property in the `MockDirOps` struct is used to store an
+// error returned from `Stat` and `Lstat` in place of the real file information.
 type MockDirOps struct {
+	types.RealDirOps
 	readDirErr error
 	removeErr  error
+	statErr    error
 }
 
 func (m *MockDirOps) ReadDir(_ string) ([]os.DirEntry, error) {
@@ -39,6 +49,20 @@ func (m *MockDirOps) Remove(_ string) error {
 	return m.removeErr
 }
 
+func (m *MockDirOps) Stat(name string) (os.FileInfo, error) {
+	if m.statErr != nil {
+		return nil, m.statErr
+	}
+	return m.RealDirOps.Stat(name)
+}
+
+func (m *MockDirOps) Lstat(name string) (os.FileInfo, error) {
+	if m.statErr != nil {
+		return nil, m.statErr
+	}
+	return m.RealDirOps.Lstat(name)
+}
+
 // CreateTempFile creates a temporary file for testing.
 func CreateTempFile(t *testing.T) *os.File {
 	t.Helper()
@@ -175,6 +199,224 @@ func TestValidateStruct(t *testing.T) {
 	}
 }
 
+// TestValidateStruct_Tags tests the rules of `validate` struct tags.
+func TestValidateStruct_Tags(t *testing.T) {
+	dir := t.TempDir()
+
+	type Server struct {
+		Host string `validate:"required"`
+		Port int    `validate:"min=1,max=65535"`
+	}
+
+	type Node struct {
+		Name string `validate:"required"`
+		Next *Node
+	}
+
+	type Config struct {
+		Name     string            `validate:"required,max=8"`
+		Mode     string            `validate:"omitempty,oneof=fast safe"`
+		Code     string            `validate:"omitempty,len=3"`
+		Pattern  string            `validate:"omitempty,regexp=^[a-z]{2,4}$"`
+		Webhook  string            `validate:"omitempty,url"`
+		Contact  string            `validate:"omitempty,email"`
+		Root     string            `validate:"omitempty,path-exists"`
+		Timeout  time.Duration     `validate:"omitempty,min=1s"`
+		Ratio    *float64          `validate:"omitempty,max=1"`
+		Servers  []Server          `validate:"omitempty,max=2"`
+		Backup   *Server           `validate:"omitempty"`
+		Labels   map[string]Server `validate:"omitempty"`
+		Head     *Node
+		Ignored  string `validate:"-"`
+		internal string
+	}
+
+	valid := func(edit func(config *Config)) Config {
+		config := Config{Name: "app"}
+		edit(&config)
+		return config
+	}
+	ratio := 1.5
+	loop := &Node{Name: "a"}
+	loop.Next = loop
+
+	tests := []*types.TestLayout[Config, string]{
+		{Name: "Valid", Input: valid(func(c *Config) {
+			c.Mode, c.Code, c.Pattern, c.Webhook, c.Contact, c.Root = "safe", "abc", "abc", "https://example.com/hook", "ops@example.com", dir
+			c.Timeout, c.Servers, c.Head = time.Minute, []Server{{Host: "a", Port: 80}}, loop
+		})},
+		{Name: "Required", Input: Config{}, Expected: "Name cannot be empty"},
+		{Name: "Max characters", Input: valid(func(c *Config) { c.Name = "application" }), Expected: "Name must be at most 8 characters"},
+		{Name: "One of", Input: valid(func(c *Config) { c.Mode = "slow" }), Expected: `Mode must be one of fast, safe, got "slow"`},
+		{Name: "Len", Input: valid(func(c *Config) { c.Code = "abcd" }), Expected: "Code must be exactly 3 characters"},
+		{Name: "Regexp with comma", Input: valid(func(c *Config) { c.Pattern = "abcde" }), Expected: "Pattern must match ^[a-z]{2,4}$"},
+		{Name: "URL", Input: valid(func(c *Config) { c.Webhook = "example.com" }), Expected: `Webhook must be a URL, got "example.com"`},
+		{Name: "Email", Input: valid(func(c *Config) { c.Contact = "Ops <ops@example.com>" }), Expected: `Contact must be an email address, got "Ops <ops@example.com>"`},
+		{Name: "Path exists", Input: valid(func(c *Config) { c.Root = filepath.Join(dir, "missing") }), Expected: fmt.Sprintf("Root must be an existing path, got %q", filepath.Join(dir, "missing"))},
+		{Name: "Duration min", Input: valid(func(c *Config) { c.Timeout = time.Millisecond }), Expected: "Timeout must be at least 1s"},
+		{Name: "Pointer target", Input: valid(func(c *Config) { c.Ratio = &ratio }), Expected: "Ratio must be at most 1"},
+		{Name: "Slice length", Input: valid(func(c *Config) { c.Servers = []Server{{"a", 1}, {"b", 2}, {"c", 3}} }), Expected: "Servers must be at most 2 items"},
+		{Name: "Slice element", Input: valid(func(c *Config) { c.Servers = []Server{{Host: "a", Port: 80}, {Host: "b"}} }), Expected: "Servers[1].Port must be at least 1"},
+		{Name: "Pointer to struct", Input: valid(func(c *Config) { c.Backup = &Server{Port: 22} }), Expected: "Backup.Host cannot be empty"},
+		{Name: "Map value", Input: valid(func(c *Config) { c.Labels = map[string]Server{"b": {Host: "b", Port: 1}, "a": {Host: "a"}} }), Expected: "Labels[a].Port must be at least 1"},
+		{Name: "Untagged nested pointer", Input: valid(func(c *Config) { c.Head = &Node{Name: "a", Next: &Node{}} }), Expected: "Head.Next.Name cannot be empty"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := ValidateStruct(test.Input)
+			if test.Expected == "" {
+				if err != nil {
+					t.Errorf("ValidateStruct() - %v error = %v, expected nil", test.Name, err)
+				}
+			} else if err == nil || err.Error() != test.Expected {
+				t.Errorf("ValidateStruct() - %v error = %v, expected %v", test.Name, err, test.Expected)
+			}
+		})
+	}
+}
+
+// TestValidateStruct_Errors tests that every failure is reported with its path, rule and value.
+func TestValidateStruct_Errors(t *testing.T) {
+	type Colors struct {
+		Background string `validate:"required"`
+		Foreground string `validate:"oneof=white black"`
+	}
+
+	type Styles struct {
+		Color Colors
+	}
+
+	type App struct {
+		Name    string
+		Style   Styles
+		Retries int `validate:"max=5"`
+	}
+
+	err := ValidateStruct(App{Style: Styles{Color: Colors{Foreground: "red"}}, Retries: 9})
+
+	var failures ValidationErrors
+	if !errors.As(err, &failures) {
+		t.Fatalf("ValidateStruct() error = %v; want ValidationErrors", err)
+	}
+	expected := ValidationErrors{
+		{Path: "Name", Field: "Name", Rule: "required", Value: "", Message: "Name cannot be empty"},
+		{Path: "Style.Color.Background", Field: "Background", Rule: "required", Value: "", Message: "Style.Color.Background cannot be empty"},
+		{Path: "Style.Color.Foreground", Field: "Foreground", Rule: "oneof", Param: "white black", Value: "red", Message: `Style.Color.Foreground must be one of white, black, got "red"`},
+		{Path: "Retries", Field: "Retries", Rule: "max", Param: "5", Value: 9, Message: "Retries must be at most 5"},
+	}
+	if !reflect.DeepEqual(failures, expected) {
+		t.Errorf("ValidateStruct() failures = %+v; want %+v", failures, expected)
+	}
+
+	tests := []*types.TestLayout[error, bool]{
+		{Name: "Validation sentinel", Input: ErrValidation, Expected: true},
+		{Name: "Rule", Input: &ValidationError{Rule: "oneof"}, Expected: true},
+		{Name: "Path", Input: &ValidationError{Path: "Style.Color.Background"}, Expected: true},
+		{Name: "Path and rule", Input: &ValidationError{Path: "Retries", Rule: "max"}, Expected: true},
+		{Name: "Path and other rule", Input: &ValidationError{Path: "Retries", Rule: "min"}, Expected: false},
+		{Name: "Other error", Input: ErrBrokenLink, Expected: false},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if result := errors.Is(err, test.Input); result != test.Expected {
+				t.Errorf("errors.Is(err, %v) - %v = %v; want %v", test.Input, test.Name, result, test.Expected)
+			}
+		})
+	}
+
+	var failure *ValidationError
+	if !errors.As(err, &failure) || failure.Path != "Name" {
+		t.Errorf("errors.As(err, *ValidationError) = %+v; want the Name failure", failure)
+	}
+	if err := ValidateStruct(App{Name: "app", Style: Styles{Color: Colors{Background: "red", Foreground: "white"}}}); err != nil {
+		t.Errorf("ValidateStruct() error = %v; want nil", err)
+	}
+}
+
+// TestValidateStructWithOps tests that `path-exists` rules check paths through the given DirOps.
+func TestValidateStructWithOps(t *testing.T) {
+	type Config struct {
+		Root string `validate:"path-exists"`
+	}
+
+	ops := memfs.New()
+	if err := ops.MkdirAll("/media/videos", 0o755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	tests := []*types.TestLayout[string, string]{
+		{Name: "Path in memfs", Input: "/media/videos"},
+		{Name: "Path missing from memfs", Input: "/media/photos", Expected: `Root must be an existing path, got "/media/photos"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := ValidateStructWithOps(Config{Root: test.Input}, ops)
+			if test.Expected == "" {
+				if err != nil {
+					t.Errorf("ValidateStructWithOps() - %v error = %v, expected nil", test.Name, err)
+				}
+			} else if err == nil || err.Error() != test.Expected {
+				t.Errorf("ValidateStructWithOps() - %v error = %v, expected %v", test.Name, err, test.Expected)
+			}
+		})
+	}
+}
+
+// TestValidateStruct_Cycles tests that maps and slices holding themselves are validated once.
+func TestValidateStruct_Cycles(t *testing.T) {
+	type Server struct {
+		Host string `validate:"required"`
+	}
+
+	type Config struct {
+		Name  string
+		Map   map[string]interface{} `validate:"omitempty"`
+		Slice []interface{}          `validate:"omitempty"`
+	}
+
+	m := map[string]interface{}{"server": Server{}}
+	m["self"] = m
+	slice := []interface{}{nil, Server{}}
+	slice[0] = slice
+
+	err := ValidateStruct(Config{Name: "app", Map: m, Slice: slice})
+	expected := "Map[server].Host cannot be empty\nSlice[1].Host cannot be empty"
+	if err == nil || err.Error() != expected {
+		t.Errorf("ValidateStruct() error = %v, expected %v", err, expected)
+	}
+}
+
+// TestValidateStruct_InvalidTags tests that malformed `validate` tags are reported.
+func TestValidateStruct_InvalidTags(t *testing.T) {
+	tests := []*types.TestLayout[interface{}, string]{
+		{Name: "Unknown rule", Input: struct {
+			Name string `validate:"requird"`
+		}{Name: "a"}, Expected: `invalid validate tag on Name: unknown rule "requird"`},
+		{Name: "Missing value", Input: struct {
+			Name string `validate:"min"`
+		}{Name: "a"}, Expected: `invalid validate tag on Name: rule "min" needs a value`},
+		{Name: "Unexpected value", Input: struct {
+			Name string `validate:"required=true"`
+		}{Name: "a"}, Expected: `invalid validate tag on Name: rule "required" does not take a value`},
+		{Name: "Bound is not a number", Input: struct {
+			Name string `validate:"max=ten"`
+		}{Name: "a"}, Expected: "invalid validate tag on Name: max=ten is not a number"},
+		{Name: "Rule does not fit the kind", Input: struct {
+			Enabled bool `validate:"min=1"`
+		}{Enabled: true}, Expected: `invalid validate tag on Enabled: rule "min" cannot check a bool`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if err := ValidateStruct(test.Input); err == nil || err.Error() != test.Expected {
+				t.Errorf("ValidateStruct() - %v error = %v, expected %v", test.Name, err, test.Expected)
+			}
+		})
+	}
+}
+
 // TestToFileType tests ToFileType func.
 func TestToFileType(t *testing.T) {
 	tests := []*types.TestLayout[string, types.FileType]{
@@ -187,7 +429,32 @@ func TestToFileType(t *testing.T) {
 		{Name: "Test mixed case", Input: "ViDeO", Expected: types.FileTypes.Video, Err: nil},   // Mixed case check
 		{Name: "Test invalid input", Input: "invalid", Expected: "", Err: nil},                 // Invalid input check
 		{Name: "Test empty input", Input: "", Expected: "", Err: nil},                          // Empty string check
+		{Name: "Test alias", Input: "Docs", Expected: types.FileTypes.Documents, Err: nil},
+		{Name: "Test audio type", Input: "audio", Expected: types.FileTypes.Audio, Err: nil},
+		{Name: "Test audio alias", Input: "Music", Expected: types.FileTypes.Audio, Err: nil},
+		{Name: "Test code type", Input: "code", Expected: types.FileTypes.Code, Err: nil},
+		{Name: "Test fonts type", Input: "fonts", Expected: types.FileTypes.Fonts, Err: nil},
+		{Name: "Test ebooks type", Input: "ebooks", Expected: types.FileTypes.Ebooks, Err: nil},
+		{Name: "Test executables type", Input: "executables", Expected: types.FileTypes.Executables, Err: nil},
+		{Name: "Test installers alias", Input: "installers", Expected: types.FileTypes.Executables, Err: nil},
+		{Name: "Test disk images type", Input: "DiskImages", Expected: types.FileTypes.DiskImages, Err: nil},
+		{Name: "Test disk images alias with a space", Input: "disk images", Expected: types.FileTypes.DiskImages, Err: nil},
+		{Name: "Test subtitles type", Input: "subtitles", Expected: types.FileTypes.Subtitles, Err: nil},
+		{Name: "Test subtitles alias", Input: "SUBS", Expected: types.FileTypes.Subtitles, Err: nil},
+		{Name: "Test registered type", Input: "lyrics", Expected: "Lyrics", Err: nil},
+		{Name: "Test registered alias", Input: "LRC", Expected: "Lyrics", Err: nil},
+		{Name: "Test mime wildcard", Input: "video/*", Expected: types.FileTypes.Video, Err: nil},
+		{Name: "Test mime type", Input: "Application/PDF", Expected: types.FileTypes.Documents, Err: nil},
+		{Name: "Test any mime type", Input: "*/*", Expected: types.FileTypes.Any, Err: nil},
+		{Name: "Test ambiguous mime wildcard", Input: "application/*", Expected: "", Err: nil},
+		{Name: "Test audio mime type", Input: "audio/mpeg", Expected: types.FileTypes.Audio, Err: nil},
+		{Name: "Test unknown mime type", Input: "chemical/x-pdb", Expected: "", Err: nil},
+	}
+
+	if err := filetypes.Register(types.FileTypeDefinition{Name: "Lyrics", Aliases: []string{"lrc"}, Extensions: []string{".lrc"}}); err != nil {
+		t.Fatalf("filetypes.Register() error = %v", err)
 	}
+	defer filetypes.Unregister("Lyrics")
 
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) { // Run each test case as a sub-test
@@ -234,6 +501,7 @@ func TestToOperatorType(t *testing.T) {
 		{Name: `Test equalto`, Input: "equalto", Expected: types.OperatorTypes.EqualTo, Err: nil},
 		{Name: "Test equal", Input: "equal", Expected: types.OperatorTypes.EqualTo, Err: nil},
 		{Name: "Test ==", Input: "==", Expected: types.OperatorTypes.EqualTo, Err: nil},
+		{Name: "Test =", Input: "=", Expected: types.OperatorTypes.EqualTo, Err: nil},
 
 		{Name: "Test gt", Input: "gt", Expected: types.OperatorTypes.GreaterThan, Err: nil},
 		{Name: "Test greater than", Input: "greater than", Expected: types.OperatorTypes.GreaterThan, Err: nil},
@@ -255,6 +523,28 @@ func TestToOperatorType(t *testing.T) {
 		{Name: "Test lessthanorequalto", Input: "lessthanorequalto", Expected: types.OperatorTypes.LessThanEqualTo, Err: nil},
 		{Name: "Test <=", Input: "<=", Expected: types.OperatorTypes.LessThanEqualTo, Err: nil},
 
+		{Name: "Test ne", Input: "ne", Expected: types.OperatorTypes.NotEqualTo, Err: nil},
+		{Name: "Test not equal to", Input: "not equal to", Expected: types.OperatorTypes.NotEqualTo, Err: nil},
+		{Name: "Test !=", Input: "!=", Expected: types.OperatorTypes.NotEqualTo, Err: nil},
+		{Name: "Test <>", Input: "<>", Expected: types.OperatorTypes.NotEqualTo, Err: nil},
+
+		{Name: "Test bt", Input: "bt", Expected: types.OperatorTypes.Between, Err: nil},
+		{Name: "Test between", Input: "Between", Expected: types.OperatorTypes.Between, Err: nil},
+		{Name: "Test ..", Input: "..", Expected: types.OperatorTypes.Between, Err: nil},
+		{Name: "Test [..]", Input: "[..]", Expected: types.OperatorTypes.Between, Err: nil},
+
+		{Name: "Test btx", Input: "btx", Expected: types.OperatorTypes.BetweenExclusive, Err: nil},
+		{Name: "Test between exclusive", Input: "between exclusive", Expected: types.OperatorTypes.BetweenExclusive, Err: nil},
+		{Name: "Test (..)", Input: "(..)", Expected: types.OperatorTypes.BetweenExclusive, Err: nil},
+
+		{Name: "Test nbt", Input: "nbt", Expected: types.OperatorTypes.NotBetween, Err: nil},
+		{Name: "Test not between", Input: "not between", Expected: types.OperatorTypes.NotBetween, Err: nil},
+		{Name: "Test !..", Input: "!..", Expected: types.OperatorTypes.NotBetween, Err: nil},
+
+		{Name: "Test nbtx", Input: "nbtx", Expected: types.OperatorTypes.NotBetweenExclusive, Err: nil},
+		{Name: "Test not between exclusive", Input: "not between exclusive", Expected: types.OperatorTypes.NotBetweenExclusive, Err: nil},
+		{Name: "Test !(..)", Input: "!(..)", Expected: types.OperatorTypes.NotBetweenExclusive, Err: nil},
+
 		{Name: "Test default case", Input: "", Expected: "", Err: nil},
 	}
 
@@ -322,6 +612,11 @@ func TestIsExtensionValid(t *testing.T) {
 		{Name: "Archive - valid extension .tar", Input: InputStruct{FileType: types.FileTypes.Archive, Path: "archive.tar"}, Expected: true},
 		{Name: "Archive - invalid extension .jpg", Input: InputStruct{FileType: types.FileTypes.Archive, Path: "image.jpg"}, Expected: false},
 		{Name: "Archive - empty extension", Input: InputStruct{FileType: types.FileTypes.Archive, Path: "archive"}, Expected: false},
+		{Name: "Archive - compound extension .tar.gz", Input: InputStruct{FileType: types.FileTypes.Archive, Path: "backup.TAR.GZ"}, Expected: true},
+		{Name: "Archive - multi-part .part1.rar", Input: InputStruct{FileType: types.FileTypes.Archive, Path: "movie.part1.rar"}, Expected: true},
+		{Name: "Archive - multi-part .7z.001", Input: InputStruct{FileType: types.FileTypes.Archive, Path: "backup.7z.001"}, Expected: true},
+		{Name: "Archive - multi-part .r00", Input: InputStruct{FileType: types.FileTypes.Archive, Path: "backup.r00"}, Expected: true},
+		{Name: "Archive - volume without number", Input: InputStruct{FileType: types.FileTypes.Archive, Path: "backup.7z.part"}, Expected: false},
 
 		// Tests for Documents file type
 		{Name: "Documents - valid extension .pdf", Input: InputStruct{FileType: types.FileTypes.Documents, Path: "document.pdf"}, Expected: true},
@@ -331,8 +626,36 @@ func TestIsExtensionValid(t *testing.T) {
 
 		// Test for fileType not present in FileExtensions map
 		{Name: "Unknown type - any extension", Input: InputStruct{FileType: "unknown_type", Path: "file.any"}, Expected: false},
+
+		// Tests for a registered file type
+		// Tests for the remaining built-in file types
+		{Name: "Audio - valid extension .flac", Input: InputStruct{FileType: types.FileTypes.Audio, Path: "song.FLAC"}, Expected: true},
+		{Name: "Audio - invalid extension .mp4", Input: InputStruct{FileType: types.FileTypes.Audio, Path: "video.mp4"}, Expected: false},
+		{Name: "Code - valid extension .go", Input: InputStruct{FileType: types.FileTypes.Code, Path: "main.go"}, Expected: true},
+		{Name: "Code - MPEG transport stream is not code", Input: InputStruct{FileType: types.FileTypes.Code, Path: "clip.ts"}, Expected: false},
+		{Name: "Fonts - valid extension .woff2", Input: InputStruct{FileType: types.FileTypes.Fonts, Path: "inter.woff2"}, Expected: true},
+		{Name: "Ebooks - valid extension .epub", Input: InputStruct{FileType: types.FileTypes.Ebooks, Path: "novel.epub"}, Expected: true},
+		{Name: "Ebooks - pdf is a document", Input: InputStruct{FileType: types.FileTypes.Ebooks, Path: "novel.pdf"}, Expected: false},
+		{Name: "Executables - valid extension .exe", Input: InputStruct{FileType: types.FileTypes.Executables, Path: "setup.exe"}, Expected: true},
+		{Name: "Executables - valid extension .deb", Input: InputStruct{FileType: types.FileTypes.Executables, Path: "tool_1.0_amd64.deb"}, Expected: true},
+		{Name: "DiskImages - valid extension .qcow2", Input: InputStruct{FileType: types.FileTypes.DiskImages, Path: "vm.qcow2"}, Expected: true},
+		{Name: "DiskImages - shared extension .iso", Input: InputStruct{FileType: types.FileTypes.DiskImages, Path: "ubuntu.iso"}, Expected: true},
+		{Name: "Archive - shared extension .iso", Input: InputStruct{FileType: types.FileTypes.Archive, Path: "ubuntu.iso"}, Expected: true},
+		{Name: "Subtitles - valid extension .srt", Input: InputStruct{FileType: types.FileTypes.Subtitles, Path: "movie.en.SRT"}, Expected: true},
+		{Name: "Subtitles - invalid extension .mp4", Input: InputStruct{FileType: types.FileTypes.Subtitles, Path: "movie.mp4"}, Expected: false},
+
+		// Tests for a registered file type
+		{Name: "Registered - valid extension .lrc", Input: InputStruct{FileType: "Lyrics", Path: "song.LRC"}, Expected: true},
+		{Name: "Registered - invalid extension .mp3", Input: InputStruct{FileType: "Lyrics", Path: "song.mp3"}, Expected: false},
+		{Name: "Registered - longest match wins", Input: InputStruct{FileType: "Lyrics", Path: "song.lrc.gz"}, Expected: true},
+		{Name: "Registered - shorter match loses", Input: InputStruct{FileType: types.FileTypes.Archive, Path: "song.lrc.gz"}, Expected: false},
 	}
 
+	if err := filetypes.Register(types.FileTypeDefinition{Name: "Lyrics", Extensions: []string{".lrc", ".lrc.gz"}}); err != nil {
+		t.Fatalf("filetypes.Register() error = %v", err)
+	}
+	defer filetypes.Unregister("Lyrics")
+
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) { // Run each test case as a sub-test
 			result := IsExtensionValid(test.Input.FileType, test.Input.Path)
@@ -353,6 +676,8 @@ func TestIsExtensionValid_ErrorFromToLowerWrapper(t *testing.T) {
 	}
 
 	// Define file types and extensions for testing
+	originalFileExtensions := types.FileExtensions
+	defer func() { types.FileExtensions = originalFileExtensions }()
 	fileType := types.FileType("exampleType")
 	types.FileExtensions = map[types.FileType]map[string]bool{
 		fileType: {
@@ -382,6 +707,72 @@ func TestIsExtensionValid_ErrorFromToLowerWrapper(t *testing.T) {
 	}
 }
 
+// TestDetectFileType tests DetectFileType func.
+func TestDetectFileType(t *testing.T) {
+	tests := []*types.TestLayout[string, types.FileType]{
+		{Name: "Video", Input: "movie.MKV", Expected: types.FileTypes.Video},
+		{Name: "Image", Input: "/photos/picture.jpeg", Expected: types.FileTypes.Image},
+		{Name: "Archive", Input: "backup.7z", Expected: types.FileTypes.Archive},
+		{Name: "Compound archive", Input: "backup.tar.bz2", Expected: types.FileTypes.Archive},
+		{Name: "Multi-part archive", Input: "backup.zip.002", Expected: types.FileTypes.Archive},
+		{Name: "Documents", Input: "notes.md", Expected: types.FileTypes.Documents},
+		{Name: "Audio", Input: "track01.m4a", Expected: types.FileTypes.Audio},
+		{Name: "Code", Input: "/src/main.py", Expected: types.FileTypes.Code},
+		{Name: "Fonts", Input: "Inter.OTF", Expected: types.FileTypes.Fonts},
+		{Name: "Ebooks", Input: "novel.mobi", Expected: types.FileTypes.Ebooks},
+		{Name: "Executables", Input: "program.exe", Expected: types.FileTypes.Executables},
+		{Name: "DiskImages", Input: "disk.vmdk", Expected: types.FileTypes.DiskImages},
+		{Name: "Shared extension goes to the first registered", Input: "ubuntu.iso", Expected: types.FileTypes.Archive},
+		{Name: "Subtitles", Input: "movie.en.srt", Expected: types.FileTypes.Subtitles},
+		{Name: "Unknown extension", Input: "program.xyz", Expected: types.FileTypes.Any},
+		{Name: "No extension", Input: "README", Expected: types.FileTypes.Any},
+		{Name: "Registered file type", Input: "song.lrc", Expected: "Lyrics"},
+	}
+
+	if err := filetypes.Register(types.FileTypeDefinition{Name: "Lyrics", Extensions: []string{".lrc"}}); err != nil {
+		t.Fatalf("filetypes.Register() error = %v", err)
+	}
+	defer filetypes.Unregister("Lyrics")
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result := DetectFileType(test.Input)
+			if result != test.Expected {
+				t.Errorf("DetectFileType(%q) - %v = %q; want %q", test.Input, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestSplitExtension tests SplitExtension func.
+func TestSplitExtension(t *testing.T) {
+	type ExpectedResults struct {
+		stem string
+		ext  string
+	}
+
+	tests := []*types.TestLayout[string, ExpectedResults]{
+		{Name: "Single extension", Input: "/media/movie.mp4", Expected: ExpectedResults{stem: "/media/movie", ext: ".mp4"}},
+		{Name: "Compound extension", Input: "/backups/site.tar.gz", Expected: ExpectedResults{stem: "/backups/site", ext: ".tar.gz"}},
+		{Name: "Compound extension keeps case", Input: "site.TAR.GZ", Expected: ExpectedResults{stem: "site", ext: ".TAR.GZ"}},
+		{Name: "Multi-part archive", Input: "movie.part01.rar", Expected: ExpectedResults{stem: "movie", ext: ".part01.rar"}},
+		{Name: "Numbered volume", Input: "backup.7z.001", Expected: ExpectedResults{stem: "backup", ext: ".7z.001"}},
+		{Name: "Dots in the stem", Input: "my.holiday.2024.mkv", Expected: ExpectedResults{stem: "my.holiday.2024", ext: ".mkv"}},
+		{Name: "Unregistered extension", Input: "program.exe", Expected: ExpectedResults{stem: "program", ext: ".exe"}},
+		{Name: "No extension", Input: "/docs/README", Expected: ExpectedResults{stem: "/docs/README", ext: ""}},
+		{Name: "Dotted directory", Input: "/media/site.tar.gz/notes", Expected: ExpectedResults{stem: "/media/site.tar.gz/notes", ext: ""}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			stem, ext := SplitExtension(test.Input)
+			if stem != test.Expected.stem || ext != test.Expected.ext {
+				t.Errorf("SplitExtension(%q) - %v = %q, %q; want %q, %q", test.Input, test.Name, stem, ext, test.Expected.stem, test.Expected.ext)
+			}
+		})
+	}
+}
+
 // TestIsDirectoryEmpty handles testing for IsDirectoryEmpty func.
 func TestIsDirectoryEmpty(t *testing.T) {
 	emptyDir := CreateEmptyDir(t)
@@ -395,6 +786,7 @@ func TestIsDirectoryEmpty(t *testing.T) {
 		{Name: "Test non-existing directory stat", Input: statFilePath, Expected: false, Err: fmt.Errorf("stat error - file not found: %s", statFilePath)},
 		{Name: "Test file instead of directory", Input: file.Name(), Expected: false, Err: errors.New("not a directory")},
 		{Name: "Test Dir with read error", Input: readDirErrorPath, Expected: false, Err: fmt.Errorf("simulated ReadDir error")},
+		{Name: "Test Dir with stat error", Input: emptyDir, Expected: false, Err: fmt.Errorf("stat error - file not found: %s", emptyDir)},
 	}
 
 	for _, test := range tests {
@@ -405,6 +797,10 @@ func TestIsDirectoryEmpty(t *testing.T) {
 				ops = &MockDirOps{
 					readDirErr: fmt.Errorf("simulated ReadDir error"),
 				}
+			case "Test Dir with stat error":
+				ops = &MockDirOps{
+					statErr: fmt.Errorf("simulated Stat error"),
+				}
 			default:
 				ops = &types.RealDirOps{}
 			}
@@ -422,6 +818,214 @@ func TestIsDirectoryEmpty(t *testing.T) {
 	}
 }
 
+// TestToSymlinkPolicy tests ToSymlinkPolicy func.
+func TestToSymlinkPolicy(t *testing.T) {
+	tests := []*types.TestLayout[string, types.SymlinkPolicy]{
+		{Name: "Test skip", Input: "skip", Expected: types.SymlinkPolicies.Skip},
+		{Name: "Test ignore", Input: "Ignore", Expected: types.SymlinkPolicies.Skip},
+		{Name: "Test follow", Input: "FOLLOW", Expected: types.SymlinkPolicies.Follow},
+		{Name: "Test file", Input: "file", Expected: types.SymlinkPolicies.File},
+		{Name: "Test as file", Input: "as file", Expected: types.SymlinkPolicies.File},
+		{Name: "Test default case", Input: "", Expected: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result := ToSymlinkPolicy(test.Input)
+			if result != test.Expected {
+				t.Errorf("ToSymlinkPolicy() - %v(%q) = %q; expected %q", test.Name, test.Input, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestToDetectionMode tests the ToDetectionMode func.
+func TestToDetectionMode(t *testing.T) {
+	tests := []*types.TestLayout[string, types.DetectionMode]{
+		{Name: "Test extension", Input: "extension", Expected: types.DetectionModes.Extension},
+		{Name: "Test ext", Input: "EXT", Expected: types.DetectionModes.Extension},
+		{Name: "Test content", Input: "Content", Expected: types.DetectionModes.Content},
+		{Name: "Test magic", Input: "magic", Expected: types.DetectionModes.Content},
+		{Name: "Test both", Input: "both", Expected: types.DetectionModes.Both},
+		{Name: "Test default case", Input: "", Expected: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result := ToDetectionMode(test.Input)
+			if result != test.Expected {
+				t.Errorf("ToDetectionMode() - %v(%q) = %q; expected %q", test.Name, test.Input, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestToSizeSystem tests the ToSizeSystem func.
+func TestToSizeSystem(t *testing.T) {
+	tests := []*types.TestLayout[string, types.SizeSystem]{
+		{Name: "Test iec", Input: "IEC", Expected: types.SizeSystems.IEC},
+		{Name: "Test binary", Input: "binary", Expected: types.SizeSystems.IEC},
+		{Name: "Test 1024", Input: "1024", Expected: types.SizeSystems.IEC},
+		{Name: "Test si", Input: "si", Expected: types.SizeSystems.SI},
+		{Name: "Test decimal", Input: "Decimal", Expected: types.SizeSystems.SI},
+		{Name: "Test 1000", Input: "1000", Expected: types.SizeSystems.SI},
+		{Name: "Test default case", Input: "", Expected: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result := ToSizeSystem(test.Input)
+			if result != test.Expected {
+				t.Errorf("ToSizeSystem() - %v(%q) = %q; expected %q", test.Name, test.Input, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestToTimeField tests the ToTimeField func.
+func TestToTimeField(t *testing.T) {
+	tests := []*types.TestLayout[string, types.TimeField]{
+		{Name: "Test mtime", Input: "mtime", Expected: types.TimeFields.Modified},
+		{Name: "Test modified", Input: "Modified", Expected: types.TimeFields.Modified},
+		{Name: "Test ctime", Input: "CTIME", Expected: types.TimeFields.Changed},
+		{Name: "Test change time", Input: "change time", Expected: types.TimeFields.Changed},
+		{Name: "Test atime", Input: "atime", Expected: types.TimeFields.Accessed},
+		{Name: "Test access", Input: "access", Expected: types.TimeFields.Accessed},
+		{Name: "Test default case", Input: "btime", Expected: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result := ToTimeField(test.Input)
+			if result != test.Expected {
+				t.Errorf("ToTimeField() - %v(%q) = %q; expected %q", test.Name, test.Input, result, test.Expected)
+			}
+		})
+	}
+}
+
+// CreateSymlinkFS creates an in-memory tree holding symlinks for testing.
+//
+//	/root
+//	├── a/ (empty)
+//	├── broken -> /missing
+//	├── link -> /outside
+//	├── loop -> /root
+//	└── movie.mp4 -> /outside/x/movie.mp4
+//	/outside/x/y/ (empty)
+//	/outside/x/movie.mp4 (2048 B)
+func CreateSymlinkFS(t *testing.T) *memfs.MemDirOps {
+	t.Helper()
+	ops := memfs.New()
+	for _, dir := range []string{"/root/a", "/outside/x/y"} {
+		if err := ops.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+	}
+	if err := ops.WriteFile("/outside/x/movie.mp4", make([]byte, 2048), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	links := map[string]string{
+		"/root/broken":    "/missing",
+		"/root/link":      "/outside",
+		"/root/loop":      "/root",
+		"/root/movie.mp4": "/outside/x/movie.mp4",
+	}
+	for name, target := range links {
+		if err := ops.Symlink(target, name); err != nil {
+			t.Fatalf("failed to create symlink: %v", err)
+		}
+	}
+	return ops
+}
+
+// TestResolveSymlink tests ResolveSymlink func.
+func TestResolveSymlink(t *testing.T) {
+	type InputStruct struct {
+		path   string
+		policy types.SymlinkPolicy
+	}
+	type ExpectedResults struct {
+		skipped bool
+		size    int64
+		symlink bool
+	}
+
+	tests := []*types.TestLayout[InputStruct, ExpectedResults]{
+		{Name: "Regular file is returned as is", Input: InputStruct{path: "/outside/x/movie.mp4"}, Expected: ExpectedResults{size: 2048}},
+		{Name: "Skip leaves the link out", Input: InputStruct{path: "/root/movie.mp4", policy: types.SymlinkPolicies.Skip}, Expected: ExpectedResults{skipped: true}},
+		{Name: "Empty policy skips", Input: InputStruct{path: "/root/movie.mp4"}, Expected: ExpectedResults{skipped: true}},
+		{Name: "Follow returns the target", Input: InputStruct{path: "/root/movie.mp4", policy: types.SymlinkPolicies.Follow}, Expected: ExpectedResults{size: 2048}},
+		{Name: "File returns the link", Input: InputStruct{path: "/root/movie.mp4", policy: types.SymlinkPolicies.File}, Expected: ExpectedResults{size: int64(len("/outside/x/movie.mp4")), symlink: true}},
+		{Name: "Broken link is reported under skip", Input: InputStruct{path: "/root/broken", policy: types.SymlinkPolicies.Skip}, Err: ErrBrokenLink},
+		{Name: "Broken link is reported under follow", Input: InputStruct{path: "/root/broken", policy: types.SymlinkPolicies.Follow}, Err: ErrBrokenLink},
+		{Name: "Missing path", Input: InputStruct{path: "/root/missing", policy: types.SymlinkPolicies.Follow}, Err: os.ErrNotExist},
+	}
+
+	ops := CreateSymlinkFS(t)
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			info, err := ResolveSymlink(test.Input.path, ops, test.Input.policy)
+			if test.Err != nil {
+				if !errors.Is(err, test.Err) {
+					t.Errorf("ResolveSymlink(%q) - %v error = %v; want %v", test.Input.path, test.Name, err, test.Err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveSymlink(%q) - %v error = %v; want nil", test.Input.path, test.Name, err)
+			}
+
+			got := ExpectedResults{skipped: info == nil}
+			if info != nil {
+				got.size, got.symlink = info.Size(), info.Mode()&os.ModeSymlink != 0
+			}
+			if got != test.Expected {
+				t.Errorf("ResolveSymlink(%q) - %v = %+v; want %+v", test.Input.path, test.Name, got, test.Expected)
+			}
+		})
+	}
+}
+
+// TestSameFile tests SameFile func against the real filesystem and MemDirOps.
+func TestSameFile(t *testing.T) {
+	dir := CreateNonEmptyDir(t)
+	file := filepath.Join(dir, "testfile.txt")
+	link := filepath.Join(dir, "link.txt")
+	if err := os.Symlink(file, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	fileInfo, _ := os.Stat(file)
+	linkInfo, _ := os.Stat(link)
+	dirInfo, _ := os.Stat(dir)
+
+	ops := CreateSymlinkFS(t)
+	memTarget, _ := ops.Stat("/outside")
+	memLink, _ := ops.Stat("/root/link")
+	memOther, _ := ops.Stat("/root/a")
+
+	tests := []*types.TestLayout[[]os.FileInfo, bool]{
+		{Name: "Real file and link to it", Input: []os.FileInfo{fileInfo, linkInfo}, Expected: true},
+		{Name: "Real file and directory", Input: []os.FileInfo{fileInfo, dirInfo}, Expected: false},
+		{Name: "MemDirOps directory and link to it", Input: []os.FileInfo{memTarget, memLink}, Expected: true},
+		{Name: "MemDirOps different directories", Input: []os.FileInfo{memTarget, memOther}, Expected: false},
+		{Name: "Nil info", Input: []os.FileInfo{nil, fileInfo}, Expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if result := SameFile(test.Input[0], test.Input[1]); result != test.Expected {
+				t.Errorf("SameFile() - %v = %v; want %v", test.Name, result, test.Expected)
+			}
+		})
+	}
+
+	if !IsSymlinkLoop([]os.FileInfo{memOther, memTarget}, memLink) {
+		t.Errorf("IsSymlinkLoop() with target among ancestors = false; want true")
+	}
+}
+
 // TestGetOperatorSizeMatches tests GetOperatorSizeMatches func.
 func TestGetOperatorSizeMatches(t *testing.T) {
 	type InputStruct struct {
@@ -471,6 +1075,62 @@ func TestGetOperatorSizeMatches(t *testing.T) {
 	}
 }
 
+// TestGetOperatorSizeMatches_Range tests the GetOperatorSizeMatches func with the NotEqualTo and range operators.
+func TestGetOperatorSizeMatches_Range(t *testing.T) {
+	type InputStruct struct {
+		Operator      types.OperatorType
+		WantedSize    int64
+		ToleranceSize float64
+		FileSize      int64
+		UpperSize     []int64
+	}
+
+	tests := []*types.TestLayout[InputStruct, bool]{
+		{Name: "NotEqualTo Equal to FileSize", Input: InputStruct{Operator: types.OperatorTypes.NotEqualTo, WantedSize: 1024, FileSize: 1024}, Expected: false},
+		{Name: "NotEqualTo Other FileSize", Input: InputStruct{Operator: types.OperatorTypes.NotEqualTo, WantedSize: 1024, FileSize: 1025}, Expected: true},
+		{Name: "NotEqualTo Within Tolerance", Input: InputStruct{Operator: types.OperatorTypes.NotEqualTo, WantedSize: 1024, ToleranceSize: 0.5, FileSize: 1500}, Expected: false},
+		{Name: "NotEqualTo Outside Tolerance", Input: InputStruct{Operator: types.OperatorTypes.NotEqualTo, WantedSize: 1024, ToleranceSize: 0.5, FileSize: 1537}, Expected: true},
+		{Name: "NotEqualTo Ignores Upper Bound", Input: InputStruct{Operator: types.OperatorTypes.NotEqualTo, WantedSize: 1024, FileSize: 1024, UpperSize: []int64{4096}}, Expected: false},
+		{Name: "Between Lower Bound", Input: InputStruct{Operator: types.OperatorTypes.Between, WantedSize: 1024, FileSize: 1024, UpperSize: []int64{2048}}, Expected: true},
+		{Name: "Between Upper Bound", Input: InputStruct{Operator: types.OperatorTypes.Between, WantedSize: 1024, FileSize: 2048, UpperSize: []int64{2048}}, Expected: true},
+		{Name: "Between Below", Input: InputStruct{Operator: types.OperatorTypes.Between, WantedSize: 1024, FileSize: 1023, UpperSize: []int64{2048}}, Expected: false},
+		{Name: "Between Above", Input: InputStruct{Operator: types.OperatorTypes.Between, WantedSize: 1024, FileSize: 2049, UpperSize: []int64{2048}}, Expected: false},
+		{Name: "Between Single Size", Input: InputStruct{Operator: types.OperatorTypes.Between, WantedSize: 1024, FileSize: 1024, UpperSize: []int64{1024}}, Expected: true},
+		{Name: "Between Below Within Tolerance", Input: InputStruct{Operator: types.OperatorTypes.Between, WantedSize: 1024, ToleranceSize: 0.5, FileSize: 512, UpperSize: []int64{2048}}, Expected: true},
+		{Name: "Between Above Within Tolerance", Input: InputStruct{Operator: types.OperatorTypes.Between, WantedSize: 1024, ToleranceSize: 0.5, FileSize: 2560, UpperSize: []int64{2048}}, Expected: true},
+		{Name: "Between Above Outside Tolerance", Input: InputStruct{Operator: types.OperatorTypes.Between, WantedSize: 1024, ToleranceSize: 0.5, FileSize: 2561, UpperSize: []int64{2048}}, Expected: false},
+		{Name: "BetweenExclusive Lower Bound", Input: InputStruct{Operator: types.OperatorTypes.BetweenExclusive, WantedSize: 1024, FileSize: 1024, UpperSize: []int64{2048}}, Expected: false},
+		{Name: "BetweenExclusive Upper Bound", Input: InputStruct{Operator: types.OperatorTypes.BetweenExclusive, WantedSize: 1024, FileSize: 2048, UpperSize: []int64{2048}}, Expected: false},
+		{Name: "BetweenExclusive Inside", Input: InputStruct{Operator: types.OperatorTypes.BetweenExclusive, WantedSize: 1024, FileSize: 1025, UpperSize: []int64{2048}}, Expected: true},
+		{Name: "NotBetween Inside", Input: InputStruct{Operator: types.OperatorTypes.NotBetween, WantedSize: 1024, FileSize: 1500, UpperSize: []int64{2048}}, Expected: false},
+		{Name: "NotBetween Lower Bound", Input: InputStruct{Operator: types.OperatorTypes.NotBetween, WantedSize: 1024, FileSize: 1024, UpperSize: []int64{2048}}, Expected: false},
+		{Name: "NotBetween Below", Input: InputStruct{Operator: types.OperatorTypes.NotBetween, WantedSize: 1024, FileSize: 1023, UpperSize: []int64{2048}}, Expected: true},
+		{Name: "NotBetween Above", Input: InputStruct{Operator: types.OperatorTypes.NotBetween, WantedSize: 1024, FileSize: 2049, UpperSize: []int64{2048}}, Expected: true},
+		{Name: "NotBetweenExclusive Lower Bound", Input: InputStruct{Operator: types.OperatorTypes.NotBetweenExclusive, WantedSize: 1024, FileSize: 1024, UpperSize: []int64{2048}}, Expected: true},
+		{Name: "NotBetweenExclusive Upper Bound", Input: InputStruct{Operator: types.OperatorTypes.NotBetweenExclusive, WantedSize: 1024, FileSize: 2048, UpperSize: []int64{2048}}, Expected: true},
+		{Name: "NotBetweenExclusive Inside", Input: InputStruct{Operator: types.OperatorTypes.NotBetweenExclusive, WantedSize: 1024, FileSize: 2047, UpperSize: []int64{2048}}, Expected: false},
+		// Errors
+		{Name: "Between Missing Upper Bound", Input: InputStruct{Operator: types.OperatorTypes.Between, WantedSize: 1024, FileSize: 1024}, Expected: false, Err: fmt.Errorf("Between needs exactly one upper bound, got 0")},
+		{Name: "Between Two Upper Bounds", Input: InputStruct{Operator: types.OperatorTypes.Between, WantedSize: 1024, FileSize: 1024, UpperSize: []int64{2048, 4096}}, Expected: false, Err: fmt.Errorf("Between needs exactly one upper bound, got 2")},
+		{Name: "NotBetween Lower Bound Above Upper Bound", Input: InputStruct{Operator: types.OperatorTypes.NotBetween, WantedSize: 2048, FileSize: 1024, UpperSize: []int64{1024}}, Expected: false, Err: fmt.Errorf("lower bound 2048 is greater than upper bound 1024")},
+		{Name: "Between Tolerance Cannot Be Negative", Input: InputStruct{Operator: types.OperatorTypes.Between, WantedSize: 1024, ToleranceSize: -1, FileSize: 1024, UpperSize: []int64{2048}}, Expected: false, Err: fmt.Errorf("error calculating tolerances toleranceSize cannot be negative")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := GetOperatorSizeMatches(test.Input.Operator, test.Input.WantedSize, test.Input.ToleranceSize, test.Input.FileSize, test.Input.UpperSize...)
+
+			if result != test.Expected {
+				t.Errorf("GetOperatorSizeMatches(%v, %v, %v, %v, %v) - %v = %v; want %v", test.Input.Operator, test.Input.WantedSize, test.Input.ToleranceSize, test.Input.FileSize, test.Input.UpperSize, test.Name, result, test.Expected)
+			}
+
+			if (err != nil && test.Err == nil) || (err == nil && test.Err != nil) || (err != nil && test.Err != nil && err.Error() != test.Err.Error()) {
+				t.Errorf("GetOperatorSizeMatches(%v, %v, %v, %v, %v) - %v error = %v; want %v", test.Input.Operator, test.Input.WantedSize, test.Input.ToleranceSize, test.Input.FileSize, test.Input.UpperSize, test.Name, err, test.Err)
+			}
+		})
+	}
+}
+
 // TestCalculateTolerances tests the CalculateTolerances func.
 func TestCalculateTolerances(t *testing.T) {
 	type InputStruct struct {
@@ -483,12 +1143,14 @@ func TestCalculateTolerances(t *testing.T) {
 	}
 
 	tests := []*types.TestLayout[InputStruct, ExpectedResults]{
-		{Name: "Basic tolerance calculation", Input: InputStruct{wantedFileSize: 1024, toleranceSize: 10}, Expected: ExpectedResults{results: types.ToleranceResults{ToleranceSize: 10240, UpperBoundSize: 11264, LowerBoundSize: 0}, err: nil}},
+		{Name: "Basic tolerance calculation", Input: InputStruct{wantedFileSize: 1024, toleranceSize: 10}, Expected: ExpectedResults{results: types.ToleranceResults{ToleranceSize: 10240, LowerToleranceSize: 10240, UpperToleranceSize: 10240, UpperBoundSize: 11264, LowerBoundSize: 0}, err: nil}},
 		{Name: "Zero tolerance size", Input: InputStruct{wantedFileSize: 2048, toleranceSize: 0}, Expected: ExpectedResults{results: types.ToleranceResults{ToleranceSize: 0, UpperBoundSize: 2048, LowerBoundSize: 2048}, err: nil}},
 		{Name: "Negative tolerance size", Input: InputStruct{wantedFileSize: 2048, toleranceSize: -5}, Expected: ExpectedResults{results: types.ToleranceResults{ToleranceSize: 0, UpperBoundSize: 0, LowerBoundSize: 0}, err: fmt.Errorf("toleranceSize cannot be negative")}},
 		{Name: "Negative wanted file size", Input: InputStruct{wantedFileSize: -1024, toleranceSize: 10}, Expected: ExpectedResults{results: types.ToleranceResults{ToleranceSize: 0, UpperBoundSize: 0, LowerBoundSize: 0}, err: fmt.Errorf("wantedFileSize cannot be negative")}},
 		{Name: "Negative tolerance size with large wanted file size", Input: InputStruct{wantedFileSize: 10737418240, toleranceSize: -50}, Expected: ExpectedResults{results: types.ToleranceResults{ToleranceSize: 0, UpperBoundSize: 0, LowerBoundSize: 0}, err: fmt.Errorf("toleranceSize cannot be negative")}},
-		{Name: "Lower bound clamped to zero", Input: InputStruct{wantedFileSize: 500, toleranceSize: 600}, Expected: ExpectedResults{results: types.ToleranceResults{ToleranceSize: 614400, UpperBoundSize: 614900, LowerBoundSize: 0}, err: nil}},
+		{Name: "Lower bound clamped to zero", Input: InputStruct{wantedFileSize: 500, toleranceSize: 600}, Expected: ExpectedResults{results: types.ToleranceResults{ToleranceSize: 614400, LowerToleranceSize: 614400, UpperToleranceSize: 614400, UpperBoundSize: 614900, LowerBoundSize: 0}, err: nil}},
+		{Name: "Upper bound clamped to MaxInt64", Input: InputStruct{wantedFileSize: math.MaxInt64 - 100, toleranceSize: 1}, Expected: ExpectedResults{results: types.ToleranceResults{ToleranceSize: 1024, LowerToleranceSize: 1024, UpperToleranceSize: 1024, UpperBoundSize: math.MaxInt64, LowerBoundSize: math.MaxInt64 - 1124}, err: nil}},
+		{Name: "Huge tolerance clamped to MaxInt64", Input: InputStruct{wantedFileSize: 1024, toleranceSize: 1e300}, Expected: ExpectedResults{results: types.ToleranceResults{ToleranceSize: math.MaxInt64, LowerToleranceSize: math.MaxInt64, UpperToleranceSize: math.MaxInt64, UpperBoundSize: math.MaxInt64, LowerBoundSize: 0}, err: nil}},
 	}
 
 	for _, test := range tests {
@@ -506,6 +1168,113 @@ func TestCalculateTolerances(t *testing.T) {
 	}
 }
 
+// TestCalculateToleranceBounds tests the CalculateToleranceBounds func.
+func TestCalculateToleranceBounds(t *testing.T) {
+	type InputStruct struct {
+		wantedFileSize int64
+		tolerance      types.Tolerance
+	}
+
+	tests := []*types.TestLayout[InputStruct, types.ToleranceResults]{
+		{Name: "Exact", Input: InputStruct{wantedFileSize: 2048}, Expected: types.ToleranceResults{UpperBoundSize: 2048, LowerBoundSize: 2048}},
+		{Name: "Percent", Input: InputStruct{wantedFileSize: 1000, tolerance: types.Tolerance{Lower: types.ToleranceBound{Percent: 5}, Upper: types.ToleranceBound{Percent: 5}}}, Expected: types.ToleranceResults{ToleranceSize: 50, LowerToleranceSize: 50, UpperToleranceSize: 50, UpperBoundSize: 1050, LowerBoundSize: 950}},
+		{Name: "Asymmetric", Input: InputStruct{wantedFileSize: 1000, tolerance: types.Tolerance{Lower: types.ToleranceBound{Bytes: 100}, Upper: types.ToleranceBound{Percent: 50}}}, Expected: types.ToleranceResults{ToleranceSize: 500, LowerToleranceSize: 100, UpperToleranceSize: 500, UpperBoundSize: 1500, LowerBoundSize: 900}},
+		{Name: "Upper only", Input: InputStruct{wantedFileSize: 1000, tolerance: types.Tolerance{Upper: types.ToleranceBound{Bytes: 24}}}, Expected: types.ToleranceResults{ToleranceSize: 24, UpperToleranceSize: 24, UpperBoundSize: 1024, LowerBoundSize: 1000}},
+		{Name: "Bytes and percent add up", Input: InputStruct{wantedFileSize: 1000, tolerance: types.Tolerance{Upper: types.ToleranceBound{Bytes: 10, Percent: 1}}}, Expected: types.ToleranceResults{ToleranceSize: 20, UpperToleranceSize: 20, UpperBoundSize: 1020, LowerBoundSize: 1000}},
+		{Name: "Lower bound clamped to zero", Input: InputStruct{wantedFileSize: 1000, tolerance: types.Tolerance{Lower: types.ToleranceBound{Percent: 150}}}, Expected: types.ToleranceResults{LowerToleranceSize: 1500, UpperBoundSize: 1000, LowerBoundSize: 0}},
+		{Name: "Percent clamped to MaxInt64", Input: InputStruct{wantedFileSize: math.MaxInt64, tolerance: types.Tolerance{Upper: types.ToleranceBound{Percent: 200}}}, Expected: types.ToleranceResults{ToleranceSize: math.MaxInt64, UpperToleranceSize: math.MaxInt64, UpperBoundSize: math.MaxInt64, LowerBoundSize: math.MaxInt64}},
+		{Name: "Bytes and percent clamped to MaxInt64", Input: InputStruct{wantedFileSize: math.MaxInt64 / 2, tolerance: types.Tolerance{Upper: types.ToleranceBound{Bytes: math.MaxInt64, Percent: 100}}}, Expected: types.ToleranceResults{ToleranceSize: math.MaxInt64, UpperToleranceSize: math.MaxInt64, UpperBoundSize: math.MaxInt64, LowerBoundSize: math.MaxInt64 / 2}},
+		{Name: "Negative wanted file size", Input: InputStruct{wantedFileSize: -1}, Err: errors.New("wantedFileSize cannot be negative")},
+		{Name: "Negative bytes", Input: InputStruct{wantedFileSize: 1000, tolerance: types.Tolerance{Lower: types.ToleranceBound{Bytes: -1}}}, Err: errors.New("tolerance cannot be negative")},
+		{Name: "Negative percent", Input: InputStruct{wantedFileSize: 1000, tolerance: types.Tolerance{Upper: types.ToleranceBound{Percent: -1}}}, Err: errors.New("tolerance cannot be negative")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := CalculateToleranceBounds(test.Input.wantedFileSize, test.Input.tolerance)
+			if (err != nil) != (test.Err != nil) || (err != nil && err.Error() != test.Err.Error()) {
+				t.Fatalf("CalculateToleranceBounds(%v, %+v) - %v error = %v; want %v", test.Input.wantedFileSize, test.Input.tolerance, test.Name, err, test.Err)
+			}
+			if result != test.Expected {
+				t.Errorf("CalculateToleranceBounds(%v, %+v) - %v = %+v; want %+v", test.Input.wantedFileSize, test.Input.tolerance, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestParseTolerance tests the ParseTolerance func.
+func TestParseTolerance(t *testing.T) {
+	percent := func(p float64) types.ToleranceBound { return types.ToleranceBound{Percent: p} }
+	bytes := func(b int64) types.ToleranceBound { return types.ToleranceBound{Bytes: b} }
+
+	tests := []*types.TestLayout[string, types.Tolerance]{
+		{Name: "Percent", Input: "5%", Expected: types.Tolerance{Lower: percent(5), Upper: percent(5)}},
+		{Name: "Fractional percent", Input: " 2.5 % ", Expected: types.Tolerance{Lower: percent(2.5), Upper: percent(2.5)}},
+		{Name: "Size", Input: "50MB", Expected: types.Tolerance{Lower: bytes(50 << 20), Upper: bytes(50 << 20)}},
+		{Name: "Bytes", Input: "512", Expected: types.Tolerance{Lower: bytes(512), Upper: bytes(512)}},
+		{Name: "Plus minus", Input: "±1KB", Expected: types.Tolerance{Lower: bytes(1024), Upper: bytes(1024)}},
+		{Name: "Above only", Input: "+10%", Expected: types.Tolerance{Upper: percent(10)}},
+		{Name: "Below only", Input: "-1 MB", Expected: types.Tolerance{Lower: bytes(1 << 20)}},
+		{Name: "Asymmetric", Input: "-1MB/+5%", Expected: types.Tolerance{Lower: bytes(1 << 20), Upper: percent(5)}},
+		{Name: "Asymmetric with comma", Input: "+50MB, -0", Expected: types.Tolerance{Upper: bytes(50 << 20)}},
+		{Name: "Empty", Input: "  ", Err: errors.New("tolerance cannot be empty")},
+		{Name: "Negative percent", Input: "--5%", Err: errors.New(`invalid tolerance "--5%": invalid percentage "-5%"`)},
+		{Name: "Invalid unit", Input: "5XB", Err: errors.New(`invalid tolerance "5XB": invalid size unit`)},
+		{Name: "Bound set twice", Input: "5%/+1MB", Err: errors.New(`tolerance "5%/+1MB" sets a bound twice`)},
+		{Name: "Too many parts", Input: "-1/+2/3", Err: errors.New(`tolerance "-1/+2/3" has more than two parts`)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := ParseTolerance(test.Input)
+			if (err != nil) != (test.Err != nil) || (err != nil && err.Error() != test.Err.Error()) {
+				t.Fatalf("ParseTolerance(%q) - %v error = %v; want %v", test.Input, test.Name, err, test.Err)
+			}
+			if result != test.Expected {
+				t.Errorf("ParseTolerance(%q) - %v = %+v; want %+v", test.Input, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
+// TestGetOperatorSizeMatchesWithTolerance tests the GetOperatorSizeMatchesWithTolerance func.
+func TestGetOperatorSizeMatchesWithTolerance(t *testing.T) {
+	type InputStruct struct {
+		Operator   types.OperatorType
+		WantedSize int64
+		Tolerance  string
+		FileSize   int64
+		UpperSize  []int64
+	}
+
+	tests := []*types.TestLayout[InputStruct, bool]{
+		{Name: "EqualTo within percent", Input: InputStruct{Operator: types.OperatorTypes.EqualTo, WantedSize: 1000, Tolerance: "5%", FileSize: 1050}, Expected: true},
+		{Name: "EqualTo outside percent", Input: InputStruct{Operator: types.OperatorTypes.EqualTo, WantedSize: 1000, Tolerance: "5%", FileSize: 1051}, Expected: false},
+		{Name: "EqualTo above only", Input: InputStruct{Operator: types.OperatorTypes.EqualTo, WantedSize: 1000, Tolerance: "+100", FileSize: 999}, Expected: false},
+		{Name: "EqualTo asymmetric below", Input: InputStruct{Operator: types.OperatorTypes.EqualTo, WantedSize: 1000, Tolerance: "-10/+100", FileSize: 990}, Expected: true},
+		{Name: "NotEqualTo asymmetric above", Input: InputStruct{Operator: types.OperatorTypes.NotEqualTo, WantedSize: 1000, Tolerance: "-10/+100", FileSize: 1101}, Expected: true},
+		{Name: "Between percent of each bound", Input: InputStruct{Operator: types.OperatorTypes.Between, WantedSize: 1000, Tolerance: "10%", FileSize: 2200, UpperSize: []int64{2000}}, Expected: true},
+		{Name: "Between below percent", Input: InputStruct{Operator: types.OperatorTypes.Between, WantedSize: 1000, Tolerance: "10%", FileSize: 899, UpperSize: []int64{2000}}, Expected: false},
+		{Name: "EqualTo near MaxInt64", Input: InputStruct{Operator: types.OperatorTypes.EqualTo, WantedSize: math.MaxInt64 - 1, Tolerance: "1GB", FileSize: math.MaxInt64}, Expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			tolerance, err := ParseTolerance(test.Input.Tolerance)
+			if err != nil {
+				t.Fatalf("ParseTolerance(%q) - %v error = %v", test.Input.Tolerance, test.Name, err)
+			}
+			result, err := GetOperatorSizeMatchesWithTolerance(test.Input.Operator, test.Input.WantedSize, tolerance, test.Input.FileSize, test.Input.UpperSize...)
+			if err != nil {
+				t.Fatalf("GetOperatorSizeMatchesWithTolerance() - %v error = %v", test.Name, err)
+			}
+			if result != test.Expected {
+				t.Errorf("GetOperatorSizeMatchesWithTolerance(%v, %v, %q, %v, %v) - %v = %v; want %v", test.Input.Operator, test.Input.WantedSize, test.Input.Tolerance, test.Input.FileSize, test.Input.UpperSize, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
 // TestConvertStringSizeToBytes tests ConvertStringSizeToBytes.
 func TestConvertStringSizeToBytes(t *testing.T) {
 	tests := []*types.TestLayout[string, int64]{
@@ -521,6 +1290,8 @@ func TestConvertStringSizeToBytes(t *testing.T) {
 		{Name: "Test 1000 M", Input: "1000 M", Expected: 0, Err: errors.New("invalid size unit")},
 		{Name: "Test 1000 XYZ", Input: "1000 XYZ", Expected: 0, Err: errors.New("invalid size unit")},
 		{Name: "Test not a size", Input: "not a size", Expected: 0, Err: errors.New("invalid size format")},
+		{Name: "Test -5 KB", Input: "-5 KB", Expected: 0, Err: errors.New("size cannot be negative")},
+		{Name: "Test 8192 PB", Input: "8192 PB", Expected: 0, Err: errors.New("size overflows int64")},
 		{Name: "Test 12.34.56 MB", Input: "12.34.56 MB", Expected: 0, Err: errors.New(`strconv.ParseFloat: parsing "12.34.56": invalid syntax`)},
 	}
 
@@ -604,10 +1375,8 @@ func TestRemoveEmptyDir(t *testing.T) {
 					removeErr: fmt.Errorf("simulated Remove error"),
 				}
 			case "Stat error":
-				originalStatFunc := osStatFunc
-				defer func() { osStatFunc = originalStatFunc }()
-				osStatFunc = func(_ string) (os.FileInfo, error) {
-					return nil, fmt.Errorf("simulated Stat error")
+				ops = &MockDirOps{
+					statErr: fmt.Errorf("simulated Stat error"),
 				}
 			default:
 				ops = &types.RealDirOps{}
@@ -626,6 +1395,54 @@ func TestRemoveEmptyDir(t *testing.T) {
 	}
 }
 
+// TestRemoveEmptyDir_MemDirOps tests RemoveEmptyDir and IsDirectoryEmpty against an in-memory filesystem.
+func TestRemoveEmptyDir_MemDirOps(t *testing.T) {
+	type ExpectedResults struct {
+		empty   bool
+		removed bool
+	}
+
+	tests := []*types.TestLayout[string, ExpectedResults]{
+		{Name: "Empty directory", Input: "/empty", Expected: ExpectedResults{empty: true, removed: true}},
+		{Name: "Directory holding only an empty directory", Input: "/nested", Expected: ExpectedResults{}, Err: fmt.Errorf("is not empty")},
+		{Name: "File", Input: "/file.txt", Expected: ExpectedResults{}, Err: fmt.Errorf("is not a directory")},
+		{Name: "Missing directory", Input: "/missing", Expected: ExpectedResults{}, Err: fmt.Errorf("directory does not exist")},
+		{Name: "Permission denied on remove", Input: "/locked", Expected: ExpectedResults{empty: true}, Err: os.ErrPermission},
+		{Name: "Symlink to empty directory", Input: "/link", Expected: ExpectedResults{}, Err: fmt.Errorf("is a symlink, not a directory")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ops := memfs.New()
+			for _, dir := range []string{"/empty", "/nested/child", "/locked"} {
+				if err := ops.MkdirAll(dir, 0o755); err != nil {
+					t.Fatalf("failed to create directory: %v", err)
+				}
+			}
+			if err := ops.WriteFile("/file.txt", []byte("test content"), 0o644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+			if err := ops.Symlink("/empty", "/link"); err != nil {
+				t.Fatalf("failed to create symlink: %v", err)
+			}
+			ops.InjectError(memfs.OpRemove, "/locked", os.ErrPermission)
+
+			empty, _ := IsDirectoryEmpty(test.Input, ops)
+			if empty != test.Expected.empty {
+				t.Errorf("IsDirectoryEmpty(%q) - %v = %v; want %v", test.Input, test.Name, empty, test.Expected.empty)
+			}
+
+			removed, err := RemoveEmptyDir(test.Input, ops)
+			if removed != test.Expected.removed {
+				t.Errorf("RemoveEmptyDir(%q) - %v = %v; want %v", test.Input, test.Name, removed, test.Expected.removed)
+			}
+			if (err != nil && test.Err == nil) || (err == nil && test.Err != nil) || (err != nil && test.Err != nil && !strings.Contains(err.Error(), test.Err.Error())) {
+				t.Errorf("RemoveEmptyDir(%q) - %v error = %v; want error containing %v", test.Input, test.Name, err, test.Err)
+			}
+		})
+	}
+}
+
 // TestInRange tests the InRange function with different scenarios.
 func TestInRange(t *testing.T) {
 	type InputStruct struct {
@@ -678,3 +1495,237 @@ func TestInRange(t *testing.T) {
 		})
 	}
 }
+
+// CreatePruneTree creates a tree with nested empty directories and, optionally, one directory holding a
+// file.
+//
+//	root/
+//	├── a/b/c/
+//	├── d/keep.txt
+//	└── e/
+func CreatePruneTree(t *testing.T, withFile bool) string {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "prune-root")
+	for _, dir := range []string{"a/b/c", "d", "e"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+	}
+	if withFile {
+		if err := os.WriteFile(filepath.Join(root, "d", "keep.txt"), []byte("test content"), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	return root
+}
+
+// TestPruneEmptyDirs tests PruneEmptyDirs func.
+func TestPruneEmptyDirs(t *testing.T) {
+	type InputStruct struct {
+		opts     types.PruneOptions
+		withFile bool
+	}
+	type ExpectedResults struct {
+		removed  []string
+		skipped  []string
+		existing []string
+	}
+
+	tests := []*types.TestLayout[InputStruct, ExpectedResults]{
+		{
+			Name:     "Prune everything empty",
+			Input:    InputStruct{opts: types.PruneOptions{}, withFile: true},
+			Expected: ExpectedResults{removed: []string{"a/b/c", "a/b", "a", "e"}, skipped: []string{"d", "."}, existing: []string{"d"}},
+		},
+		{
+			Name:     "Dry run removes nothing",
+			Input:    InputStruct{opts: types.PruneOptions{DryRun: true}, withFile: true},
+			Expected: ExpectedResults{removed: []string{"a/b/c", "a/b", "a", "e"}, skipped: []string{"d", "."}, existing: []string{"a/b/c", "e"}},
+		},
+		{
+			Name:     "Max depth stops descent",
+			Input:    InputStruct{opts: types.PruneOptions{MaxDepth: 1}, withFile: true},
+			Expected: ExpectedResults{removed: []string{"e"}, skipped: []string{"a/b", "a", "d", "."}, existing: []string{"a/b/c"}},
+		},
+		{
+			Name:     "Root removed once empty",
+			Input:    InputStruct{opts: types.PruneOptions{}, withFile: false},
+			Expected: ExpectedResults{removed: []string{"a/b/c", "a/b", "a", "d", "e", "."}, skipped: []string{}},
+		},
+		{
+			Name:     "Keep root",
+			Input:    InputStruct{opts: types.PruneOptions{KeepRoot: true}, withFile: false},
+			Expected: ExpectedResults{removed: []string{"a/b/c", "a/b", "a", "d", "e"}, skipped: []string{"."}, existing: []string{"."}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			root := CreatePruneTree(t, test.Input.withFile)
+
+			report, err := PruneEmptyDirs(root, &types.RealDirOps{}, test.Input.opts)
+			if err != nil {
+				t.Fatalf("PruneEmptyDirs() - %v error = %v, expected nil", test.Name, err)
+			}
+
+			rel := func(paths []string) []string {
+				out := []string{}
+				for _, p := range paths {
+					r, _ := filepath.Rel(root, p)
+					out = append(out, filepath.ToSlash(r))
+				}
+				return out
+			}
+
+			if got := rel(report.Removed); !reflect.DeepEqual(got, test.Expected.removed) {
+				t.Errorf("PruneEmptyDirs() - %v removed = %v; want %v", test.Name, got, test.Expected.removed)
+			}
+			if got := rel(report.Skipped); !reflect.DeepEqual(got, test.Expected.skipped) {
+				t.Errorf("PruneEmptyDirs() - %v skipped = %v; want %v", test.Name, got, test.Expected.skipped)
+			}
+			for _, p := range test.Expected.existing {
+				if _, err := os.Stat(filepath.Join(root, p)); err != nil {
+					t.Errorf("PruneEmptyDirs() - %v expected %s to exist: %v", test.Name, p, err)
+				}
+			}
+		})
+	}
+}
+
+// TestPruneEmptyDirs_Ignore tests that ignored directories are neither descended into nor removed.
+func TestPruneEmptyDirs_Ignore(t *testing.T) {
+	root := CreatePruneTree(t, false)
+	matcher, err := ignore.New(root, "/a/b", "e/")
+	if err != nil {
+		t.Fatalf("ignore.New() error = %v", err)
+	}
+
+	report, err := PruneEmptyDirs(root, &types.RealDirOps{}, types.PruneOptions{Ignore: matcher})
+	if err != nil {
+		t.Fatalf("PruneEmptyDirs() error = %v", err)
+	}
+
+	if len(report.Removed) != 1 || report.Removed[0] != filepath.Join(root, "d") {
+		t.Errorf("PruneEmptyDirs() removed = %v; want only %s", report.Removed, filepath.Join(root, "d"))
+	}
+	for _, dir := range []string{"a/b/c", "e"} {
+		if _, err := os.Stat(filepath.Join(root, dir)); err != nil {
+			t.Errorf("PruneEmptyDirs() removed ignored %s: %v", dir, err)
+		}
+	}
+}
+
+// TestPruneEmptyDirs_Symlinks tests that symlinks are kept, followed according to the policy and
+// reported when broken or looping.
+func TestPruneEmptyDirs_Symlinks(t *testing.T) {
+	type ExpectedResults struct {
+		removed []string
+		failed  []string
+		broken  []string
+	}
+
+	tests := []*types.TestLayout[types.SymlinkPolicy, ExpectedResults]{
+		{
+			Name:     "Skip",
+			Input:    types.SymlinkPolicies.Skip,
+			Expected: ExpectedResults{removed: []string{"/root/a"}, failed: []string{}, broken: []string{"/root/broken"}},
+		},
+		{
+			Name:     "File",
+			Input:    types.SymlinkPolicies.File,
+			Expected: ExpectedResults{removed: []string{"/root/a"}, failed: []string{}, broken: []string{"/root/broken"}},
+		},
+		{
+			Name:     "Follow",
+			Input:    types.SymlinkPolicies.Follow,
+			Expected: ExpectedResults{removed: []string{"/root/a", "/root/link/x/y"}, failed: []string{"/root/loop"}, broken: []string{"/root/broken"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ops := CreateSymlinkFS(t)
+			report, err := PruneEmptyDirs("/root", ops, types.PruneOptions{Symlinks: test.Input})
+			if err != nil {
+				t.Fatalf("PruneEmptyDirs() - %v error = %v", test.Name, err)
+			}
+
+			slash := func(paths []string) []string {
+				out := []string{}
+				for _, p := range paths {
+					out = append(out, filepath.ToSlash(p))
+				}
+				return out
+			}
+			failed := []string{}
+			for _, failure := range report.Failed {
+				if !errors.Is(failure.Err, ErrSymlinkLoop) {
+					t.Errorf("PruneEmptyDirs() - %v failure = %v; want %v", test.Name, failure.Err, ErrSymlinkLoop)
+				}
+				failed = append(failed, failure.Path)
+			}
+
+			if got := slash(report.Removed); !reflect.DeepEqual(got, test.Expected.removed) {
+				t.Errorf("PruneEmptyDirs() - %v removed = %v; want %v", test.Name, got, test.Expected.removed)
+			}
+			if got := slash(failed); !reflect.DeepEqual(got, test.Expected.failed) {
+				t.Errorf("PruneEmptyDirs() - %v failed = %v; want %v", test.Name, got, test.Expected.failed)
+			}
+			if got := slash(report.BrokenLinks); !reflect.DeepEqual(got, test.Expected.broken) {
+				t.Errorf("PruneEmptyDirs() - %v broken links = %v; want %v", test.Name, got, test.Expected.broken)
+			}
+			for _, link := range []string{"/root/link", "/root/loop", "/root/broken", "/root/movie.mp4"} {
+				if _, err := ops.Lstat(link); err != nil {
+					t.Errorf("PruneEmptyDirs() - %v removed symlink %s", test.Name, link)
+				}
+			}
+		})
+	}
+
+	ops := CreateSymlinkFS(t)
+	if _, err := PruneEmptyDirs("/root/link", ops, types.PruneOptions{}); err == nil || !strings.Contains(err.Error(), "is a symlink") {
+		t.Errorf("PruneEmptyDirs() on symlinked root error = %v; want is a symlink", err)
+	}
+	report, err := PruneEmptyDirs("/root/link", ops, types.PruneOptions{Symlinks: types.SymlinkPolicies.Follow})
+	if err != nil || !reflect.DeepEqual(report.Removed, []string{filepath.Join("/root/link", "x", "y")}) {
+		t.Errorf("PruneEmptyDirs() following symlinked root = %+v, %v", report, err)
+	}
+}
+
+// TestPruneEmptyDirs_Errors tests PruneEmptyDirs func error handling.
+func TestPruneEmptyDirs_Errors(t *testing.T) {
+	file := CreateTempFile(t)
+	file.Close()
+	emptyDir := CreateEmptyDir(t)
+
+	tests := []*types.TestLayout[string, int]{
+		{Name: "Root does not exist", Input: "nonexistent-dir", Expected: 0, Err: fmt.Errorf("directory does not exist")},
+		{Name: "Root is not a directory", Input: file.Name(), Expected: 0, Err: fmt.Errorf("is not a directory")},
+		{Name: "ReadDir error is reported", Input: emptyDir, Expected: 1},
+		{Name: "Remove error is reported", Input: emptyDir, Expected: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var ops types.DirOps
+			switch test.Name {
+			case "ReadDir error is reported":
+				ops = &MockDirOps{readDirErr: fmt.Errorf("simulated ReadDir error")}
+			case "Remove error is reported":
+				ops = &MockDirOps{removeErr: fmt.Errorf("simulated Remove error")}
+			default:
+				ops = &types.RealDirOps{}
+			}
+
+			report, err := PruneEmptyDirs(test.Input, ops, types.PruneOptions{})
+
+			if len(report.Failed) != test.Expected {
+				t.Errorf("PruneEmptyDirs(%q) - %v failed = %v; want %v", test.Input, test.Name, report.Failed, test.Expected)
+			}
+
+			if (err != nil && test.Err == nil) || (err == nil && test.Err != nil) || (err != nil && test.Err != nil && !strings.Contains(err.Error(), test.Err.Error())) {
+				t.Errorf("PruneEmptyDirs(%q) - %v error = %v; want error containing %v", test.Input, test.Name, err, test.Err)
+			}
+		})
+	}
+}